@@ -0,0 +1,415 @@
+package paymentlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultWebhookTimeout = 5 * time.Second
+	DefaultAlertQueueSize = 100
+)
+
+var (
+	InvalidAlertRule = errors.New("Invalid alert rule.")
+	AlertQueueFull   = errors.New("Alert queue is full; alert dropped.")
+)
+
+// AlertRule fires when the failure logs in the Window before a failure
+// reach Threshold. A rule with a MinRate fires on the failure rate instead
+// of the failure count.
+type AlertRule struct {
+	Name   string
+	Window time.Duration
+	// Threshold is the number of failures in the window that fires the
+	// rule, when MinRate is 0.
+	Threshold int
+	// MinRate is the failure rate (failures divided by payment logs
+	// created in the window) that fires the rule. MinAttempts keeps a few
+	// failures among very few attempts from firing it.
+	MinRate     float64
+	MinAttempts int
+	// GroupBy evaluates the rule separately for each project or source,
+	// counting only failures with the same project or source as the one
+	// just stored. DimensionReasonCode isn't supported.
+	GroupBy Dimension
+	// ProjectID, Source and ReasonCodes, if set, limit the rule to matching
+	// failures.
+	ProjectID   string
+	Source      string
+	ReasonCodes []string
+	// Cooldown is how long the rule stays quiet after firing for a group.
+	// The zero value uses Window.
+	Cooldown time.Duration
+}
+
+func (r AlertRule) Validate() error {
+	if r.Name == "" || r.Window <= 0 || (r.Threshold < 1 && r.MinRate <= 0) {
+		return InvalidAlertRule
+	}
+	if r.GroupBy != DimensionNone && r.GroupBy != DimensionProject && r.GroupBy != DimensionSource {
+		return InvalidAlertRule
+	}
+	return nil
+}
+
+type Alert struct {
+	Rule     string    `json:"rule"`
+	Key      string    `json:"key,omitempty"`
+	Failures int       `json:"failures"`
+	Attempts int       `json:"attempts,omitempty"`
+	Rate     float64   `json:"rate,omitempty"`
+	Since    time.Time `json:"since"`
+	Until    time.Time `json:"until"`
+	// FailureLog is the failure that fired the rule.
+	FailureLog FailureLog `json:"failure_log"`
+}
+
+func (a Alert) String() string {
+	msg := fmt.Sprintf("alert %s: %d failures between %s and %s", a.Rule, a.Failures, a.Since.Format(time.RFC3339), a.Until.Format(time.RFC3339))
+	if a.Key != "" {
+		msg += " for " + a.Key
+	}
+	if a.Attempts > 0 {
+		msg += fmt.Sprintf(" (%d attempts, rate %.3f)", a.Attempts, a.Rate)
+	}
+	return msg
+}
+
+type Notifier interface {
+	Notify(alert Alert) error
+}
+
+// WebhookNotifier POSTs alerts to URL as JSON.
+type WebhookNotifier struct {
+	URL        string
+	HTTPClient *http.Client
+}
+
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{URL: url, HTTPClient: &http.Client{Timeout: DefaultWebhookTimeout}}
+}
+
+func (n *WebhookNotifier) Notify(alert Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	resp, err := n.HTTPClient.Post(n.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("Alert webhook responded with %s.", resp.Status)
+	}
+	return nil
+}
+
+// LogNotifier writes alerts to Logger, or to the standard logger if Logger
+// is nil.
+type LogNotifier struct {
+	Logger *log.Logger
+}
+
+func (n LogNotifier) Notify(alert Alert) error {
+	if n.Logger == nil {
+		log.Println(alert)
+		return nil
+	}
+	n.Logger.Println(alert)
+	return nil
+}
+
+// AlertEngine evaluates its rules every time a failure log is stored. Store
+// is the store rules are evaluated against; it should be the store being
+// wrapped, not the wrapped store, so evaluating doesn't go through the
+// middleware again. Alerts are sent to the notifiers in the background, so a
+// slow notifier never holds up storing failures; Close waits for them to be
+// sent.
+type AlertEngine struct {
+	Store     LogStore
+	Rules     []AlertRule
+	Notifiers []Notifier
+	// OnError, if set, is called with errors evaluating rules or sending
+	// notifications, which otherwise go unreported.
+	OnError func(err error)
+
+	fired  map[string]time.Time
+	queue  chan Alert
+	sent   chan struct{}
+	closed bool
+	sync.Mutex
+}
+
+func NewAlertEngine(store LogStore, rules []AlertRule, notifiers ...Notifier) (*AlertEngine, error) {
+	for _, rule := range rules {
+		if err := rule.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return &AlertEngine{
+		Store:     store,
+		Rules:     rules,
+		Notifiers: notifiers,
+		fired:     map[string]time.Time{},
+	}, nil
+}
+
+// Middleware returns a Middleware that evaluates the rules after every
+// successful StoreFailureLog call.
+func (e *AlertEngine) Middleware() Middleware {
+	return Middleware{
+		After: func(call Call, err error, elapsed time.Duration) {
+			if call.Op != OpStoreFailureLog || err != nil {
+				return
+			}
+			if _, err := e.Evaluate(call.Args[0].(FailureLog)); err != nil {
+				e.report(err)
+			}
+		},
+	}
+}
+
+// Run evaluates the rules as failures are stored, until sub is closed.
+// It's an alternative to Middleware for stores with a change feed.
+func (e *AlertEngine) Run(sub *Subscription) error {
+	for event := range sub.Events() {
+		if event.Type != EventFailureLogStored || event.FailureLog == nil {
+			continue
+		}
+		if _, err := e.Evaluate(*event.FailureLog); err != nil {
+			e.report(err)
+		}
+	}
+	return sub.Err()
+}
+
+// Close waits for queued alerts to be sent. Alerts fired after Close are
+// dropped.
+func (e *AlertEngine) Close() {
+	e.Lock()
+	if e.closed {
+		e.Unlock()
+		return
+	}
+	e.closed = true
+	queue, sent := e.queue, e.sent
+	e.Unlock()
+	if queue != nil {
+		close(queue)
+		<-sent
+	}
+}
+
+func (e *AlertEngine) report(err error) {
+	if e.OnError != nil {
+		e.OnError(err)
+	}
+}
+
+// dispatch queues an alert for the notifiers, starting the goroutine that
+// sends them the first time. Alerts are dropped rather than block when the
+// queue is full.
+func (e *AlertEngine) dispatch(alert Alert) {
+	e.Lock()
+	if e.closed {
+		e.Unlock()
+		return
+	}
+	if e.queue == nil {
+		e.queue, e.sent = make(chan Alert, DefaultAlertQueueSize), make(chan struct{})
+		go e.send(e.queue, e.sent)
+	}
+	queued := true
+	select {
+	case e.queue <- alert:
+	default:
+		queued = false
+	}
+	e.Unlock()
+	if !queued {
+		e.report(AlertQueueFull)
+	}
+}
+
+func (e *AlertEngine) send(queue <-chan Alert, sent chan<- struct{}) {
+	defer close(sent)
+	for alert := range queue {
+		for _, notifier := range e.Notifiers {
+			if err := notifier.Notify(alert); err != nil {
+				e.report(err)
+			}
+		}
+	}
+}
+
+func matchesReasonCode(codes []string, code string) bool {
+	if len(codes) == 0 {
+		return true
+	}
+	for _, c := range codes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// paymentLogsSince calls fn with the payment logs created after since. Only
+// those are read if store is a PaymentLogsSinceStore; otherwise payment
+// logs are iterated newest first until they're older than since.
+func paymentLogsSince(store LogStore, since time.Time, fn func(log PaymentLog)) error {
+	if windowed, ok := store.(PaymentLogsSinceStore); ok {
+		logs, err := windowed.ListPaymentLogsSince(since)
+		for _, log := range logs {
+			fn(log)
+		}
+		return err
+	}
+	return store.IteratePaymentLogs(func(log PaymentLog) error {
+		if !log.Created.After(since) {
+			return StopIteration
+		}
+		fn(log)
+		return nil
+	})
+}
+
+// Evaluate checks every rule against the window ending at failure's
+// timestamp, queues an alert for the notifiers for each rule that fires,
+// and returns the alerts. Rules with a MinRate count the payment logs
+// created in their window, which is only cheap if Store is a
+// PaymentLogsSinceStore.
+func (e *AlertEngine) Evaluate(failure FailureLog) ([]Alert, error) {
+	alerts := []Alert{}
+	if len(e.Rules) == 0 {
+		return alerts, nil
+	}
+	logs := map[string]PaymentLog{}
+	paymentLog := func(id string) (PaymentLog, error) {
+		if log, ok := logs[id]; ok {
+			return log, nil
+		}
+		log, err := e.Store.GetPaymentLog(id)
+		if err == LogNotFound {
+			err = nil
+		}
+		logs[id] = log
+		return log, err
+	}
+	source := func(f FailureLog) (string, error) {
+		if f.Source != "" {
+			return f.Source, nil
+		}
+		log, err := paymentLog(f.PaymentLogID)
+		return log.Source, err
+	}
+	failed, err := paymentLog(failure.PaymentLogID)
+	if err != nil {
+		return alerts, err
+	}
+	failedSource, err := source(failure)
+	if err != nil {
+		return alerts, err
+	}
+	for _, rule := range e.Rules {
+		project, src := rule.ProjectID, rule.Source
+		if (project != "" && failed.ProjectID != project) ||
+			(src != "" && failedSource != src) ||
+			!matchesReasonCode(rule.ReasonCodes, failure.FailureReasonCode) {
+			continue
+		}
+		var key string
+		switch rule.GroupBy {
+		case DimensionProject:
+			key = failed.ProjectID
+			project = key
+		case DimensionSource:
+			key = failedSource
+			src = key
+		}
+		since := failure.Timestamp.Add(-rule.Window)
+		alert := Alert{Rule: rule.Name, Key: key, Since: since, Until: failure.Timestamp, FailureLog: failure}
+		window, err := e.Store.ListFailureLogsSince(since)
+		if err != nil {
+			return alerts, err
+		}
+		for _, f := range window {
+			if f.Timestamp.After(failure.Timestamp) || !matchesReasonCode(rule.ReasonCodes, f.FailureReasonCode) {
+				continue
+			}
+			if project != "" {
+				log, err := paymentLog(f.PaymentLogID)
+				if err != nil {
+					return alerts, err
+				}
+				if log.ProjectID != project {
+					continue
+				}
+			}
+			if src != "" {
+				s, err := source(f)
+				if err != nil {
+					return alerts, err
+				}
+				if s != src {
+					continue
+				}
+			}
+			alert.Failures++
+		}
+		if rule.MinRate > 0 {
+			err := paymentLogsSince(e.Store, since, func(log PaymentLog) {
+				if !log.Created.After(failure.Timestamp) &&
+					(project == "" || log.ProjectID == project) && (src == "" || log.Source == src) {
+					alert.Attempts++
+				}
+			})
+			if err != nil {
+				return alerts, err
+			}
+			if alert.Attempts == 0 || alert.Attempts < rule.MinAttempts {
+				continue
+			}
+			alert.Rate = float64(alert.Failures) / float64(alert.Attempts)
+			if alert.Rate < rule.MinRate {
+				continue
+			}
+		} else if alert.Failures < rule.Threshold {
+			continue
+		}
+		if !e.cooledDown(rule, key, failure.Timestamp) {
+			continue
+		}
+		alerts = append(alerts, alert)
+		e.dispatch(alert)
+	}
+	return alerts, nil
+}
+
+// cooledDown reports whether rule may fire for key at t, and if so records
+// that it has.
+func (e *AlertEngine) cooledDown(rule AlertRule, key string, t time.Time) bool {
+	cooldown := rule.Cooldown
+	if cooldown <= 0 {
+		cooldown = rule.Window
+	}
+	e.Lock()
+	defer e.Unlock()
+	if e.fired == nil {
+		e.fired = map[string]time.Time{}
+	}
+	id := rule.Name + "\x00" + key
+	if last, ok := e.fired[id]; ok && t.Before(last.Add(cooldown)) {
+		return false
+	}
+	e.fired[id] = t
+	return true
+}
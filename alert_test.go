@@ -0,0 +1,237 @@
+package paymentlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type recordingNotifier struct {
+	alerts []Alert
+}
+
+func (n *recordingNotifier) Notify(alert Alert) error {
+	n.alerts = append(n.alerts, alert)
+	return nil
+}
+
+func storeAlertTestPayment(t *testing.T, store LogStore, id, project string, created time.Time) {
+	err := store.StorePaymentLog(PaymentLog{
+		ID:          id,
+		Amount:      100,
+		Source:      "test-source",
+		SourceID:    id,
+		Created:     created,
+		Status:      StatusPending,
+		Currency:    CurrencyUSD,
+		ProjectID:   project,
+		UserID:      "user-id",
+		AccountID:   "account-id",
+		AccountType: "google",
+	})
+	if err != nil {
+		t.Fatalf("Error storing payment log: %s", err)
+	}
+}
+
+func TestAlertRuleValidation(t *testing.T) {
+	rules := []AlertRule{
+		{Window: time.Hour, Threshold: 1},
+		{Name: "no-window", Threshold: 1},
+		{Name: "no-threshold", Window: time.Hour},
+		{Name: "by-reason", Window: time.Hour, Threshold: 1, GroupBy: DimensionReasonCode},
+	}
+	for _, rule := range rules {
+		if _, err := NewAlertEngine(NewMemoryStore(), []AlertRule{rule}); err != InvalidAlertRule {
+			t.Errorf("Expected %+v to be invalid, got %v.", rule, err)
+		}
+	}
+}
+
+func TestAlertingOnFailureCount(t *testing.T) {
+	memory := NewMemoryStore()
+	notifier := &recordingNotifier{}
+	engine, err := NewAlertEngine(memory, []AlertRule{
+		{Name: "declines", Window: 10 * time.Minute, Threshold: 3, ReasonCodes: []string{"card-declined"}},
+	}, notifier)
+	if err != nil {
+		t.Fatalf("Error creating alert engine: %s", err)
+	}
+	store := Wrap(memory, engine.Middleware())
+	start := time.Date(2014, time.March, 1, 12, 0, 0, 0, time.UTC)
+	failures := []struct {
+		offset time.Duration
+		code   string
+	}{
+		{0, "card-declined"},
+		{time.Minute, "card-declined"},
+		{2 * time.Minute, "card-declined"},
+		// within the cooldown
+		{5 * time.Minute, "card-declined"},
+		{11 * time.Minute, "insufficient-funds"},
+		// the first declines have left the window
+		{12 * time.Minute, "card-declined"},
+		{13 * time.Minute, "card-declined"},
+	}
+	for pos, failure := range failures {
+		id := string(rune('a' + pos))
+		storeAlertTestPayment(t, store, id, "project-id", start.Add(failure.offset))
+		err := store.StoreFailureLog(FailureLog{ID: id, PaymentLogID: id, FailureReasonCode: failure.code, Timestamp: start.Add(failure.offset)})
+		if err != nil {
+			t.Fatalf("Error storing failure log: %s", err)
+		}
+	}
+	engine.Close()
+	if len(notifier.alerts) != 2 {
+		t.Fatalf("Expected 2 alerts, got %+v.", notifier.alerts)
+	}
+	if alert := notifier.alerts[0]; alert.Rule != "declines" || alert.Failures != 3 || alert.FailureLog.ID != "c" || !alert.Until.Equal(start.Add(2*time.Minute)) {
+		t.Errorf("Unexpected first alert: %+v", alert)
+	}
+	if alert := notifier.alerts[1]; alert.Failures != 3 || alert.FailureLog.ID != "g" {
+		t.Errorf("Unexpected second alert: %+v", alert)
+	}
+}
+
+// windowedStore fails any scan of every payment log, so only windowed
+// queries work.
+type windowedStore struct {
+	*MemoryStore
+}
+
+func (windowedStore) IteratePaymentLogs(fn func(log PaymentLog) error) error {
+	return errors.New("Unexpected scan of every payment log.")
+}
+
+func TestAlertingOnFailureRateByProject(t *testing.T) {
+	store := windowedStore{NewMemoryStore()}
+	start := time.Date(2014, time.March, 1, 12, 0, 0, 0, time.UTC)
+	for pos := 0; pos < 4; pos++ {
+		storeAlertTestPayment(t, store, "healthy-"+string(rune('a'+pos)), "healthy", start.Add(time.Duration(pos)*time.Minute))
+		storeAlertTestPayment(t, store, "failing-"+string(rune('a'+pos)), "failing", start.Add(time.Duration(pos)*time.Minute))
+	}
+	failures := []FailureLog{
+		{ID: "f1", PaymentLogID: "healthy-a", Timestamp: start.Add(5 * time.Minute)},
+		{ID: "f2", PaymentLogID: "failing-a", Timestamp: start.Add(5 * time.Minute)},
+		{ID: "f3", PaymentLogID: "failing-b", Timestamp: start.Add(6 * time.Minute)},
+	}
+	for _, failure := range failures {
+		if err := store.StoreFailureLog(failure); err != nil {
+			t.Fatalf("Error storing failure log: %s", err)
+		}
+	}
+	engine, err := NewAlertEngine(store, []AlertRule{
+		{Name: "rate", Window: time.Hour, MinRate: 0.5, MinAttempts: 4, GroupBy: DimensionProject},
+		{Name: "other-project", Window: time.Hour, Threshold: 1, ProjectID: "other"},
+	})
+	if err != nil {
+		t.Fatalf("Error creating alert engine: %s", err)
+	}
+	alerts, err := engine.Evaluate(failures[0])
+	if err != nil || len(alerts) != 0 {
+		t.Errorf("Expected no alerts for the healthy project, got %+v, %v.", alerts, err)
+	}
+	alerts, err = engine.Evaluate(failures[2])
+	if err != nil {
+		t.Fatalf("Error evaluating rules: %s", err)
+	}
+	if len(alerts) != 1 {
+		t.Fatalf("Expected 1 alert, got %+v.", alerts)
+	}
+	if alert := alerts[0]; alert.Key != "failing" || alert.Failures != 2 || alert.Attempts != 4 || alert.Rate != 0.5 {
+		t.Errorf("Unexpected alert: %+v", alert)
+	}
+}
+
+type blockingNotifier struct {
+	release chan struct{}
+	alerts  int
+}
+
+func (n *blockingNotifier) Notify(alert Alert) error {
+	<-n.release
+	n.alerts++
+	return nil
+}
+
+func TestSlowNotifiersDontBlockStoring(t *testing.T) {
+	memory := NewMemoryStore()
+	notifier := &blockingNotifier{release: make(chan struct{})}
+	engine, err := NewAlertEngine(memory, []AlertRule{
+		{Name: "any", Window: time.Minute, Threshold: 1, Cooldown: time.Nanosecond},
+	}, notifier)
+	if err != nil {
+		t.Fatalf("Error creating alert engine: %s", err)
+	}
+	store := Wrap(memory, engine.Middleware())
+	start := time.Date(2014, time.March, 1, 12, 0, 0, 0, time.UTC)
+	stored := make(chan error)
+	go func() {
+		for pos := 0; pos < 3; pos++ {
+			id := string(rune('a' + pos))
+			storeAlertTestPayment(t, store, id, "project-id", start.Add(time.Duration(pos)*time.Second))
+			if err := store.StoreFailureLog(FailureLog{ID: id, PaymentLogID: id, Timestamp: start.Add(time.Duration(pos) * time.Second)}); err != nil {
+				stored <- err
+				return
+			}
+		}
+		stored <- nil
+	}()
+	select {
+	case err := <-stored:
+		if err != nil {
+			t.Fatalf("Error storing failure log: %s", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Storing failure logs blocked on the notifier.")
+	}
+	close(notifier.release)
+	engine.Close()
+	if notifier.alerts != 3 {
+		t.Errorf("Expected 3 alerts to be sent, got %d.", notifier.alerts)
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	var received Alert
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Unexpected request: %s %s", r.Method, r.Header.Get("Content-Type"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("Error decoding alert: %s", err)
+		}
+		if received.Rule == "fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+	notifier := NewWebhookNotifier(server.URL)
+	alert := Alert{Rule: "declines", Failures: 3, FailureLog: FailureLog{ID: "failure-id"}}
+	if err := notifier.Notify(alert); err != nil {
+		t.Fatalf("Error sending alert: %s", err)
+	}
+	if received.Rule != alert.Rule || received.Failures != alert.Failures || received.FailureLog.ID != alert.FailureLog.ID {
+		t.Errorf("Expected %+v, got %+v.", alert, received)
+	}
+	if err := notifier.Notify(Alert{Rule: "fail"}); err == nil {
+		t.Errorf("Expected an error for a failed delivery.")
+	}
+}
+
+func TestLogNotifier(t *testing.T) {
+	var buf bytes.Buffer
+	notifier := LogNotifier{Logger: log.New(&buf, "", 0)}
+	if err := notifier.Notify(Alert{Rule: "declines", Key: "project-id", Failures: 3}); err != nil {
+		t.Fatalf("Error logging alert: %s", err)
+	}
+	if !strings.Contains(buf.String(), "alert declines: 3 failures") || !strings.Contains(buf.String(), "for project-id") {
+		t.Errorf("Unexpected log output: %q", buf.String())
+	}
+}
@@ -0,0 +1,178 @@
+package paymentlog
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+const (
+	DefaultAnalyticsBucket = time.Hour
+	MaxAnalyticsBuckets    = 10000
+)
+
+var (
+	InvalidAnalyticsWindow = errors.New("Invalid analytics window.")
+	UnknownDimension       = errors.New("Unknown analytics dimension.")
+)
+
+// Dimension is what failure rates can be grouped by.
+type Dimension string
+
+const (
+	DimensionNone       Dimension = ""
+	DimensionProject    Dimension = "project"
+	DimensionSource     Dimension = "source"
+	DimensionReasonCode Dimension = "reason_code"
+)
+
+func ParseDimension(s string) (Dimension, error) {
+	switch Dimension(s) {
+	case DimensionNone, DimensionProject, DimensionSource, DimensionReasonCode:
+		return Dimension(s), nil
+	}
+	return "", UnknownDimension
+}
+
+type FailureRateOptions struct {
+	// Since and Until are the window to report on. Both are required.
+	Since time.Time
+	Until time.Time
+	// Bucket is the width of each point in the series. The zero value
+	// uses DefaultAnalyticsBucket.
+	Bucket time.Duration
+	// GroupBy splits the report into one series per project, source or
+	// reason code. DimensionNone reports a single series.
+	GroupBy Dimension
+}
+
+// FailureRatePoint covers the bucket starting at Start. Attempts counts the
+// payment logs created in the bucket and Failures the failure logs
+// timestamped in it; Rate is Failures divided by Attempts, or 0 when there
+// were no attempts.
+type FailureRatePoint struct {
+	Start    time.Time `json:"start"`
+	Attempts int       `json:"attempts"`
+	Failures int       `json:"failures"`
+	Rate     float64   `json:"rate"`
+}
+
+// FailureRateSeries has a point for every bucket in the window, including
+// empty ones. Key is the project, source or reason code the series is
+// for, and is empty when the report isn't grouped.
+type FailureRateSeries struct {
+	Key    string             `json:"key"`
+	Points []FailureRatePoint `json:"points"`
+}
+
+// FailureRates reports failure counts and rates over a window as time
+// series, sorted by Key. When grouped by reason code, a series' attempts
+// are every payment attempt in the bucket, since attempts that didn't fail
+// have no reason code.
+func FailureRates(store LogStore, opts FailureRateOptions) ([]FailureRateSeries, error) {
+	if opts.Bucket <= 0 {
+		opts.Bucket = DefaultAnalyticsBucket
+	}
+	if opts.Since.IsZero() || !opts.Until.After(opts.Since) {
+		return nil, InvalidAnalyticsWindow
+	}
+	buckets := int((opts.Until.Sub(opts.Since) + opts.Bucket - 1) / opts.Bucket)
+	if buckets > MaxAnalyticsBuckets {
+		return nil, InvalidAnalyticsWindow
+	}
+	if _, err := ParseDimension(string(opts.GroupBy)); err != nil {
+		return nil, err
+	}
+	series := map[string][]FailureRatePoint{}
+	points := func(key string) []FailureRatePoint {
+		if _, ok := series[key]; !ok {
+			series[key] = make([]FailureRatePoint, buckets)
+			for pos := range series[key] {
+				series[key][pos].Start = opts.Since.Add(time.Duration(pos) * opts.Bucket)
+			}
+		}
+		return series[key]
+	}
+	bucket := func(t time.Time) int {
+		if t.Before(opts.Since) || !t.Before(opts.Until) {
+			return -1
+		}
+		return int(t.Sub(opts.Since) / opts.Bucket)
+	}
+	totals := make([]int, buckets)
+	logs := map[string]PaymentLog{}
+	err := store.IteratePaymentLogs(func(log PaymentLog) error {
+		logs[log.ID] = log
+		pos := bucket(log.Created)
+		if pos < 0 {
+			return nil
+		}
+		totals[pos]++
+		switch opts.GroupBy {
+		case DimensionNone:
+			points("")[pos].Attempts++
+		case DimensionProject:
+			points(log.ProjectID)[pos].Attempts++
+		case DimensionSource:
+			points(log.Source)[pos].Attempts++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = store.IterateFailureLogs(func(failure FailureLog) error {
+		pos := bucket(failure.Timestamp)
+		if pos < 0 {
+			return nil
+		}
+		var key string
+		switch opts.GroupBy {
+		case DimensionProject:
+			key = logs[failure.PaymentLogID].ProjectID
+		case DimensionSource:
+			key = failure.Source
+			if key == "" {
+				key = logs[failure.PaymentLogID].Source
+			}
+		case DimensionReasonCode:
+			key = failure.FailureReasonCode
+		}
+		points(key)[pos].Failures++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if opts.GroupBy == DimensionNone {
+		points("")
+	}
+	results := make([]FailureRateSeries, 0, len(series))
+	for key, points := range series {
+		for pos := range points {
+			if opts.GroupBy == DimensionReasonCode {
+				points[pos].Attempts = totals[pos]
+			}
+			if points[pos].Attempts > 0 {
+				points[pos].Rate = float64(points[pos].Failures) / float64(points[pos].Attempts)
+			}
+		}
+		results = append(results, FailureRateSeries{Key: key, Points: points})
+	}
+	sort.Sort(seriesByKey(results))
+	return results, nil
+}
+
+type seriesByKey []FailureRateSeries
+
+func (s seriesByKey) Len() int {
+	return len(s)
+}
+
+func (s seriesByKey) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+func (s seriesByKey) Less(i, j int) bool {
+	return s[i].Key < s[j].Key
+}
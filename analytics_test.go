@@ -0,0 +1,147 @@
+package paymentlog
+
+import (
+	"testing"
+	"time"
+)
+
+func analyticsTestStore(t *testing.T, start time.Time) *MemoryStore {
+	store := NewMemoryStore()
+	attempts := []struct {
+		id      string
+		project string
+		source  string
+		offset  time.Duration
+	}{
+		{"a", "project-a", SourceBalanced, 0},
+		{"b", "project-a", SourceBalanced, 10 * time.Minute},
+		{"c", "project-b", SourceStripe, 20 * time.Minute},
+		{"d", "project-b", SourceStripe, 30 * time.Minute},
+		{"e", "project-a", SourceStripe, time.Hour + time.Minute},
+		{"f", "project-a", SourceStripe, 5 * time.Hour},
+	}
+	for _, attempt := range attempts {
+		err := store.StorePaymentLog(PaymentLog{
+			ID:          attempt.id,
+			Amount:      100,
+			Source:      attempt.source,
+			SourceID:    attempt.id,
+			Created:     start.Add(attempt.offset),
+			Status:      StatusPending,
+			Currency:    CurrencyUSD,
+			ProjectID:   attempt.project,
+			UserID:      "user-id",
+			AccountID:   "account-id",
+			AccountType: "google",
+		})
+		if err != nil {
+			t.Fatalf("Error storing payment log: %s", err)
+		}
+	}
+	failures := []FailureLog{
+		{ID: "fa", PaymentLogID: "a", FailureReasonCode: "insufficient-funds", Timestamp: start.Add(time.Minute)},
+		{ID: "fc", PaymentLogID: "c", Source: SourceStripe, FailureReasonCode: "card_declined", Timestamp: start.Add(21 * time.Minute)},
+		{ID: "fd", PaymentLogID: "d", Source: SourceStripe, FailureReasonCode: "card_declined", Timestamp: start.Add(time.Hour + 5*time.Minute)},
+	}
+	for _, failure := range failures {
+		if err := store.StoreFailureLog(failure); err != nil {
+			t.Fatalf("Error storing failure log: %s", err)
+		}
+	}
+	return store
+}
+
+func checkSeries(t *testing.T, series FailureRateSeries, key string, attempts, failures []int) {
+	if series.Key != key {
+		t.Errorf("Expected series %q, got %q.", key, series.Key)
+		return
+	}
+	if len(series.Points) != len(attempts) {
+		t.Errorf("Expected %d points for %q, got %+v.", len(attempts), key, series.Points)
+		return
+	}
+	for pos, point := range series.Points {
+		if point.Attempts != attempts[pos] || point.Failures != failures[pos] {
+			t.Errorf("Expected point %d of %q to have %d attempts and %d failures, got %+v.", pos, key, attempts[pos], failures[pos], point)
+		}
+	}
+}
+
+func TestFailureRates(t *testing.T) {
+	start := time.Date(2014, time.March, 1, 12, 0, 0, 0, time.UTC)
+	store := analyticsTestStore(t, start)
+	opts := FailureRateOptions{Since: start, Until: start.Add(3 * time.Hour)}
+	series, err := FailureRates(store, opts)
+	if err != nil {
+		t.Fatalf("Error computing failure rates: %s", err)
+	}
+	if len(series) != 1 {
+		t.Fatalf("Expected 1 series, got %+v.", series)
+	}
+	checkSeries(t, series[0], "", []int{4, 1, 0}, []int{2, 1, 0})
+	if series[0].Points[0].Rate != 0.5 || series[0].Points[2].Rate != 0 {
+		t.Errorf("Unexpected rates: %+v", series[0].Points)
+	}
+	if !series[0].Points[1].Start.Equal(start.Add(time.Hour)) {
+		t.Errorf("Expected the second bucket to start at %s, got %s.", start.Add(time.Hour), series[0].Points[1].Start)
+	}
+
+	opts.GroupBy = DimensionProject
+	series, err = FailureRates(store, opts)
+	if err != nil {
+		t.Fatalf("Error computing failure rates: %s", err)
+	}
+	if len(series) != 2 {
+		t.Fatalf("Expected 2 series, got %+v.", series)
+	}
+	checkSeries(t, series[0], "project-a", []int{2, 1, 0}, []int{1, 0, 0})
+	checkSeries(t, series[1], "project-b", []int{2, 0, 0}, []int{1, 1, 0})
+
+	opts.GroupBy = DimensionSource
+	series, err = FailureRates(store, opts)
+	if err != nil {
+		t.Fatalf("Error computing failure rates: %s", err)
+	}
+	if len(series) != 2 {
+		t.Fatalf("Expected 2 series, got %+v.", series)
+	}
+	checkSeries(t, series[0], SourceBalanced, []int{2, 0, 0}, []int{1, 0, 0})
+	checkSeries(t, series[1], SourceStripe, []int{2, 1, 0}, []int{1, 1, 0})
+
+	opts.GroupBy, opts.Bucket = DimensionReasonCode, 90*time.Minute
+	series, err = FailureRates(store, opts)
+	if err != nil {
+		t.Fatalf("Error computing failure rates: %s", err)
+	}
+	if len(series) != 2 {
+		t.Fatalf("Expected 2 series, got %+v.", series)
+	}
+	checkSeries(t, series[0], "card_declined", []int{5, 0}, []int{2, 0})
+	checkSeries(t, series[1], "insufficient-funds", []int{5, 0}, []int{1, 0})
+	if series[0].Points[0].Rate != 0.4 {
+		t.Errorf("Expected a rate of 0.4, got %f.", series[0].Points[0].Rate)
+	}
+}
+
+func TestFailureRatesWithInvalidOptions(t *testing.T) {
+	start := time.Date(2014, time.March, 1, 12, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	options := []FailureRateOptions{
+		{Until: start},
+		{Since: start, Until: start},
+		{Since: start, Until: start.Add(MaxAnalyticsBuckets*time.Minute + 1), Bucket: time.Minute},
+	}
+	for _, opts := range options {
+		if _, err := FailureRates(store, opts); err != InvalidAnalyticsWindow {
+			t.Errorf("Expected %s for %+v, got %v.", InvalidAnalyticsWindow, opts, err)
+		}
+	}
+	_, err := FailureRates(store, FailureRateOptions{Since: start, Until: start.Add(time.Hour), GroupBy: "user"})
+	if err != UnknownDimension {
+		t.Errorf("Expected %s, got %v.", UnknownDimension, err)
+	}
+	series, err := FailureRates(store, FailureRateOptions{Since: start, Until: start.Add(time.Hour)})
+	if err != nil || len(series) != 1 || len(series[0].Points) != 1 {
+		t.Errorf("Expected an empty series for an empty store, got %+v, %v.", series, err)
+	}
+}
@@ -0,0 +1,249 @@
+package balanced
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"code.whipround.net/paymentlog"
+)
+
+const SignatureHeader = "X-Balanced-Signature"
+
+var MissingEntity = errors.New("Balanced event is missing its entity.")
+
+type Debit struct {
+	ID                string            `json:"id"`
+	Amount            uint              `json:"amount"`
+	Currency          string            `json:"currency"`
+	Status            string            `json:"status"`
+	Description       string            `json:"description"`
+	FailureReason     string            `json:"failure_reason"`
+	FailureReasonCode string            `json:"failure_reason_code"`
+	CreatedAt         time.Time         `json:"created_at"`
+	UpdatedAt         time.Time         `json:"updated_at"`
+	Meta              map[string]string `json:"meta"`
+}
+
+type Refund struct {
+	ID        string    `json:"id"`
+	Amount    uint      `json:"amount"`
+	Currency  string    `json:"currency"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Links     struct {
+		Debit string `json:"debit"`
+	} `json:"links"`
+}
+
+type Event struct {
+	ID         string    `json:"id"`
+	Type       string    `json:"type"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Entity     struct {
+		Debits  []Debit  `json:"debits"`
+		Refunds []Refund `json:"refunds"`
+	} `json:"entity"`
+}
+
+// PaymentLogID returns the ID of the payment log that records a Balanced
+// debit.
+func PaymentLogID(debitID string) string {
+	return paymentlog.SourcePaymentLogID(paymentlog.SourceBalanced, debitID)
+}
+
+func Sign(body []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a SignatureHeader value. Nothing verifies against an empty
+// secret.
+func Verify(body []byte, signature, secret string) bool {
+	if secret == "" {
+		return false
+	}
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+func ParseEvents(body []byte) ([]Event, error) {
+	var payload struct {
+		Events []Event `json:"events"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, err
+	}
+	return payload.Events, nil
+}
+
+func (d Debit) PaymentLog() paymentlog.PaymentLog {
+	return paymentlog.PaymentLog{
+		ID:          PaymentLogID(d.ID),
+		Amount:      d.Amount,
+		Description: d.Description,
+		Source:      paymentlog.SourceBalanced,
+		SourceID:    d.ID,
+		Created:     d.CreatedAt,
+		Updated:     d.UpdatedAt,
+		Status:      Adapter{}.Status(d.Status),
+		Currency:    strings.ToLower(d.Currency),
+		ProjectID:   d.Meta["project_id"],
+		UserID:      d.Meta["user_id"],
+		AccountID:   d.Meta["account_id"],
+		AccountType: d.Meta["account_type"],
+	}
+}
+
+// SourceEvent normalizes a Balanced event. Event types that don't affect
+// payment logs normalize to an empty SourceEvent.
+func (e Event) SourceEvent() (paymentlog.SourceEvent, error) {
+	result := paymentlog.SourceEvent{ID: e.ID}
+	switch e.Type {
+	case "debit.created", "debit.updated", "debit.succeeded", "debit.failed":
+		if len(e.Entity.Debits) == 0 {
+			return result, MissingEntity
+		}
+		debit := e.Entity.Debits[0]
+		log := debit.PaymentLog()
+		result.PaymentLogID = log.ID
+		result.PaymentLog = &log
+		result.Change = &paymentlog.PaymentLogChange{
+			Status:  &log.Status,
+			Updated: &log.Updated,
+		}
+		if log.Status == paymentlog.StatusFailed {
+			result.Failure = &paymentlog.FailureLog{
+				ID:                e.ID,
+				PaymentLogID:      log.ID,
+				Source:            paymentlog.SourceBalanced,
+				FailureReason:     debit.FailureReason,
+				FailureReasonCode: debit.FailureReasonCode,
+				Timestamp:         e.OccurredAt,
+			}
+		}
+	case "refund.created", "refund.updated", "refund.succeeded", "refund.failed":
+		if len(e.Entity.Refunds) == 0 {
+			return result, MissingEntity
+		}
+		refund := e.Entity.Refunds[0]
+		result.PaymentLogID = PaymentLogID(refund.Links.Debit)
+		switch refund.Status {
+		case "succeeded":
+			// Balanced only reports the amount of this refund, so a debit
+			// refunded in several parts stays succeeded
+			result.RefundedAmount = refund.Amount
+			status := paymentlog.StatusRefunded
+			updated := refund.UpdatedAt
+			result.Change = &paymentlog.PaymentLogChange{
+				Status:  &status,
+				Updated: &updated,
+			}
+		case "failed":
+			result.Failure = &paymentlog.FailureLog{
+				ID:                e.ID,
+				PaymentLogID:      result.PaymentLogID,
+				Source:            paymentlog.SourceBalanced,
+				FailureReason:     "Refund " + refund.ID + " failed.",
+				FailureReasonCode: "refund-failed",
+				Timestamp:         e.OccurredAt,
+			}
+		}
+	}
+	return result, nil
+}
+
+// Apply records a Balanced event in store. Applying the same event more than
+// once has the same effect as applying it once.
+func Apply(store paymentlog.LogStore, event Event) error {
+	normalized, err := event.SourceEvent()
+	if err != nil {
+		return err
+	}
+	return paymentlog.ApplySourceEvent(store, normalized)
+}
+
+// Adapter is the paymentlog.SourceAdapter for Balanced. Requests must carry
+// the hex encoded HMAC-SHA256 of their body in SignatureHeader.
+type Adapter struct{}
+
+func (Adapter) Source() string {
+	return paymentlog.SourceBalanced
+}
+
+func (Adapter) Verify(payload []byte, header http.Header, secret string) bool {
+	return Verify(payload, header.Get(SignatureHeader), secret)
+}
+
+func (Adapter) ParseEvents(payload []byte) ([]paymentlog.SourceEvent, error) {
+	events, err := ParseEvents(payload)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]paymentlog.SourceEvent, 0, len(events))
+	for _, event := range events {
+		normalized, err := event.SourceEvent()
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, normalized)
+	}
+	return results, nil
+}
+
+func (Adapter) Status(processorStatus string) string {
+	switch processorStatus {
+	case "pending":
+		return paymentlog.StatusPending
+	case "succeeded":
+		return paymentlog.StatusSucceeded
+	case "failed":
+		return paymentlog.StatusFailed
+	}
+	return ""
+}
+
+// FailureCategories maps Balanced failure reason codes onto failure
+// categories.
+var FailureCategories = map[string]paymentlog.FailureCategory{
+	"insufficient-funds":                    paymentlog.CategoryInsufficientFunds,
+	"card-declined":                         paymentlog.CategoryCardDeclined,
+	"authorization-failed":                  paymentlog.CategoryCardDeclined,
+	"card-not-validated":                    paymentlog.CategoryCardDeclined,
+	"card-expired":                          paymentlog.CategoryExpiredCard,
+	"account-closed":                        paymentlog.CategoryInvalidAccount,
+	"bank-account-authentication-forbidden": paymentlog.CategoryInvalidAccount,
+	"invalid-routing-number":                paymentlog.CategoryInvalidAccount,
+	"no-account":                            paymentlog.CategoryInvalidAccount,
+	"card-reported-stolen":                  paymentlog.CategoryFraudSuspected,
+	"suspected-fraud":                       paymentlog.CategoryFraudSuspected,
+	"processor-error":                       paymentlog.CategoryProcessorError,
+	"unexpected-payload":                    paymentlog.CategoryProcessorError,
+	"timeout":                               paymentlog.CategoryNetworkError,
+	"refund-failed":                         paymentlog.CategoryRefundFailed,
+}
+
+func (Adapter) FailureCategory(code string) paymentlog.FailureCategory {
+	return FailureCategories[code]
+}
+
+func init() {
+	paymentlog.RegisterSource(Adapter{})
+}
+
+// NewHandler returns a handler for Balanced webhook callbacks.
+func NewHandler(store paymentlog.LogStore, secret string) *paymentlog.WebhookHandler {
+	return paymentlog.NewWebhookHandler(store, Adapter{}, secret)
+}
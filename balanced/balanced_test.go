@@ -0,0 +1,182 @@
+package balanced
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"code.whipround.net/paymentlog"
+)
+
+const testSecret = "shh"
+
+func fixture(t *testing.T, name string) []byte {
+	body, err := ioutil.ReadFile(filepath.Join("testdata", name+".json"))
+	if err != nil {
+		t.Fatalf("Error reading fixture %s: %s", name, err)
+	}
+	return body
+}
+
+func deliver(t *testing.T, handler http.Handler, body []byte, signature string) *httptest.ResponseRecorder {
+	r, err := http.NewRequest("POST", "/webhooks/balanced", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("Error creating request: %s", err)
+	}
+	r.Header.Set(SignatureHeader, signature)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestAdapterIsRegistered(t *testing.T) {
+	adapter, err := paymentlog.SourceAdapterFor(paymentlog.SourceBalanced)
+	if err != nil {
+		t.Fatalf("Error finding adapter: %s", err)
+	}
+	if _, ok := adapter.(Adapter); !ok {
+		t.Errorf("Expected the balanced adapter, got %T.", adapter)
+	}
+}
+
+func TestDebitLifecycle(t *testing.T) {
+	store := paymentlog.NewMemoryStore()
+	handler := NewHandler(store, testSecret)
+	for _, name := range []string{"debit_created", "debit_succeeded", "refund_succeeded"} {
+		body := fixture(t, name)
+		w := deliver(t, handler, body, Sign(body, testSecret))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected status %d, got %d: %s", name, http.StatusOK, w.Code, w.Body.String())
+		}
+	}
+	log, err := store.GetPaymentLog(PaymentLogID("WD5SwXr9jcCfCmmjTH5MCMFD"))
+	if err != nil {
+		t.Fatalf("Error retrieving payment log: %s", err)
+	}
+	expectation := paymentlog.PaymentLog{
+		ID:          "balanced-WD5SwXr9jcCfCmmjTH5MCMFD",
+		Amount:      1250,
+		Description: "Contribution to project-id",
+		Source:      paymentlog.SourceBalanced,
+		SourceID:    "WD5SwXr9jcCfCmmjTH5MCMFD",
+		Created:     time.Date(2014, time.March, 1, 12, 0, 0, 0, time.UTC),
+		Updated:     time.Date(2014, time.March, 2, 9, 30, 0, 0, time.UTC),
+		Status:      paymentlog.StatusRefunded,
+		Currency:    paymentlog.CurrencyUSD,
+		ProjectID:   "project-id",
+		UserID:      "user-id",
+		AccountID:   "account-id",
+		AccountType: "google",
+	}
+	if log.ID != expectation.ID || log.Amount != expectation.Amount || log.Status != expectation.Status ||
+		log.Currency != expectation.Currency || log.ProjectID != expectation.ProjectID ||
+		!log.Created.Equal(expectation.Created) || !log.Updated.Equal(expectation.Updated) {
+		t.Errorf("Expected %+v, got %+v.", expectation, log)
+	}
+}
+
+func TestReplayedEventsAreIdempotent(t *testing.T) {
+	store := paymentlog.NewMemoryStore()
+	handler := NewHandler(store, testSecret)
+	for _, name := range []string{"debit_created", "refund_succeeded", "debit_succeeded", "debit_created", "refund_succeeded"} {
+		body := fixture(t, name)
+		w := deliver(t, handler, body, Sign(body, testSecret))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected status %d, got %d: %s", name, http.StatusOK, w.Code, w.Body.String())
+		}
+	}
+	log, err := store.GetPaymentLog(PaymentLogID("WD5SwXr9jcCfCmmjTH5MCMFD"))
+	if err != nil {
+		t.Fatalf("Error retrieving payment log: %s", err)
+	}
+	if log.Status != paymentlog.StatusRefunded {
+		t.Errorf("Expected a replayed succeeded event not to undo the refund, got status %s.", log.Status)
+	}
+}
+
+func TestPartialRefund(t *testing.T) {
+	store := paymentlog.NewMemoryStore()
+	handler := NewHandler(store, testSecret)
+	for _, name := range []string{"debit_created", "debit_succeeded", "refund_partial"} {
+		body := fixture(t, name)
+		w := deliver(t, handler, body, Sign(body, testSecret))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected status %d, got %d: %s", name, http.StatusOK, w.Code, w.Body.String())
+		}
+	}
+	log, err := store.GetPaymentLog(PaymentLogID("WD5SwXr9jcCfCmmjTH5MCMFD"))
+	if err != nil {
+		t.Fatalf("Error retrieving payment log: %s", err)
+	}
+	if log.Status != paymentlog.StatusSucceeded {
+		t.Errorf("Expected a partial refund to leave the debit succeeded, got status %s.", log.Status)
+	}
+}
+
+func TestFailedDebit(t *testing.T) {
+	store := paymentlog.NewMemoryStore()
+	handler := NewHandler(store, testSecret)
+	for _, name := range []string{"debit_created", "debit_failed", "debit_failed"} {
+		body := fixture(t, name)
+		w := deliver(t, handler, body, Sign(body, testSecret))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected status %d, got %d: %s", name, http.StatusOK, w.Code, w.Body.String())
+		}
+	}
+	log, err := store.GetPaymentLog(PaymentLogID("WD5SwXr9jcCfCmmjTH5MCMFD"))
+	if err != nil {
+		t.Fatalf("Error retrieving payment log: %s", err)
+	}
+	if log.Status != paymentlog.StatusFailed {
+		t.Errorf("Expected status %s, got %s.", paymentlog.StatusFailed, log.Status)
+	}
+	failures, err := store.ListFailureLogs(10, 0)
+	if err != nil {
+		t.Fatalf("Error listing failure logs: %s", err)
+	}
+	if len(failures) != 1 {
+		t.Fatalf("Expected 1 failure log, got %+v.", failures)
+	}
+	if failures[0].PaymentLogID != log.ID || failures[0].FailureReason != "R01: Insufficient funds" || failures[0].FailureReasonCode != "insufficient-funds" {
+		t.Errorf("Unexpected failure log: %+v", failures[0])
+	}
+	if category := failures[0].Category(); category != paymentlog.CategoryInsufficientFunds {
+		t.Errorf("Expected category %s, got %s.", paymentlog.CategoryInsufficientFunds, category)
+	}
+}
+
+func TestRejectingUnsignedEvents(t *testing.T) {
+	store := paymentlog.NewMemoryStore()
+	handler := NewHandler(store, testSecret)
+	body := fixture(t, "debit_created")
+	for _, signature := range []string{"", "not hex", Sign(body, "wrong secret")} {
+		w := deliver(t, handler, body, signature)
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("Expected status %d for signature %q, got %d.", http.StatusUnauthorized, signature, w.Code)
+		}
+	}
+	logs, _ := store.ListPaymentLogs(10, 0)
+	if len(logs) != 0 {
+		t.Errorf("Expected unsigned events to be ignored, got %+v.", logs)
+	}
+}
+
+func TestRejectingEventsWithoutSecret(t *testing.T) {
+	store := paymentlog.NewMemoryStore()
+	handler := NewHandler(store, "")
+	body := fixture(t, "debit_created")
+	if w := deliver(t, handler, body, Sign(body, "")); w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status %d without a secret, got %d.", http.StatusInternalServerError, w.Code)
+	}
+	if Verify(body, Sign(body, ""), "") {
+		t.Errorf("Expected an empty secret not to verify.")
+	}
+	logs, _ := store.ListPaymentLogs(10, 0)
+	if len(logs) != 0 {
+		t.Errorf("Expected events to be refused without a secret, got %+v.", logs)
+	}
+}
@@ -0,0 +1,112 @@
+package paymentlog
+
+import "errors"
+
+// FailureCategory is a processor-independent classification of a
+// FailureLog's FailureReasonCode.
+type FailureCategory string
+
+const (
+	CategoryUnknown           FailureCategory = "unknown"
+	CategoryInsufficientFunds FailureCategory = "insufficient_funds"
+	CategoryCardDeclined      FailureCategory = "card_declined"
+	CategoryExpiredCard       FailureCategory = "expired_card"
+	CategoryInvalidAccount    FailureCategory = "invalid_account"
+	CategoryFraudSuspected    FailureCategory = "fraud_suspected"
+	CategoryProcessorError    FailureCategory = "processor_error"
+	CategoryNetworkError      FailureCategory = "network_error"
+	CategoryRefundFailed      FailureCategory = "refund_failed"
+)
+
+var FailureCategories = []FailureCategory{
+	CategoryUnknown,
+	CategoryInsufficientFunds,
+	CategoryCardDeclined,
+	CategoryExpiredCard,
+	CategoryInvalidAccount,
+	CategoryFraudSuspected,
+	CategoryProcessorError,
+	CategoryNetworkError,
+	CategoryRefundFailed,
+}
+
+var UnknownFailureCategory = errors.New("Unknown failure category.")
+
+func ParseFailureCategory(s string) (FailureCategory, error) {
+	for _, category := range FailureCategories {
+		if string(category) == s {
+			return category, nil
+		}
+	}
+	return "", UnknownFailureCategory
+}
+
+// Category classifies the failure using the mapping table of the adapter
+// for its Source. Failures without a registered source, or with codes the
+// adapter doesn't know, are CategoryUnknown. Failures logged before Source
+// was recorded need ResolveFailureSource first.
+func (f FailureLog) Category() FailureCategory {
+	adapter, err := SourceAdapterFor(f.Source)
+	if err != nil {
+		return CategoryUnknown
+	}
+	category := adapter.FailureCategory(f.FailureReasonCode)
+	if category == "" {
+		return CategoryUnknown
+	}
+	return category
+}
+
+// ResolveFailureSource fills in a failure's missing Source from its
+// payment log, the way failures logged before Source was recorded are
+// attributed elsewhere. The failure is returned unchanged if its payment
+// log can't be found.
+func ResolveFailureSource(store LogStore, failure FailureLog) FailureLog {
+	if failure.Source != "" || failure.PaymentLogID == "" {
+		return failure
+	}
+	if log, err := store.GetPaymentLog(failure.PaymentLogID); err == nil {
+		failure.Source = log.Source
+	}
+	return failure
+}
+
+// CategoryFilter matches failures by Category, so failures without a
+// Source should be passed through ResolveFailureSource first.
+func CategoryFilter(category FailureCategory) FailureLogFilter {
+	return func(failure FailureLog) bool {
+		return failure.Category() == category
+	}
+}
+
+// ListFailureLogsByCategory lists the failure logs in category, newest
+// first. A num of 0 or less lists all of them.
+func ListFailureLogsByCategory(store LogStore, category FailureCategory, num, offset int) ([]FailureLog, error) {
+	results := []FailureLog{}
+	matches := 0
+	sources := map[string]string{}
+	err := store.IterateFailureLogs(func(failure FailureLog) error {
+		resolved := failure
+		if resolved.Source == "" {
+			source, ok := sources[failure.PaymentLogID]
+			if !ok {
+				source = ResolveFailureSource(store, failure).Source
+				sources[failure.PaymentLogID] = source
+			}
+			resolved.Source = source
+		}
+		if resolved.Category() != category {
+			return nil
+		}
+		matches++
+		if matches <= offset {
+			return nil
+		}
+		results = append(results, failure)
+		if num > 0 && len(results) >= num {
+			return StopIteration
+		}
+		return nil
+	})
+	return results, err
+}
@@ -0,0 +1,98 @@
+package paymentlog
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParsingFailureCategories(t *testing.T) {
+	for _, category := range FailureCategories {
+		result, err := ParseFailureCategory(string(category))
+		if err != nil || result != category {
+			t.Errorf("Expected %s to parse, got %s (%v).", category, result, err)
+		}
+	}
+	if _, err := ParseFailureCategory("bogus"); err != UnknownFailureCategory {
+		t.Errorf("Expected %s, got %v.", UnknownFailureCategory, err)
+	}
+}
+
+func TestCategorizingFailures(t *testing.T) {
+	if _, err := SourceAdapterFor("test-source"); err == UnknownSource {
+		RegisterSource(testAdapter{})
+	}
+	failures := map[FailureLog]FailureCategory{
+		FailureLog{Source: "test-source", FailureReasonCode: "fraud_suspected"}: CategoryFraudSuspected,
+		FailureLog{Source: "test-source", FailureReasonCode: ""}:                CategoryUnknown,
+		FailureLog{Source: "no-such-source", FailureReasonCode: "500"}:          CategoryUnknown,
+	}
+	for failure, expectation := range failures {
+		if result := failure.Category(); result != expectation {
+			t.Errorf("Expected %+v to be %s, got %s.", failure, expectation, result)
+		}
+	}
+}
+
+func TestListingFailureLogsByCategory(t *testing.T) {
+	if _, err := SourceAdapterFor("test-source"); err == UnknownSource {
+		RegisterSource(testAdapter{})
+	}
+	store := NewMemoryStore()
+	now := time.Now()
+	codes := []string{"network_error", "expired_card", "network_error", "network_error"}
+	for pos, code := range codes {
+		err := store.StoreFailureLog(FailureLog{
+			ID:                string('a' + rune(pos)),
+			Source:            "test-source",
+			FailureReasonCode: code,
+			Timestamp:         now.Add(time.Duration(pos) * time.Minute),
+		})
+		if err != nil {
+			t.Fatalf("Error storing failure log: %s", err)
+		}
+	}
+	results, err := ListFailureLogsByCategory(store, CategoryNetworkError, 2, 1)
+	if err != nil {
+		t.Fatalf("Error listing failure logs: %s", err)
+	}
+	if len(results) != 2 || results[0].ID != "c" || results[1].ID != "a" {
+		t.Errorf("Expected c and a, got %+v.", results)
+	}
+	results, err = ListFailureLogsByCategory(store, CategoryExpiredCard, 0, 0)
+	if err != nil {
+		t.Fatalf("Error listing failure logs: %s", err)
+	}
+	if len(results) != 1 || results[0].ID != "b" {
+		t.Errorf("Expected b, got %+v.", results)
+	}
+}
+
+func TestCategorizingFailuresWithoutSource(t *testing.T) {
+	if _, err := SourceAdapterFor("test-source"); err == UnknownSource {
+		RegisterSource(testAdapter{})
+	}
+	store := NewMemoryStore()
+	now := time.Now()
+	if err := store.StorePaymentLog(PaymentLog{ID: "payment", Source: "test-source", Created: now}); err != nil {
+		t.Fatalf("Error storing payment log: %s", err)
+	}
+	// failures logged before Source was recorded
+	failure := FailureLog{ID: "a", PaymentLogID: "payment", FailureReasonCode: "network_error", Timestamp: now}
+	if err := store.StoreFailureLog(failure); err != nil {
+		t.Fatalf("Error storing failure log: %s", err)
+	}
+	if category := ResolveFailureSource(store, failure).Category(); category != CategoryNetworkError {
+		t.Errorf("Expected %s, got %s.", CategoryNetworkError, category)
+	}
+	results, err := ListFailureLogsByCategory(store, CategoryNetworkError, 0, 0)
+	if err != nil {
+		t.Fatalf("Error listing failure logs: %s", err)
+	}
+	if len(results) != 1 || results[0].ID != "a" {
+		t.Errorf("Expected a, got %+v.", results)
+	}
+	schedule, err := NewRetryScheduler(store, RetryPolicy{}).Schedule(failure)
+	if err != nil || schedule.State != RetryScheduled {
+		t.Errorf("Expected the failure to be retried, got %+v, %v.", schedule, err)
+	}
+}
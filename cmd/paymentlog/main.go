@@ -0,0 +1,436 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"code.whipround.net/paymentlog"
+	"code.whipround.net/paymentlog/httpapi"
+)
+
+const usage = `usage: paymentlog [flags] <command> [arguments]
+
+commands:
+  get <id>                   show a payment log
+  list [flags]               list payment logs, newest first
+  failures <payment id>      show the failures for a payment log
+  set-status <id> <status>   change the status of a payment log
+  migrate [flags]            copy every log into another backend
+
+flags:
+`
+
+var (
+	UnknownFormat  = errors.New("Unknown output format.")
+	UnknownCommand = errors.New("Unknown command.")
+	MissingBackend = errors.New("No payment log backend configured; pass -server or -snapshot.")
+)
+
+type usageError struct {
+	msg string
+}
+
+func (u usageError) Error() string {
+	return u.msg
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		if _, ok := err.(usageError); ok {
+			os.Exit(2)
+		}
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("paymentlog", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	server := flags.String("server", os.Getenv("PAYMENTLOG_SERVER"), "base URL of a payment log HTTP API")
+	timeout := flags.Duration("timeout", httpapi.DefaultTimeout, "timeout for requests to the server")
+	snapshot := flags.String("snapshot", "", "path to a MemoryStore snapshot to use instead of a server")
+	format := flags.String("format", "table", "output format: table, json, jsonl or csv")
+	flags.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return usageError{err.Error()}
+	}
+	if flags.NArg() < 1 {
+		flags.Usage()
+		return usageError{"No command given."}
+	}
+	if *format != "table" && *format != "json" && *format != "jsonl" && *format != "csv" {
+		return UnknownFormat
+	}
+	switch {
+	case *server != "" && flags.Arg(0) == "migrate":
+		return migrate(httpapi.NewClient(*server, *timeout), flags.Args()[1:], *timeout, stdout, stderr)
+	case *server != "":
+		return runCommand(httpapi.NewClient(*server, *timeout), flags.Arg(0), flags.Args()[1:], *format, stdout, stderr)
+	case *snapshot != "" && flags.Arg(0) == "migrate":
+		store, err := loadSnapshot(*snapshot)
+		if err != nil {
+			return err
+		}
+		return migrate(store, flags.Args()[1:], *timeout, stdout, stderr)
+	case *snapshot != "":
+		store, err := loadSnapshot(*snapshot)
+		if err != nil {
+			return err
+		}
+		if err := runCommand(store, flags.Arg(0), flags.Args()[1:], *format, stdout, stderr); err != nil {
+			return err
+		}
+		if mutatingCommands[flags.Arg(0)] {
+			return saveSnapshot(*snapshot, store)
+		}
+		return nil
+	}
+	return MissingBackend
+}
+
+var mutatingCommands = map[string]bool{
+	"set-status": true,
+}
+
+func loadSnapshot(path string) (*paymentlog.MemoryStore, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return paymentlog.LoadMemoryStore(f)
+}
+
+func saveSnapshot(path string, store *paymentlog.MemoryStore) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if err := store.Snapshot(f); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func runCommand(store paymentlog.LogStore, command string, args []string, format string, stdout, stderr io.Writer) error {
+	switch command {
+	case "get":
+		if len(args) != 1 {
+			return usageError{"usage: paymentlog get <id>"}
+		}
+		log, err := store.GetPaymentLog(args[0])
+		if err != nil {
+			return err
+		}
+		return writePaymentLogs(stdout, format, []paymentlog.PaymentLog{log})
+	case "list":
+		return list(store, args, format, stdout, stderr)
+	case "failures":
+		if len(args) != 1 {
+			return usageError{"usage: paymentlog failures <payment id>"}
+		}
+		// the store can't list failures by payment, so fetch them all in
+		// one call rather than a page at a time
+		all, err := store.ListFailureLogs(0, 0)
+		if err != nil {
+			return err
+		}
+		failures := make([]paymentlog.FailureLog, 0)
+		matches := paymentlog.PaymentLogIDFilter(args[0])
+		for _, failure := range all {
+			if matches(failure) {
+				failures = append(failures, failure)
+			}
+		}
+		return writeFailureLogs(stdout, format, failures)
+	case "set-status":
+		if len(args) != 2 {
+			return usageError{"usage: paymentlog set-status <id> <status>"}
+		}
+		now := time.Now()
+		err := store.UpdatePaymentLog(args[0], paymentlog.PaymentLogChange{
+			Status:  &args[1],
+			Updated: &now,
+		})
+		if err != nil {
+			return err
+		}
+		log, err := store.GetPaymentLog(args[0])
+		if err != nil {
+			return err
+		}
+		return writePaymentLogs(stdout, format, []paymentlog.PaymentLog{log})
+	}
+	return UnknownCommand
+}
+
+func list(store paymentlog.LogStore, args []string, format string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("list", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	project := flags.String("project", "", "only list payment logs for this project ID")
+	user := flags.String("user", "", "only list payment logs for this user ID")
+	since := flags.String("since", "", "only list payment logs created at or after this RFC 3339 time")
+	until := flags.String("until", "", "only list payment logs created before this RFC 3339 time")
+	num := flags.Int("num", 0, "maximum number of payment logs to list; 0 lists all")
+	if err := flags.Parse(args); err != nil {
+		return usageError{err.Error()}
+	}
+	var sinceTime, untilTime time.Time
+	var err error
+	if *since != "" {
+		sinceTime, err = time.Parse(time.RFC3339, *since)
+		if err != nil {
+			return usageError{"Invalid -since time: " + err.Error()}
+		}
+	}
+	if *until != "" {
+		untilTime, err = time.Parse(time.RFC3339, *until)
+		if err != nil {
+			return usageError{"Invalid -until time: " + err.Error()}
+		}
+	}
+	filters := []paymentlog.PaymentLogFilter{}
+	if *since != "" || *until != "" {
+		filters = append(filters, paymentlog.CreatedFilter(sinceTime, untilTime))
+	}
+	// let the store select by project or user, so only matching logs are
+	// fetched from a server
+	var logs []paymentlog.PaymentLog
+	switch {
+	case *project != "" && *user != "":
+		filters = append(filters, paymentlog.UserFilter(*user))
+		fallthrough
+	case *project != "":
+		logs, err = listAll(*num, filters, func(num int) ([]paymentlog.PaymentLog, error) {
+			return store.ListPaymentLogsByProject(*project, num, 0)
+		})
+	case *user != "":
+		logs, err = listAll(*num, filters, func(num int) ([]paymentlog.PaymentLog, error) {
+			return store.ListPaymentLogsByUser(*user, num, 0)
+		})
+	default:
+		logs = make([]paymentlog.PaymentLog, 0)
+		err = store.IteratePaymentLogs(func(log paymentlog.PaymentLog) error {
+			if !matchesFilters(log, filters) {
+				return nil
+			}
+			logs = append(logs, log)
+			if *num > 0 && len(logs) >= *num {
+				return paymentlog.StopIteration
+			}
+			return nil
+		})
+	}
+	if err != nil {
+		return err
+	}
+	return writePaymentLogs(stdout, format, logs)
+}
+
+// listAll lists payment logs with list, keeping the first num that match
+// every filter. Filtered lists have to fetch everything.
+func listAll(num int, filters []paymentlog.PaymentLogFilter, list func(num int) ([]paymentlog.PaymentLog, error)) ([]paymentlog.PaymentLog, error) {
+	fetch := num
+	if len(filters) > 0 {
+		fetch = 0
+	}
+	logs, err := list(fetch)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]paymentlog.PaymentLog, 0, len(logs))
+	for _, log := range logs {
+		if num > 0 && len(results) >= num {
+			break
+		}
+		if matchesFilters(log, filters) {
+			results = append(results, log)
+		}
+	}
+	return results, nil
+}
+
+func matchesFilters(log paymentlog.PaymentLog, filters []paymentlog.PaymentLogFilter) bool {
+	for _, filter := range filters {
+		if !filter(log) {
+			return false
+		}
+	}
+	return true
+}
+
+func migrate(src paymentlog.LogStore, args []string, timeout time.Duration, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	toServer := flags.String("to-server", "", "base URL of the payment log HTTP API to copy into")
+	toSnapshot := flags.String("to-snapshot", "", "path of the MemoryStore snapshot to copy into; created if it doesn't exist")
+	checkpointPath := flags.String("checkpoint", "", "file to record progress in, and resume from if it exists")
+	batch := flags.Int("batch", paymentlog.DefaultMigrationBatchSize, "number of logs to copy per batch")
+	if err := flags.Parse(args); err != nil {
+		return usageError{err.Error()}
+	}
+	var dst paymentlog.LogStore
+	var save func() error
+	switch {
+	case *toServer != "" && *toSnapshot != "":
+		return usageError{"Only one of -to-server and -to-snapshot may be specified."}
+	case *toServer != "":
+		dst = httpapi.NewClient(*toServer, timeout)
+		save = func() error { return nil }
+	case *toSnapshot != "":
+		store, err := loadSnapshot(*toSnapshot)
+		if os.IsNotExist(err) {
+			store, err = paymentlog.NewMemoryStore(), nil
+		}
+		if err != nil {
+			return err
+		}
+		dst = store
+		save = func() error { return saveSnapshot(*toSnapshot, store) }
+	default:
+		return usageError{"One of -to-server and -to-snapshot must be specified."}
+	}
+	opts := paymentlog.MigrationOptions{BatchSize: *batch}
+	if *checkpointPath != "" {
+		checkpoint, err := ioutil.ReadFile(*checkpointPath)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if err == nil {
+			if err := json.Unmarshal(checkpoint, &opts.Checkpoint); err != nil {
+				return err
+			}
+		}
+		opts.OnCheckpoint = func(checkpoint paymentlog.MigrationCheckpoint) error {
+			if err := save(); err != nil {
+				return err
+			}
+			encoded, err := json.Marshal(checkpoint)
+			if err != nil {
+				return err
+			}
+			return ioutil.WriteFile(*checkpointPath, encoded, 0644)
+		}
+	}
+	result, err := paymentlog.Migrate(src, dst, opts)
+	if saveErr := save(); err == nil {
+		err = saveErr
+	}
+	fmt.Fprintf(stdout, "payment logs: %d copied, %d already present, %d in source, %d in destination\n", result.PaymentLogsCopied, result.PaymentLogsSkipped, result.SourcePaymentLogs, result.DestinationPaymentLogs)
+	fmt.Fprintf(stdout, "failure logs: %d copied, %d already present, %d in source, %d in destination\n", result.FailureLogsCopied, result.FailureLogsSkipped, result.SourceFailureLogs, result.DestinationFailureLogs)
+	for _, id := range result.MismatchedPaymentLogs {
+		fmt.Fprintf(stdout, "mismatched payment log: %s\n", id)
+	}
+	for _, id := range result.MismatchedFailureLogs {
+		fmt.Fprintf(stdout, "mismatched failure log: %s\n", id)
+	}
+	return err
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+var paymentLogColumns = []string{"ID", "AMOUNT", "CURRENCY", "STATUS", "PROJECT", "USER", "SOURCE", "SOURCE ID", "CREATED", "UPDATED", "DESCRIPTION"}
+
+func paymentLogRow(log paymentlog.PaymentLog) []string {
+	return []string{log.ID, paymentlog.FormatAmount(log.Amount), log.Currency, log.Status, log.ProjectID, log.UserID, log.Source, log.SourceID, formatTime(log.Created), formatTime(log.Updated), log.Description}
+}
+
+var failureLogColumns = []string{"ID", "PAYMENT LOG", "CODE", "REASON", "TIMESTAMP", "STATE"}
+
+func failureLogRow(failure paymentlog.FailureLog) []string {
+	return []string{failure.ID, failure.PaymentLogID, failure.FailureReasonCode, failure.FailureReason, formatTime(failure.Timestamp), failure.CurrentState()}
+}
+
+func writePaymentLogs(w io.Writer, format string, logs []paymentlog.PaymentLog) error {
+	var enc paymentlog.PaymentLogEncoder
+	switch format {
+	case "json":
+		return writeJSON(w, logs)
+	case "jsonl":
+		enc = paymentlog.NewPaymentLogJSONLinesEncoder(w)
+	case "csv":
+		enc = paymentlog.NewPaymentLogCSVEncoder(w)
+	default:
+		rows := make([][]string, 0, len(logs))
+		for _, log := range logs {
+			rows = append(rows, paymentLogRow(log))
+		}
+		return writeTable(w, paymentLogColumns, rows)
+	}
+	for _, log := range logs {
+		if err := enc.Encode(log); err != nil {
+			return err
+		}
+	}
+	return enc.Flush()
+}
+
+func writeFailureLogs(w io.Writer, format string, failures []paymentlog.FailureLog) error {
+	var enc paymentlog.FailureLogEncoder
+	switch format {
+	case "json":
+		return writeJSON(w, failures)
+	case "jsonl":
+		enc = paymentlog.NewFailureLogJSONLinesEncoder(w)
+	case "csv":
+		enc = paymentlog.NewFailureLogCSVEncoder(w)
+	default:
+		rows := make([][]string, 0, len(failures))
+		for _, failure := range failures {
+			rows = append(rows, failureLogRow(failure))
+		}
+		return writeTable(w, failureLogColumns, rows)
+	}
+	for _, failure := range failures {
+		if err := enc.Encode(failure); err != nil {
+			return err
+		}
+	}
+	return enc.Flush()
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", out)
+	return err
+}
+
+func writeTable(w io.Writer, header []string, rows [][]string) error {
+	out := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	for _, row := range append([][]string{header}, rows...) {
+		for pos, col := range row {
+			if pos > 0 {
+				fmt.Fprint(out, "\t")
+			}
+			fmt.Fprint(out, col)
+		}
+		fmt.Fprint(out, "\n")
+	}
+	return out.Flush()
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"code.whipround.net/paymentlog"
+	"code.whipround.net/paymentlog/httpapi"
+)
+
+func testStore(t *testing.T) *paymentlog.MemoryStore {
+	store := paymentlog.NewMemoryStore()
+	created := time.Date(2014, time.March, 1, 12, 0, 0, 0, time.UTC)
+	for pos, id := range []string{"id1", "id2", "id3"} {
+		err := store.StorePaymentLog(paymentlog.PaymentLog{
+			ID:          id,
+			Amount:      uint(1250 * (pos + 1)),
+			Source:      paymentlog.SourceBalanced,
+			SourceID:    "balanced-" + id,
+			Created:     created.Add(time.Duration(pos) * time.Hour),
+			Status:      paymentlog.StatusPending,
+			Currency:    paymentlog.CurrencyUSD,
+			ProjectID:   "project-id",
+			UserID:      "user-" + id,
+			AccountID:   "account-id",
+			AccountType: "google",
+		})
+		if err != nil {
+			t.Fatalf("Error storing payment log: %s", err)
+		}
+	}
+	err := store.StoreFailureLog(paymentlog.FailureLog{
+		ID:                "failure-id",
+		PaymentLogID:      "id2",
+		FailureReason:     "card declined",
+		FailureReasonCode: "card-declined",
+		Timestamp:         created,
+	})
+	if err != nil {
+		t.Fatalf("Error storing failure log: %s", err)
+	}
+	return store
+}
+
+func TestGetCommand(t *testing.T) {
+	var out bytes.Buffer
+	err := runCommand(testStore(t), "get", []string{"id2"}, "table", &out, ioutil.Discard)
+	if err != nil {
+		t.Fatalf("Error running get: %s", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected a header and one row, got %q.", out.String())
+	}
+	if !strings.HasPrefix(lines[1], "id2 ") || !strings.Contains(lines[1], " 25.00 ") {
+		t.Errorf("Unexpected row: %q", lines[1])
+	}
+	err = runCommand(testStore(t), "get", []string{"not-an-id"}, "table", &out, ioutil.Discard)
+	if err != paymentlog.LogNotFound {
+		t.Errorf("Expected %s, got %v.", paymentlog.LogNotFound, err)
+	}
+}
+
+func TestListCommand(t *testing.T) {
+	var out bytes.Buffer
+	err := runCommand(testStore(t), "list", []string{"-since", "2014-03-01T12:30:00Z", "-num", "1"}, "json", &out, ioutil.Discard)
+	if err != nil {
+		t.Fatalf("Error running list: %s", err)
+	}
+	var logs []paymentlog.PaymentLog
+	if err := json.Unmarshal(out.Bytes(), &logs); err != nil {
+		t.Fatalf("Error decoding list output: %s", err)
+	}
+	if len(logs) != 1 || logs[0].ID != "id3" {
+		t.Errorf("Expected [id3], got %+v.", logs)
+	}
+	out.Reset()
+	err = runCommand(testStore(t), "list", []string{"-user", "user-id1"}, "csv", &out, ioutil.Discard)
+	if err != nil {
+		t.Fatalf("Error running list: %s", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[1], "id1,12.50,usd,") {
+		t.Errorf("Unexpected CSV output: %q", out.String())
+	}
+}
+
+func TestListCommandSelectsInStore(t *testing.T) {
+	calls := map[paymentlog.Operation]int{}
+	store := paymentlog.Wrap(testStore(t), paymentlog.Middleware{
+		Before: func(call paymentlog.Call) error {
+			calls[call.Op]++
+			return nil
+		},
+	})
+	tests := map[string][]string{
+		"id2":     {"-user", "user-id2"},
+		"id3,id2": {"-project", "project-id", "-num", "2"},
+		"id1":     {"-project", "project-id", "-user", "user-id1"},
+	}
+	for expectation, args := range tests {
+		var out bytes.Buffer
+		if err := runCommand(store, "list", args, "json", &out, ioutil.Discard); err != nil {
+			t.Fatalf("Error running list %v: %s", args, err)
+		}
+		var logs []paymentlog.PaymentLog
+		if err := json.Unmarshal(out.Bytes(), &logs); err != nil {
+			t.Fatalf("Error decoding list output: %s", err)
+		}
+		ids := []string{}
+		for _, log := range logs {
+			ids = append(ids, log.ID)
+		}
+		if strings.Join(ids, ",") != expectation {
+			t.Errorf("list %v: expected [%s], got %v.", args, expectation, ids)
+		}
+	}
+	if calls[paymentlog.OpIteratePaymentLogs] != 0 || calls[paymentlog.OpListPaymentLogsByProject] != 2 || calls[paymentlog.OpListPaymentLogsByUser] != 1 {
+		t.Errorf("Expected list to select by project and user in the store, got %v.", calls)
+	}
+}
+
+func TestFailuresCommand(t *testing.T) {
+	var out bytes.Buffer
+	err := runCommand(testStore(t), "failures", []string{"id2"}, "csv", &out, ioutil.Discard)
+	if err != nil {
+		t.Fatalf("Error running failures: %s", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[1], "failure-id,id2,card declined,card-declined,") {
+		t.Errorf("Unexpected CSV output: %q", out.String())
+	}
+}
+
+func TestSetStatusCommandOverHTTP(t *testing.T) {
+	store := testStore(t)
+	server := httptest.NewServer(httpapi.NewServer(store))
+	defer server.Close()
+	var out bytes.Buffer
+	err := run([]string{"-server", server.URL, "set-status", "id1", "succeeded"}, &out, ioutil.Discard)
+	if err != nil {
+		t.Fatalf("Error running set-status: %s", err)
+	}
+	log, err := store.GetPaymentLog("id1")
+	if err != nil {
+		t.Fatalf("Error retrieving payment log: %s", err)
+	}
+	if log.Status != "succeeded" || log.Updated.IsZero() {
+		t.Errorf("Expected status succeeded and an updated timestamp, got %+v.", log)
+	}
+}
+
+func TestSetStatusCommandOnSnapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "paymentlog")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "snapshot.json")
+	if err := saveSnapshot(path, testStore(t)); err != nil {
+		t.Fatalf("Error saving snapshot: %s", err)
+	}
+	err = run([]string{"-snapshot", path, "set-status", "id1", "succeeded"}, ioutil.Discard, ioutil.Discard)
+	if err != nil {
+		t.Fatalf("Error running set-status: %s", err)
+	}
+	store, err := loadSnapshot(path)
+	if err != nil {
+		t.Fatalf("Error loading snapshot: %s", err)
+	}
+	log, err := store.GetPaymentLog("id1")
+	if err != nil {
+		t.Fatalf("Error retrieving payment log: %s", err)
+	}
+	if log.Status != "succeeded" {
+		t.Errorf("Expected the status change to be saved, got %+v.", log)
+	}
+}
+
+func TestMigrateCommand(t *testing.T) {
+	dir, err := ioutil.TempDir("", "paymentlog")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	server := httptest.NewServer(httpapi.NewServer(testStore(t)))
+	defer server.Close()
+	path := filepath.Join(dir, "snapshot.json")
+	checkpoint := filepath.Join(dir, "checkpoint.json")
+	var out bytes.Buffer
+	err = run([]string{"-server", server.URL, "migrate", "-to-snapshot", path, "-checkpoint", checkpoint, "-batch", "2"}, &out, ioutil.Discard)
+	if err != nil {
+		t.Fatalf("Error running migrate: %s\n%s", err, out.String())
+	}
+	if !strings.Contains(out.String(), "payment logs: 3 copied, 0 already present, 3 in source, 3 in destination") {
+		t.Errorf("Unexpected migrate output: %q", out.String())
+	}
+	store, err := loadSnapshot(path)
+	if err != nil {
+		t.Fatalf("Error loading snapshot: %s", err)
+	}
+	if _, err := store.GetPaymentLog("id3"); err != nil {
+		t.Errorf("Error retrieving migrated payment log: %s", err)
+	}
+	if _, err := os.Stat(checkpoint); err != nil {
+		t.Errorf("Expected a checkpoint file to be written: %s", err)
+	}
+}
+
+func TestRunWithoutBackend(t *testing.T) {
+	err := run([]string{"-server", "", "-snapshot", "", "get", "id1"}, ioutil.Discard, ioutil.Discard)
+	if err != MissingBackend {
+		t.Errorf("Expected %s, got %v.", MissingBackend, err)
+	}
+	err = run([]string{"-server", "http://localhost", "-format", "xml", "get", "id1"}, ioutil.Discard, ioutil.Discard)
+	if err != UnknownFormat {
+		t.Errorf("Expected %s, got %v.", UnknownFormat, err)
+	}
+}
@@ -0,0 +1,125 @@
+package paymentlog
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	DisputeWon      = "won"
+	DisputeLost     = "lost"
+	DisputeAccepted = "accepted"
+)
+
+var (
+	MissingDisputeID           = errors.New("Missing dispute ID.")
+	MissingDisputePaymentLogID = errors.New("Missing dispute payment log ID.")
+	MissingDisputeAmount       = errors.New("Missing dispute amount.")
+	MissingDisputeCreated      = errors.New("Missing dispute created timestamp.")
+	InvalidDisputeOutcome      = errors.New("Invalid dispute outcome.")
+	DisputeExceedsPayment      = errors.New("Disputed amount exceeds the payment amount.")
+	DisputeAlreadyExists       = errors.New("Dispute already exists.")
+	DisputeNotFound            = errors.New("Dispute not found.")
+)
+
+// Dispute is a cardholder disputing all or part of a payment. Outcome is
+// empty while the dispute is undecided.
+type Dispute struct {
+	ID           string    `json:"id"`
+	PaymentLogID string    `json:"payment_log_id"`
+	Reason       string    `json:"reason,omitempty"`
+	Amount       uint      `json:"amount"`
+	EvidenceDue  time.Time `json:"evidence_due,omitempty"`
+	Outcome      string    `json:"outcome,omitempty"`
+	Created      time.Time `json:"created"`
+	Updated      time.Time `json:"updated,omitempty"`
+}
+
+func validDisputeOutcome(outcome string) bool {
+	switch outcome {
+	case "", DisputeWon, DisputeLost, DisputeAccepted:
+		return true
+	}
+	return false
+}
+
+func (d Dispute) Validate() error {
+	switch {
+	case d.ID == "":
+		return MissingDisputeID
+	case d.PaymentLogID == "":
+		return MissingDisputePaymentLogID
+	case d.Amount == 0:
+		return MissingDisputeAmount
+	case d.Created.IsZero():
+		return MissingDisputeCreated
+	case !validDisputeOutcome(d.Outcome):
+		return InvalidDisputeOutcome
+	default:
+		return nil
+	}
+}
+
+// ChargedBack reports whether the disputed amount has been taken back from
+// the payment: the dispute was lost, or accepted without a fight.
+func (d Dispute) ChargedBack() bool {
+	return d.Outcome == DisputeLost || d.Outcome == DisputeAccepted
+}
+
+type DisputeChange struct {
+	Reason      *string    `json:"reason,omitempty"`
+	Amount      *uint      `json:"amount,omitempty"`
+	EvidenceDue *time.Time `json:"evidence_due,omitempty"`
+	Outcome     *string    `json:"outcome,omitempty"`
+	Updated     *time.Time `json:"updated,omitempty"`
+}
+
+func (c DisputeChange) apply(d Dispute) Dispute {
+	if c.Reason != nil {
+		d.Reason = *c.Reason
+	}
+	if c.Amount != nil {
+		d.Amount = *c.Amount
+	}
+	if c.EvidenceDue != nil {
+		d.EvidenceDue = *c.EvidenceDue
+	}
+	if c.Outcome != nil {
+		d.Outcome = *c.Outcome
+	}
+	if c.Updated != nil {
+		d.Updated = *c.Updated
+	}
+	return d
+}
+
+// DisputeStore keeps disputes against payment logs. Storing or updating a
+// dispute keeps the ChargedBack amount of its payment log in step with the
+// disputes that were lost or accepted.
+type DisputeStore interface {
+	// StoreDispute returns LogNotFound if the disputed payment log doesn't
+	// exist.
+	StoreDispute(dispute Dispute) error
+	UpdateDispute(id string, change DisputeChange) error
+	GetDispute(id string) (Dispute, error)
+	// ListDisputes lists every dispute, newest first.
+	ListDisputes(num, offset int) ([]Dispute, error)
+	ListDisputesByPaymentLog(paymentLogID string) ([]Dispute, error)
+}
+
+type sortedDisputes []Dispute
+
+func (s sortedDisputes) Len() int {
+	return len(s)
+}
+
+func (s sortedDisputes) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+func (s sortedDisputes) Less(i, j int) bool {
+	if s[i].Created.Equal(s[j].Created) {
+		return s[i].ID < s[j].ID
+	}
+	return s[i].Created.After(s[j].Created)
+}
@@ -0,0 +1,192 @@
+package paymentlog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDisputeValidation(t *testing.T) {
+	created := time.Date(2014, time.March, 1, 12, 0, 0, 0, time.UTC)
+	disputes := map[error]Dispute{
+		MissingDisputeID:           {PaymentLogID: "payment", Amount: 100, Created: created},
+		MissingDisputePaymentLogID: {ID: "dispute", Amount: 100, Created: created},
+		MissingDisputeAmount:       {ID: "dispute", PaymentLogID: "payment", Created: created},
+		MissingDisputeCreated:      {ID: "dispute", PaymentLogID: "payment", Amount: 100},
+		InvalidDisputeOutcome:      {ID: "dispute", PaymentLogID: "payment", Amount: 100, Created: created, Outcome: "pending"},
+	}
+	for expectation, dispute := range disputes {
+		if err := dispute.Validate(); err != expectation {
+			t.Errorf("Expected %s for %+v, got %v.", expectation, dispute, err)
+		}
+	}
+}
+
+func TestLosingDisputesAdjustsNetAmount(t *testing.T) {
+	store := exportTestStore()
+	created := time.Date(2014, time.March, 2, 12, 0, 0, 0, time.UTC)
+	id := "test-payment-log 1"
+	sub, err := store.Subscribe(0, 100)
+	if err != nil {
+		t.Fatalf("Error subscribing: %s", err)
+	}
+	defer sub.Close()
+
+	disputes := []Dispute{
+		{ID: "dp1", PaymentLogID: id, Reason: "fraudulent", Amount: 1000, EvidenceDue: created.Add(7 * 24 * time.Hour), Created: created},
+		{ID: "dp2", PaymentLogID: id, Reason: "duplicate", Amount: 250, Created: created.Add(time.Hour)},
+	}
+	for _, dispute := range disputes {
+		if err := store.StoreDispute(dispute); err != nil {
+			t.Fatalf("Error storing dispute: %s", err)
+		}
+	}
+	if err := store.StoreDispute(disputes[0]); err != DisputeAlreadyExists {
+		t.Errorf("Expected %s, got %v.", DisputeAlreadyExists, err)
+	}
+	if err := store.StoreDispute(Dispute{ID: "dp3", PaymentLogID: "missing", Amount: 1, Created: created}); err != LogNotFound {
+		t.Errorf("Expected %s, got %v.", LogNotFound, err)
+	}
+	if err := store.StoreDispute(Dispute{ID: "dp3", PaymentLogID: id, Amount: 1251, Created: created}); err != DisputeExceedsPayment {
+		t.Errorf("Expected %s, got %v.", DisputeExceedsPayment, err)
+	}
+	if log, _ := store.GetPaymentLog(id); log.ChargedBack != 0 || log.NetAmount() != 1250 {
+		t.Errorf("Expected undecided disputes not to affect the net amount, got %+v.", log)
+	}
+
+	lost, accepted, won := DisputeLost, DisputeAccepted, DisputeWon
+	updated := created.Add(24 * time.Hour)
+	if err := store.UpdateDispute("dp1", DisputeChange{Outcome: &lost, Updated: &updated}); err != nil {
+		t.Fatalf("Error updating dispute: %s", err)
+	}
+	if err := store.UpdateDispute("dp2", DisputeChange{Outcome: &accepted}); err != nil {
+		t.Fatalf("Error updating dispute: %s", err)
+	}
+	if log, _ := store.GetPaymentLog(id); log.ChargedBack != 1250 || log.NetAmount() != 0 {
+		t.Errorf("Expected 1250 to be charged back, got %+v.", log)
+	}
+	// the first dispute is won on appeal
+	if err := store.UpdateDispute("dp1", DisputeChange{Outcome: &won}); err != nil {
+		t.Fatalf("Error updating dispute: %s", err)
+	}
+	if log, _ := store.GetPaymentLog(id); log.ChargedBack != 250 || log.NetAmount() != 1000 {
+		t.Errorf("Expected 250 to be charged back, got %+v.", log)
+	}
+	invalid := "reversed"
+	if err := store.UpdateDispute("dp1", DisputeChange{Outcome: &invalid}); err != InvalidDisputeOutcome {
+		t.Errorf("Expected %s, got %v.", InvalidDisputeOutcome, err)
+	}
+	if err := store.UpdateDispute("missing", DisputeChange{Outcome: &lost}); err != DisputeNotFound {
+		t.Errorf("Expected %s, got %v.", DisputeNotFound, err)
+	}
+
+	dispute, err := store.GetDispute("dp1")
+	if err != nil || dispute.Outcome != DisputeWon || !dispute.Updated.Equal(updated) || dispute.Reason != "fraudulent" {
+		t.Errorf("Unexpected dispute: %+v, %v", dispute, err)
+	}
+	results, err := store.ListDisputesByPaymentLog(id)
+	if err != nil || len(results) != 2 || results[0].ID != "dp2" || results[1].ID != "dp1" {
+		t.Errorf("Expected [dp2 dp1], got %+v, %v.", results, err)
+	}
+	results, err = store.ListDisputes(1, 1)
+	if err != nil || len(results) != 1 || results[0].ID != "dp1" {
+		t.Errorf("Expected [dp1], got %+v, %v.", results, err)
+	}
+
+	types := []string{}
+	for len(types) < 5 {
+		event := nextEvent(t, sub)
+		if event.Dispute != nil {
+			types = append(types, event.Type)
+		}
+	}
+	if strings.Join(types, " ") != "dispute.stored dispute.stored dispute.updated dispute.updated dispute.updated" {
+		t.Errorf("Unexpected dispute events: %v", types)
+	}
+
+	if err := store.DeletePaymentLog(id); err != nil {
+		t.Fatalf("Error deleting payment log: %s", err)
+	}
+	if _, err := store.GetDispute("dp1"); err != DisputeNotFound {
+		t.Errorf("Expected disputes to be deleted with their payment log, got %v.", err)
+	}
+}
+
+func TestDisputesSurviveSnapshots(t *testing.T) {
+	store := exportTestStore()
+	lost := DisputeLost
+	created := time.Date(2014, time.March, 2, 12, 0, 0, 0, time.UTC)
+	if err := store.StoreDispute(Dispute{ID: "dp1", PaymentLogID: "test-payment-log 1", Amount: 250, Created: created}); err != nil {
+		t.Fatalf("Error storing dispute: %s", err)
+	}
+	if err := store.UpdateDispute("dp1", DisputeChange{Outcome: &lost}); err != nil {
+		t.Fatalf("Error updating dispute: %s", err)
+	}
+	var buf bytes.Buffer
+	if err := store.Snapshot(&buf); err != nil {
+		t.Fatalf("Error snapshotting memory store: %s", err)
+	}
+	loaded, err := LoadMemoryStore(&buf)
+	if err != nil {
+		t.Fatalf("Error loading memory store: %s", err)
+	}
+	if dispute, err := loaded.GetDispute("dp1"); err != nil || dispute.Outcome != DisputeLost {
+		t.Errorf("Unexpected dispute: %+v, %v", dispute, err)
+	}
+	if log, _ := loaded.GetPaymentLog("test-payment-log 1"); log.NetAmount() != 1000 {
+		t.Errorf("Expected a net amount of 1000, got %d.", log.NetAmount())
+	}
+}
+
+func TestImportingPaymentLogsWithoutChargebacks(t *testing.T) {
+	legacy := "id,amount,currency,status,description,source,source_id,created,updated,project_id,user_id,account_id,account_type\n" +
+		"id,12.50,usd,pending,,balanced,balanced-id,2014-03-01T12:00:00Z,,project-id,user-id,account-id,google\n"
+	store := NewMemoryStore()
+	if _, err := ImportPaymentLogs(store, NewPaymentLogCSVDecoder(strings.NewReader(legacy))); err != nil {
+		t.Fatalf("Error importing payment logs: %s", err)
+	}
+	if log, err := store.GetPaymentLog("id"); err != nil || log.Amount != 1250 || log.ChargedBack != 0 {
+		t.Errorf("Unexpected payment log: %+v, %v", log, err)
+	}
+}
+
+func TestChargedBackIsDerivedFromDisputes(t *testing.T) {
+	store := NewMemoryStore()
+	log := *exportTestStore().paymentLogs["test-payment-log 1"]
+	log.Status, log.ChargedBack = StatusSucceeded, 100
+	if err := store.StorePaymentLog(log); err != nil {
+		t.Fatalf("Error storing payment log: %s", err)
+	}
+	if stored, _ := store.GetPaymentLog(log.ID); stored.ChargedBack != 0 {
+		t.Errorf("Expected a stored payment log without disputes not to be charged back, got %+v.", stored)
+	}
+	if balances, _ := store.UnpaidBalances("project-id"); balances[CurrencyUSD] != log.Amount {
+		t.Errorf("Expected an unpaid balance of %d, got %v.", log.Amount, balances)
+	}
+
+	var buf bytes.Buffer
+	if err := store.Snapshot(&buf); err != nil {
+		t.Fatalf("Error snapshotting memory store: %s", err)
+	}
+	snapshot := strings.Replace(buf.String(), `"status":"succeeded"`, `"status":"succeeded","charged_back":100`, 1)
+	if snapshot == buf.String() {
+		t.Fatalf("Expected to edit the snapshot: %s", snapshot)
+	}
+	loaded, err := LoadMemoryStore(strings.NewReader(snapshot))
+	if err != nil {
+		t.Fatalf("Error loading memory store: %s", err)
+	}
+	if stored, _ := loaded.GetPaymentLog(log.ID); stored.ChargedBack != 0 {
+		t.Errorf("Expected a loaded payment log without disputes not to be charged back, got %+v.", stored)
+	}
+
+	csv := "id,amount,currency,status,description,source,source_id,created,updated,project_id,user_id,account_id,account_type,charged_back\n" +
+		"id,12.50,usd,succeeded,,balanced,balanced-id,2014-03-01T12:00:00Z,,project-id,user-id,account-id,google,5.00\n"
+	if _, err := ImportPaymentLogs(store, NewPaymentLogCSVDecoder(strings.NewReader(csv))); err != nil {
+		t.Fatalf("Error importing payment logs: %s", err)
+	}
+	if imported, _ := store.GetPaymentLog("id"); imported.ChargedBack != 0 {
+		t.Errorf("Expected an imported payment log without disputes not to be charged back, got %+v.", imported)
+	}
+}
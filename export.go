@@ -0,0 +1,520 @@
+package paymentlog
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	InvalidAmount    = errors.New("Invalid payment log amount.")
+	InvalidCSVHeader = errors.New("Invalid CSV header row.")
+)
+
+var PaymentLogCSVHeader = []string{
+	"id",
+	"amount",
+	"currency",
+	"status",
+	"description",
+	"source",
+	"source_id",
+	"created",
+	"updated",
+	"project_id",
+	"user_id",
+	"account_id",
+	"account_type",
+	"charged_back",
+	"processor_fee",
+	"platform_fee",
+}
+
+var FailureLogCSVHeader = []string{
+	"id",
+	"payment_log_id",
+	"failure_reason",
+	"failure_reason_code",
+	"timestamp",
+	"source",
+	"state",
+	"resolver",
+	"resolution_note",
+	"state_updated",
+}
+
+// FormatAmount formats an amount in minor currency units (e.g. cents) as a
+// decimal string with two decimal places.
+func FormatAmount(amount uint) string {
+	return fmt.Sprintf("%d.%02d", amount/100, amount%100)
+}
+
+func ParseAmount(s string) (uint, error) {
+	parts := strings.SplitN(s, ".", 2)
+	major, err := strconv.ParseUint(parts[0], 10, 0)
+	if err != nil {
+		return 0, InvalidAmount
+	}
+	var minor uint64
+	if len(parts) == 2 {
+		if len(parts[1]) == 0 || len(parts[1]) > 2 {
+			return 0, InvalidAmount
+		}
+		minor, err = strconv.ParseUint(parts[1], 10, 0)
+		if err != nil {
+			return 0, InvalidAmount
+		}
+		if len(parts[1]) == 1 {
+			minor *= 10
+		}
+	}
+	return uint(major*100 + minor), nil
+}
+
+func formatCSVTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+func parseCSVTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339Nano, s)
+}
+
+type PaymentLogFilter func(log PaymentLog) bool
+
+type FailureLogFilter func(failure FailureLog) bool
+
+func ProjectFilter(projectID string) PaymentLogFilter {
+	return func(log PaymentLog) bool {
+		return log.ProjectID == projectID
+	}
+}
+
+func UserFilter(userID string) PaymentLogFilter {
+	return func(log PaymentLog) bool {
+		return log.UserID == userID
+	}
+}
+
+// CreatedFilter matches payment logs created at or after since and before
+// until. A zero since or until leaves that end of the range open.
+func CreatedFilter(since, until time.Time) PaymentLogFilter {
+	return func(log PaymentLog) bool {
+		if !since.IsZero() && log.Created.Before(since) {
+			return false
+		}
+		if !until.IsZero() && !log.Created.Before(until) {
+			return false
+		}
+		return true
+	}
+}
+
+func PaymentLogIDFilter(paymentLogID string) FailureLogFilter {
+	return func(failure FailureLog) bool {
+		return failure.PaymentLogID == paymentLogID
+	}
+}
+
+func FailureTimestampFilter(since, until time.Time) FailureLogFilter {
+	return func(failure FailureLog) bool {
+		if !since.IsZero() && failure.Timestamp.Before(since) {
+			return false
+		}
+		if !until.IsZero() && !failure.Timestamp.Before(until) {
+			return false
+		}
+		return true
+	}
+}
+
+type PaymentLogEncoder interface {
+	Encode(log PaymentLog) error
+	Flush() error
+}
+
+type FailureLogEncoder interface {
+	Encode(failure FailureLog) error
+	Flush() error
+}
+
+type PaymentLogDecoder interface {
+	// Decode returns io.EOF when there are no more payment logs.
+	Decode() (PaymentLog, error)
+}
+
+type FailureLogDecoder interface {
+	// Decode returns io.EOF when there are no more failure logs.
+	Decode() (FailureLog, error)
+}
+
+func ExportPaymentLogs(store LogStore, enc PaymentLogEncoder, filters ...PaymentLogFilter) error {
+	err := store.IteratePaymentLogs(func(log PaymentLog) error {
+		for _, filter := range filters {
+			if !filter(log) {
+				return nil
+			}
+		}
+		return enc.Encode(log)
+	})
+	if err != nil {
+		return err
+	}
+	return enc.Flush()
+}
+
+func ExportFailureLogs(store LogStore, enc FailureLogEncoder, filters ...FailureLogFilter) error {
+	err := store.IterateFailureLogs(func(failure FailureLog) error {
+		for _, filter := range filters {
+			if !filter(failure) {
+				return nil
+			}
+		}
+		return enc.Encode(failure)
+	})
+	if err != nil {
+		return err
+	}
+	return enc.Flush()
+}
+
+func ImportPaymentLogs(store LogStore, dec PaymentLogDecoder) (int, error) {
+	imported := 0
+	for {
+		log, err := dec.Decode()
+		if err == io.EOF {
+			return imported, nil
+		}
+		if err != nil {
+			return imported, err
+		}
+		if err := store.StorePaymentLog(log); err != nil {
+			return imported, err
+		}
+		imported++
+	}
+}
+
+func ImportFailureLogs(store LogStore, dec FailureLogDecoder) (int, error) {
+	imported := 0
+	for {
+		failure, err := dec.Decode()
+		if err == io.EOF {
+			return imported, nil
+		}
+		if err != nil {
+			return imported, err
+		}
+		if err := store.StoreFailureLog(failure); err != nil {
+			return imported, err
+		}
+		imported++
+	}
+}
+
+type csvPaymentLogEncoder struct {
+	w             *csv.Writer
+	headerWritten bool
+}
+
+// NewPaymentLogCSVEncoder returns an encoder that writes PaymentLogCSVHeader
+// followed by one row per payment log. The header is written even if no
+// payment logs are encoded.
+func NewPaymentLogCSVEncoder(w io.Writer) PaymentLogEncoder {
+	return &csvPaymentLogEncoder{w: csv.NewWriter(w)}
+}
+
+func (c *csvPaymentLogEncoder) header() error {
+	if c.headerWritten {
+		return nil
+	}
+	c.headerWritten = true
+	return c.w.Write(PaymentLogCSVHeader)
+}
+
+func (c *csvPaymentLogEncoder) Encode(log PaymentLog) error {
+	if err := c.header(); err != nil {
+		return err
+	}
+	return c.w.Write([]string{
+		log.ID,
+		FormatAmount(log.Amount),
+		log.Currency,
+		log.Status,
+		log.Description,
+		log.Source,
+		log.SourceID,
+		formatCSVTime(log.Created),
+		formatCSVTime(log.Updated),
+		log.ProjectID,
+		log.UserID,
+		log.AccountID,
+		log.AccountType,
+		FormatAmount(log.ChargedBack),
+		FormatAmount(log.ProcessorFee),
+		FormatAmount(log.PlatformFee),
+	})
+}
+
+func (c *csvPaymentLogEncoder) Flush() error {
+	if err := c.header(); err != nil {
+		return err
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type csvFailureLogEncoder struct {
+	w             *csv.Writer
+	headerWritten bool
+}
+
+func NewFailureLogCSVEncoder(w io.Writer) FailureLogEncoder {
+	return &csvFailureLogEncoder{w: csv.NewWriter(w)}
+}
+
+func (c *csvFailureLogEncoder) header() error {
+	if c.headerWritten {
+		return nil
+	}
+	c.headerWritten = true
+	return c.w.Write(FailureLogCSVHeader)
+}
+
+func (c *csvFailureLogEncoder) Encode(failure FailureLog) error {
+	if err := c.header(); err != nil {
+		return err
+	}
+	return c.w.Write([]string{
+		failure.ID,
+		failure.PaymentLogID,
+		failure.FailureReason,
+		failure.FailureReasonCode,
+		formatCSVTime(failure.Timestamp),
+		failure.Source,
+		failure.State,
+		failure.Resolver,
+		failure.ResolutionNote,
+		formatCSVTime(failure.StateUpdated),
+	})
+}
+
+func (c *csvFailureLogEncoder) Flush() error {
+	if err := c.header(); err != nil {
+		return err
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func matchesCSVHeader(header, expected []string) bool {
+	if len(header) != len(expected) {
+		return false
+	}
+	for pos := range expected {
+		if header[pos] != expected[pos] {
+			return false
+		}
+	}
+	return true
+}
+
+// readCSVHeader reads a header row, which must match one of the accepted
+// headers.
+func readCSVHeader(r *csv.Reader, accepted ...[]string) error {
+	header, err := r.Read()
+	if err == io.EOF {
+		return io.EOF
+	}
+	if err != nil {
+		return err
+	}
+	for _, expected := range accepted {
+		if matchesCSVHeader(header, expected) {
+			return nil
+		}
+	}
+	return InvalidCSVHeader
+}
+
+type csvPaymentLogDecoder struct {
+	r          *csv.Reader
+	headerRead bool
+}
+
+func NewPaymentLogCSVDecoder(r io.Reader) PaymentLogDecoder {
+	return &csvPaymentLogDecoder{r: csv.NewReader(r)}
+}
+
+func (c *csvPaymentLogDecoder) Decode() (PaymentLog, error) {
+	if !c.headerRead {
+		// payment logs exported before chargebacks and fees were tracked
+		// have fewer columns
+		if err := readCSVHeader(c.r, PaymentLogCSVHeader, PaymentLogCSVHeader[:14], PaymentLogCSVHeader[:13]); err != nil {
+			return PaymentLog{}, err
+		}
+		c.headerRead = true
+	}
+	record, err := c.r.Read()
+	if err != nil {
+		return PaymentLog{}, err
+	}
+	amount, err := ParseAmount(record[1])
+	if err != nil {
+		return PaymentLog{}, err
+	}
+	created, err := parseCSVTime(record[7])
+	if err != nil {
+		return PaymentLog{}, err
+	}
+	updated, err := parseCSVTime(record[8])
+	if err != nil {
+		return PaymentLog{}, err
+	}
+	log := PaymentLog{
+		ID:          record[0],
+		Amount:      amount,
+		Currency:    record[2],
+		Status:      record[3],
+		Description: record[4],
+		Source:      record[5],
+		SourceID:    record[6],
+		Created:     created,
+		Updated:     updated,
+		ProjectID:   record[9],
+		UserID:      record[10],
+		AccountID:   record[11],
+		AccountType: record[12],
+	}
+	if len(record) > 13 {
+		if log.ChargedBack, err = ParseAmount(record[13]); err != nil {
+			return PaymentLog{}, err
+		}
+	}
+	if len(record) > 15 {
+		if log.ProcessorFee, err = ParseAmount(record[14]); err != nil {
+			return PaymentLog{}, err
+		}
+		if log.PlatformFee, err = ParseAmount(record[15]); err != nil {
+			return PaymentLog{}, err
+		}
+	}
+	return log, nil
+}
+
+type csvFailureLogDecoder struct {
+	r          *csv.Reader
+	headerRead bool
+}
+
+func NewFailureLogCSVDecoder(r io.Reader) FailureLogDecoder {
+	return &csvFailureLogDecoder{r: csv.NewReader(r)}
+}
+
+func (c *csvFailureLogDecoder) Decode() (FailureLog, error) {
+	if !c.headerRead {
+		// failure logs exported before they had a source or a state
+		// have fewer columns
+		if err := readCSVHeader(c.r, FailureLogCSVHeader, FailureLogCSVHeader[:6], FailureLogCSVHeader[:5]); err != nil {
+			return FailureLog{}, err
+		}
+		c.headerRead = true
+	}
+	record, err := c.r.Read()
+	if err != nil {
+		return FailureLog{}, err
+	}
+	timestamp, err := parseCSVTime(record[4])
+	if err != nil {
+		return FailureLog{}, err
+	}
+	failure := FailureLog{
+		ID:                record[0],
+		PaymentLogID:      record[1],
+		FailureReason:     record[2],
+		FailureReasonCode: record[3],
+		Timestamp:         timestamp,
+	}
+	if len(record) > 5 {
+		failure.Source = record[5]
+	}
+	if len(record) > 9 {
+		failure.State = record[6]
+		failure.Resolver = record[7]
+		failure.ResolutionNote = record[8]
+		if failure.StateUpdated, err = parseCSVTime(record[9]); err != nil {
+			return FailureLog{}, err
+		}
+	}
+	return failure, nil
+}
+
+type jsonLinesPaymentLogEncoder struct {
+	enc *json.Encoder
+}
+
+func NewPaymentLogJSONLinesEncoder(w io.Writer) PaymentLogEncoder {
+	return jsonLinesPaymentLogEncoder{enc: json.NewEncoder(w)}
+}
+
+func (j jsonLinesPaymentLogEncoder) Encode(log PaymentLog) error {
+	return j.enc.Encode(log)
+}
+
+func (j jsonLinesPaymentLogEncoder) Flush() error {
+	return nil
+}
+
+type jsonLinesFailureLogEncoder struct {
+	enc *json.Encoder
+}
+
+func NewFailureLogJSONLinesEncoder(w io.Writer) FailureLogEncoder {
+	return jsonLinesFailureLogEncoder{enc: json.NewEncoder(w)}
+}
+
+func (j jsonLinesFailureLogEncoder) Encode(failure FailureLog) error {
+	return j.enc.Encode(failure)
+}
+
+func (j jsonLinesFailureLogEncoder) Flush() error {
+	return nil
+}
+
+type jsonLinesPaymentLogDecoder struct {
+	dec *json.Decoder
+}
+
+func NewPaymentLogJSONLinesDecoder(r io.Reader) PaymentLogDecoder {
+	return jsonLinesPaymentLogDecoder{dec: json.NewDecoder(r)}
+}
+
+func (j jsonLinesPaymentLogDecoder) Decode() (PaymentLog, error) {
+	var log PaymentLog
+	err := j.dec.Decode(&log)
+	return log, err
+}
+
+type jsonLinesFailureLogDecoder struct {
+	dec *json.Decoder
+}
+
+func NewFailureLogJSONLinesDecoder(r io.Reader) FailureLogDecoder {
+	return jsonLinesFailureLogDecoder{dec: json.NewDecoder(r)}
+}
+
+func (j jsonLinesFailureLogDecoder) Decode() (FailureLog, error) {
+	var failure FailureLog
+	err := j.dec.Decode(&failure)
+	return failure, err
+}
@@ -0,0 +1,243 @@
+package paymentlog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func exportTestStore() *MemoryStore {
+	store := NewMemoryStore()
+	created := time.Date(2014, time.March, 1, 12, 0, 0, 0, time.UTC)
+	logs := []PaymentLog{
+		PaymentLog{
+			ID:          "test-payment-log 1",
+			Amount:      1250,
+			Description: "a description, with a comma",
+			Source:      SourceBalanced,
+			SourceID:    "balanced-id",
+			Created:     created,
+			Status:      StatusPending,
+			Currency:    CurrencyUSD,
+			ProjectID:   "project-id",
+			UserID:      "user-id",
+			AccountID:   "account-id",
+			AccountType: "google",
+		}, PaymentLog{
+			ID:          "test-payment-log 2",
+			Amount:      5,
+			Source:      SourceBalanced,
+			SourceID:    "balanced-id",
+			Created:     created.Add(time.Hour),
+			Updated:     created.Add(2 * time.Hour),
+			Status:      StatusPending,
+			Currency:    CurrencyUSD,
+			ProjectID:   "other-project-id",
+			UserID:      "user-id",
+			AccountID:   "account-id",
+			AccountType: "google",
+		}, PaymentLog{
+			ID:          "test-payment-log 3",
+			Amount:      100000,
+			Source:      SourceBalanced,
+			SourceID:    "balanced-id",
+			Created:     created.Add(3 * time.Hour),
+			Status:      StatusPending,
+			Currency:    CurrencyUSD,
+			ProjectID:   "project-id",
+			UserID:      "other-user-id",
+			AccountID:   "account-id",
+			AccountType: "google",
+		},
+	}
+	for pos := range logs {
+		log := logs[pos]
+		store.paymentLogs[log.ID] = &log
+	}
+	failures := []FailureLog{
+		FailureLog{
+			ID:                "id1",
+			PaymentLogID:      "test-payment-log 1",
+			Source:            SourceBalanced,
+			FailureReason:     "you screwed up",
+			FailureReasonCode: "500",
+			Timestamp:         created,
+		},
+		FailureLog{
+			ID:                "id2",
+			PaymentLogID:      "test-payment-log 2",
+			FailureReason:     "you screwed up \"again\"",
+			FailureReasonCode: "500",
+			Timestamp:         created.Add(time.Minute),
+		},
+	}
+	for pos := range failures {
+		failure := failures[pos]
+		store.failureLogs[failure.ID] = &failure
+	}
+	return store
+}
+
+func TestFormattingAmounts(t *testing.T) {
+	amounts := map[uint]string{
+		0:      "0.00",
+		5:      "0.05",
+		1250:   "12.50",
+		100000: "1000.00",
+	}
+	for amount, expectation := range amounts {
+		if result := FormatAmount(amount); result != expectation {
+			t.Errorf("Expected %d to format as %s, got %s.", amount, expectation, result)
+		}
+		result, err := ParseAmount(expectation)
+		if err != nil {
+			t.Errorf("Error parsing %s: %s", expectation, err)
+		}
+		if result != amount {
+			t.Errorf("Expected %s to parse as %d, got %d.", expectation, amount, result)
+		}
+	}
+	for _, invalid := range []string{"", "-1.00", "1.234", "1.", "abc", "1.x"} {
+		if _, err := ParseAmount(invalid); err != InvalidAmount {
+			t.Errorf("Expected %s when parsing %q, got %v.", InvalidAmount, invalid, err)
+		}
+	}
+	if result, _ := ParseAmount("12.5"); result != 1250 {
+		t.Errorf("Expected 12.5 to parse as 1250, got %d.", result)
+	}
+}
+
+func TestExportingPaymentLogsAsCSV(t *testing.T) {
+	store := exportTestStore()
+	var buf bytes.Buffer
+	err := ExportPaymentLogs(store, NewPaymentLogCSVEncoder(&buf), ProjectFilter("project-id"))
+	if err != nil {
+		t.Fatalf("Error exporting payment logs: %s", err)
+	}
+	expectation := `id,amount,currency,status,description,source,source_id,created,updated,project_id,user_id,account_id,account_type,charged_back,processor_fee,platform_fee
+test-payment-log 3,1000.00,usd,pending,,balanced,balanced-id,2014-03-01T15:00:00Z,,project-id,other-user-id,account-id,google,0.00,0.00,0.00
+test-payment-log 1,12.50,usd,pending,"a description, with a comma",balanced,balanced-id,2014-03-01T12:00:00Z,,project-id,user-id,account-id,google,0.00,0.00,0.00
+`
+	if buf.String() != expectation {
+		t.Errorf("Expected CSV:\n%s\ngot:\n%s", expectation, buf.String())
+	}
+	buf.Reset()
+	err = ExportPaymentLogs(store, NewPaymentLogCSVEncoder(&buf), ProjectFilter("no-such-project"))
+	if err != nil {
+		t.Fatalf("Error exporting payment logs: %s", err)
+	}
+	if buf.String() != strings.Join(PaymentLogCSVHeader, ",")+"\n" {
+		t.Errorf("Expected only a header row, got %q.", buf.String())
+	}
+}
+
+func TestRoundTrippingPaymentLogs(t *testing.T) {
+	store := exportTestStore()
+	formats := map[string]func(store LogStore) (int, error){
+		"csv": func(dst LogStore) (int, error) {
+			var buf bytes.Buffer
+			if err := ExportPaymentLogs(store, NewPaymentLogCSVEncoder(&buf)); err != nil {
+				return 0, err
+			}
+			return ImportPaymentLogs(dst, NewPaymentLogCSVDecoder(&buf))
+		},
+		"json lines": func(dst LogStore) (int, error) {
+			var buf bytes.Buffer
+			if err := ExportPaymentLogs(store, NewPaymentLogJSONLinesEncoder(&buf)); err != nil {
+				return 0, err
+			}
+			if strings.Count(buf.String(), "\n") != len(store.paymentLogs) {
+				t.Errorf("Expected one line per payment log, got %q.", buf.String())
+			}
+			return ImportPaymentLogs(dst, NewPaymentLogJSONLinesDecoder(&buf))
+		},
+	}
+	for format, roundTrip := range formats {
+		dst := NewMemoryStore()
+		imported, err := roundTrip(dst)
+		if err != nil {
+			t.Errorf("%s: error round-tripping payment logs: %s", format, err)
+			continue
+		}
+		if imported != len(store.paymentLogs) {
+			t.Errorf("%s: expected %d payment logs to be imported, got %d.", format, len(store.paymentLogs), imported)
+		}
+		for id, log := range store.paymentLogs {
+			result, ok := dst.paymentLogs[id]
+			if !ok {
+				t.Errorf("%s: payment log %s was not imported.", format, id)
+				continue
+			}
+			success, field, expectation, res := comparePaymentLogs(*log, *result)
+			if !success {
+				t.Errorf("%s: expected payment log %s %s to be %+v, got %+v.", format, id, field, expectation, res)
+			}
+		}
+	}
+}
+
+func TestRoundTrippingFailureLogs(t *testing.T) {
+	store := exportTestStore()
+	formats := map[string]func(store LogStore) (int, error){
+		"csv": func(dst LogStore) (int, error) {
+			var buf bytes.Buffer
+			if err := ExportFailureLogs(store, NewFailureLogCSVEncoder(&buf)); err != nil {
+				return 0, err
+			}
+			return ImportFailureLogs(dst, NewFailureLogCSVDecoder(&buf))
+		},
+		"json lines": func(dst LogStore) (int, error) {
+			var buf bytes.Buffer
+			if err := ExportFailureLogs(store, NewFailureLogJSONLinesEncoder(&buf)); err != nil {
+				return 0, err
+			}
+			return ImportFailureLogs(dst, NewFailureLogJSONLinesDecoder(&buf))
+		},
+	}
+	for format, roundTrip := range formats {
+		dst := NewMemoryStore()
+		imported, err := roundTrip(dst)
+		if err != nil {
+			t.Errorf("%s: error round-tripping failure logs: %s", format, err)
+			continue
+		}
+		if imported != len(store.failureLogs) {
+			t.Errorf("%s: expected %d failure logs to be imported, got %d.", format, len(store.failureLogs), imported)
+		}
+		for id, failure := range store.failureLogs {
+			result, ok := dst.failureLogs[id]
+			if !ok {
+				t.Errorf("%s: failure log %s was not imported.", format, id)
+				continue
+			}
+			success, field, expectation, res := compareFailureLogs(*failure, *result)
+			if !success {
+				t.Errorf("%s: expected failure log %s %s to be %+v, got %+v.", format, id, field, expectation, res)
+			}
+		}
+	}
+}
+
+func TestImportingCSVWithWrongHeader(t *testing.T) {
+	_, err := ImportPaymentLogs(NewMemoryStore(), NewPaymentLogCSVDecoder(strings.NewReader("id,amount\nid,1.00\n")))
+	if err != InvalidCSVHeader {
+		t.Errorf("Expected %s, got %v.", InvalidCSVHeader, err)
+	}
+	imported, err := ImportFailureLogs(NewMemoryStore(), NewFailureLogCSVDecoder(strings.NewReader("")))
+	if err != nil || imported != 0 {
+		t.Errorf("Expected an empty import to succeed, got %d, %v.", imported, err)
+	}
+}
+
+func TestImportingFailureLogsWithoutSources(t *testing.T) {
+	csv := "id,payment_log_id,failure_reason,failure_reason_code,timestamp\nid1,payment-log,you screwed up,500,\n"
+	store := NewMemoryStore()
+	imported, err := ImportFailureLogs(store, NewFailureLogCSVDecoder(strings.NewReader(csv)))
+	if err != nil || imported != 1 {
+		t.Fatalf("Expected 1 failure log to be imported, got %d, %v.", imported, err)
+	}
+	if store.failureLogs["id1"].Source != "" || store.failureLogs["id1"].FailureReasonCode != "500" {
+		t.Errorf("Unexpected failure log: %+v", store.failureLogs["id1"])
+	}
+}
@@ -0,0 +1,185 @@
+package paymentlog
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	EventPaymentLogCreated = "payment_log.created"
+	EventPaymentLogUpdated = "payment_log.updated"
+	EventPaymentLogDeleted = "payment_log.deleted"
+	EventFailureLogStored  = "failure_log.stored"
+	EventFailureLogUpdated = "failure_log.updated"
+	EventDisputeStored     = "dispute.stored"
+	EventDisputeUpdated    = "dispute.updated"
+	EventPayoutStored      = "payout.stored"
+
+	DefaultFeedRetention = 10000
+)
+
+var (
+	PositionExpired    = errors.New("Feed position is no longer retained.")
+	PositionNotReached = errors.New("Feed position has not been reached yet.")
+)
+
+type Event struct {
+	Position      uint64
+	Type          string
+	Timestamp     time.Time
+	PaymentLogID  string
+	PaymentLog    *PaymentLog
+	Change        *PaymentLogChange
+	ChangedFields []string
+	FailureLog    *FailureLog
+	Dispute       *Dispute
+	Payout        *Payout
+}
+
+type ChangeFeed interface {
+	// Subscribe returns a Subscription that receives every event after the
+	// passed position. Passing 0 replays every retained event, even once
+	// older events have been trimmed; passing the Position of the last event
+	// a consumer saw resumes where it left off.
+	Subscribe(after uint64, buffer int) (*Subscription, error)
+	// Head returns the position of the latest event, or 0 if nothing has
+	// been published. Subscribing after it receives only new events.
+	Head() uint64
+}
+
+// Subscription delivers events in order over a buffered channel. Publishing
+// never blocks on a slow subscriber; instead the subscriber falls behind in
+// the feed, and if it falls out of the retained events its channel is
+// closed and Err returns PositionExpired.
+type Subscription struct {
+	feed    *feed
+	events  chan Event
+	done    chan struct{}
+	cursor  uint64
+	stopped bool
+	err     error
+	once    sync.Once
+}
+
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+func (s *Subscription) Err() error {
+	s.feed.Lock()
+	defer s.feed.Unlock()
+	return s.err
+}
+
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.feed.Lock()
+		s.stopped = true
+		s.feed.Unlock()
+		close(s.done)
+		s.feed.cond.Broadcast()
+	})
+}
+
+func (s *Subscription) pump() {
+	defer close(s.events)
+	for {
+		s.feed.Lock()
+		for !s.stopped && s.cursor+1 >= s.feed.next {
+			s.feed.cond.Wait()
+		}
+		if s.stopped {
+			s.feed.Unlock()
+			return
+		}
+		oldest := s.feed.oldest()
+		if s.cursor+1 < oldest {
+			s.err = PositionExpired
+			s.feed.Unlock()
+			return
+		}
+		pending := make([]Event, len(s.feed.events)-int(s.cursor+1-oldest))
+		copy(pending, s.feed.events[s.cursor+1-oldest:])
+		s.feed.Unlock()
+		for _, event := range pending {
+			select {
+			case s.events <- event:
+				s.cursor = event.Position
+			case <-s.done:
+				return
+			}
+		}
+	}
+}
+
+type feed struct {
+	events    []Event
+	next      uint64
+	retention int
+	cond      *sync.Cond
+	sync.Mutex
+}
+
+func newFeed(retention int) *feed {
+	f := &feed{
+		events:    make([]Event, 0),
+		next:      1,
+		retention: retention,
+	}
+	f.cond = sync.NewCond(&f.Mutex)
+	return f
+}
+
+func (f *feed) oldest() uint64 {
+	if len(f.events) == 0 {
+		return f.next
+	}
+	return f.events[0].Position
+}
+
+func (f *feed) head() uint64 {
+	f.Lock()
+	defer f.Unlock()
+	return f.next - 1
+}
+
+func (f *feed) publish(event Event) {
+	f.Lock()
+	event.Position = f.next
+	event.Timestamp = time.Now()
+	f.next++
+	f.events = append(f.events, event)
+	if f.retention > 0 && len(f.events) > f.retention {
+		trimmed := make([]Event, f.retention)
+		copy(trimmed, f.events[len(f.events)-f.retention:])
+		f.events = trimmed
+	}
+	f.Unlock()
+	f.cond.Broadcast()
+}
+
+func (f *feed) subscribe(after uint64, buffer int) (*Subscription, error) {
+	f.Lock()
+	defer f.Unlock()
+	if after == 0 {
+		after = f.oldest() - 1
+	}
+	if after >= f.next {
+		return nil, PositionNotReached
+	}
+	if after+1 < f.oldest() {
+		return nil, PositionExpired
+	}
+	if buffer < 0 {
+		buffer = 0
+	}
+	s := &Subscription{
+		feed:   f,
+		events: make(chan Event, buffer),
+		done:   make(chan struct{}),
+		cursor: after,
+	}
+	go s.pump()
+	return s, nil
+}
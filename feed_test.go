@@ -0,0 +1,189 @@
+package paymentlog
+
+import (
+	"testing"
+	"time"
+)
+
+func nextEvent(t *testing.T, sub *Subscription) Event {
+	select {
+	case event, ok := <-sub.Events():
+		if !ok {
+			t.Fatalf("Subscription closed unexpectedly: %v", sub.Err())
+		}
+		return event
+	case <-time.After(time.Second):
+		t.Fatalf("Timed out waiting for an event.")
+	}
+	return Event{}
+}
+
+func TestSubscribingToMemoryStore(t *testing.T) {
+	store := NewMemoryStore()
+	sub, err := store.Subscribe(0, 1)
+	if err != nil {
+		t.Fatalf("Error subscribing to memory store: %s", err)
+	}
+	defer sub.Close()
+	p := PaymentLog{
+		ID:          "test-payment-log",
+		Amount:      1,
+		Source:      SourceBalanced,
+		SourceID:    "balanced-id",
+		Created:     time.Now(),
+		Status:      StatusPending,
+		Currency:    CurrencyUSD,
+		ProjectID:   "project-id",
+		UserID:      "user-id",
+		AccountID:   "account-id",
+		AccountType: "google",
+	}
+	f := FailureLog{
+		ID:                "id",
+		PaymentLogID:      p.ID,
+		FailureReason:     "you screwed up",
+		FailureReasonCode: "500",
+		Timestamp:         time.Now(),
+	}
+	status := "new status"
+	err = store.StorePaymentLog(p)
+	if err != nil {
+		t.Errorf("Error storing payment log in memory: %s", err)
+	}
+	err = store.UpdatePaymentLog(p.ID, PaymentLogChange{Status: &status})
+	if err != nil {
+		t.Errorf("Error updating payment log in memory: %s", err)
+	}
+	err = store.StoreFailureLog(f)
+	if err != nil {
+		t.Errorf("Error storing failure log in memory: %s", err)
+	}
+	err = store.DeletePaymentLog(p.ID)
+	if err != nil {
+		t.Errorf("Error deleting payment log in memory: %s", err)
+	}
+
+	event := nextEvent(t, sub)
+	if event.Type != EventPaymentLogCreated || event.Position != 1 {
+		t.Errorf("Expected %s at position 1, got %s at %d.", EventPaymentLogCreated, event.Type, event.Position)
+	}
+	success, field, expectation, result := comparePaymentLogs(p, *event.PaymentLog)
+	if !success {
+		t.Errorf("Mismatch. Expected payment log %s to be %+v, got %+v.", field, expectation, result)
+	}
+	event = nextEvent(t, sub)
+	if event.Type != EventPaymentLogUpdated || event.Position != 2 {
+		t.Errorf("Expected %s at position 2, got %s at %d.", EventPaymentLogUpdated, event.Type, event.Position)
+	}
+	if len(event.ChangedFields) != 1 || event.ChangedFields[0] != "Status" {
+		t.Errorf("Expected changed fields to be [Status], got %v.", event.ChangedFields)
+	}
+	if event.PaymentLog.Status != status {
+		t.Errorf("Expected updated status to be %s, got %s.", status, event.PaymentLog.Status)
+	}
+	event = nextEvent(t, sub)
+	if event.Type != EventFailureLogStored || event.Position != 3 {
+		t.Errorf("Expected %s at position 3, got %s at %d.", EventFailureLogStored, event.Type, event.Position)
+	}
+	success, field, expectation, result = compareFailureLogs(f, *event.FailureLog)
+	if !success {
+		t.Errorf("Mismatch. Expected failure log %s to be %+v, got %+v.", field, expectation, result)
+	}
+	event = nextEvent(t, sub)
+	if event.Type != EventPaymentLogDeleted || event.Position != 4 || event.PaymentLogID != p.ID {
+		t.Errorf("Expected %s of %s at position 4, got %s of %s at %d.", EventPaymentLogDeleted, p.ID, event.Type, event.PaymentLogID, event.Position)
+	}
+}
+
+func TestResumingSubscription(t *testing.T) {
+	store := NewMemoryStore()
+	for _, id := range []string{"id1", "id2", "id3"} {
+		err := store.StorePaymentLog(PaymentLog{ID: id})
+		if err != nil {
+			t.Errorf("Error storing payment log in memory: %s", err)
+		}
+	}
+	sub, err := store.Subscribe(2, 0)
+	if err != nil {
+		t.Fatalf("Error subscribing to memory store: %s", err)
+	}
+	defer sub.Close()
+	event := nextEvent(t, sub)
+	if event.Position != 3 || event.PaymentLogID != "id3" {
+		t.Errorf("Expected id3 at position 3, got %s at %d.", event.PaymentLogID, event.Position)
+	}
+	_, err = store.Subscribe(4, 0)
+	if err != PositionNotReached {
+		t.Errorf("Expected %s, got %v.", PositionNotReached, err)
+	}
+}
+
+func TestSlowSubscriberExpires(t *testing.T) {
+	store := NewMemoryStore()
+	store.feed = newFeed(2)
+	sub, err := store.Subscribe(0, 0)
+	if err != nil {
+		t.Fatalf("Error subscribing to memory store: %s", err)
+	}
+	defer sub.Close()
+	for _, id := range []string{"id1", "id2", "id3", "id4", "id5"} {
+		err := store.StorePaymentLog(PaymentLog{ID: id})
+		if err != nil {
+			t.Errorf("Error storing payment log in memory: %s", err)
+		}
+	}
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case _, ok := <-sub.Events():
+			if ok {
+				continue
+			}
+			if sub.Err() != PositionExpired {
+				t.Errorf("Expected %s, got %v.", PositionExpired, sub.Err())
+			}
+			_, err = store.Subscribe(1, 0)
+			if err != PositionExpired {
+				t.Errorf("Expected %s when resubscribing, got %v.", PositionExpired, err)
+			}
+			return
+		case <-timeout:
+			t.Fatalf("Timed out waiting for the subscription to expire.")
+		}
+	}
+}
+
+func TestSubscribingToTrimmedFeed(t *testing.T) {
+	store := NewMemoryStore()
+	store.feed = newFeed(2)
+	if head := store.Head(); head != 0 {
+		t.Errorf("Expected an empty feed to have head 0, got %d.", head)
+	}
+	for _, id := range []string{"id1", "id2", "id3", "id4", "id5"} {
+		err := store.StorePaymentLog(PaymentLog{ID: id})
+		if err != nil {
+			t.Errorf("Error storing payment log in memory: %s", err)
+		}
+	}
+	sub, err := store.Subscribe(0, 0)
+	if err != nil {
+		t.Fatalf("Error subscribing to trimmed feed: %s", err)
+	}
+	defer sub.Close()
+	if event := nextEvent(t, sub); event.Position != 4 || event.PaymentLogID != "id4" {
+		t.Errorf("Expected the oldest retained event, id4 at position 4, got %s at %d.", event.PaymentLogID, event.Position)
+	}
+	head := store.Head()
+	if head != 5 {
+		t.Errorf("Expected head 5, got %d.", head)
+	}
+	latest, err := store.Subscribe(head, 0)
+	if err != nil {
+		t.Fatalf("Error subscribing from head: %s", err)
+	}
+	defer latest.Close()
+	store.StorePaymentLog(PaymentLog{ID: "id6"})
+	if event := nextEvent(t, latest); event.Position != 6 || event.PaymentLogID != "id6" {
+		t.Errorf("Expected only new events from head, got %s at %d.", event.PaymentLogID, event.Position)
+	}
+}
@@ -0,0 +1,204 @@
+package grpcapi
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"code.whipround.net/paymentlog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const DefaultTimeout = 10 * time.Second
+
+// Client implements paymentlog.LogStore against a LogStore service. Timeout
+// bounds each call except IteratePaymentLogs and IterateFailureLogs, which
+// stream until they run out of logs or fn stops them.
+type Client struct {
+	Service LogStoreClient
+	Timeout time.Duration
+}
+
+func NewClient(conn grpc.ClientConnInterface, timeout time.Duration) *Client {
+	return &Client{Service: NewLogStoreClient(conn), Timeout: timeout}
+}
+
+func clientError(err error) error {
+	s, ok := status.FromError(err)
+	if !ok || err == nil {
+		return err
+	}
+	switch s.Code() {
+	case codes.NotFound:
+		if s.Message() == paymentlog.LogNotFound.Error() {
+			return paymentlog.LogNotFound
+		}
+		if s.Message() == paymentlog.FailureLogNotFound.Error() {
+			return paymentlog.FailureLogNotFound
+		}
+	case codes.AlreadyExists:
+		if s.Message() == paymentlog.AlreadyExists.Error() {
+			return paymentlog.AlreadyExists
+		}
+	case codes.InvalidArgument:
+		for _, invalid := range invalidArguments {
+			if s.Message() == invalid.Error() {
+				return invalid
+			}
+		}
+	}
+	return err
+}
+
+func (c *Client) context() (context.Context, context.CancelFunc) {
+	if c.Timeout <= 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), c.Timeout)
+}
+
+func page(num, offset int) *Page {
+	return &Page{Num: int32(num), Offset: int32(offset)}
+}
+
+func (c *Client) StorePaymentLog(log paymentlog.PaymentLog) error {
+	ctx, cancel := c.context()
+	defer cancel()
+	_, err := c.Service.StorePaymentLog(ctx, toPaymentLog(log))
+	return clientError(err)
+}
+
+func (c *Client) UpdatePaymentLog(id string, change paymentlog.PaymentLogChange) error {
+	ctx, cancel := c.context()
+	defer cancel()
+	_, err := c.Service.UpdatePaymentLog(ctx, &UpdatePaymentLogRequest{Id: id, Change: toPaymentLogChange(change)})
+	return clientError(err)
+}
+
+func (c *Client) DeletePaymentLog(id string) error {
+	ctx, cancel := c.context()
+	defer cancel()
+	_, err := c.Service.DeletePaymentLog(ctx, &PaymentLogID{Id: id})
+	return clientError(err)
+}
+
+func (c *Client) GetPaymentLog(id string) (paymentlog.PaymentLog, error) {
+	ctx, cancel := c.context()
+	defer cancel()
+	log, err := c.Service.GetPaymentLog(ctx, &PaymentLogID{Id: id})
+	if err != nil {
+		return paymentlog.PaymentLog{}, clientError(err)
+	}
+	return fromPaymentLog(log), nil
+}
+
+func (c *Client) ListPaymentLogsByProject(campaignID string, num, offset int) ([]paymentlog.PaymentLog, error) {
+	ctx, cancel := c.context()
+	defer cancel()
+	logs, err := c.Service.ListPaymentLogsByProject(ctx, &ListByProjectRequest{ProjectId: campaignID, Page: page(num, offset)})
+	if err != nil {
+		return nil, clientError(err)
+	}
+	return fromPaymentLogs(logs), nil
+}
+
+func (c *Client) ListPaymentLogsByUser(userID string, num, offset int) ([]paymentlog.PaymentLog, error) {
+	ctx, cancel := c.context()
+	defer cancel()
+	logs, err := c.Service.ListPaymentLogsByUser(ctx, &ListByUserRequest{UserId: userID, Page: page(num, offset)})
+	if err != nil {
+		return nil, clientError(err)
+	}
+	return fromPaymentLogs(logs), nil
+}
+
+func (c *Client) ListPaymentLogs(num, offset int) ([]paymentlog.PaymentLog, error) {
+	ctx, cancel := c.context()
+	defer cancel()
+	logs, err := c.Service.ListPaymentLogs(ctx, page(num, offset))
+	if err != nil {
+		return nil, clientError(err)
+	}
+	return fromPaymentLogs(logs), nil
+}
+
+func (c *Client) IteratePaymentLogs(fn func(log paymentlog.PaymentLog) error) error {
+	// cancelling the stream tells the server to stop sending when fn stops
+	// early
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := c.Service.StreamPaymentLogs(ctx, &Page{})
+	if err != nil {
+		return clientError(err)
+	}
+	for {
+		log, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return clientError(err)
+		}
+		err = fn(fromPaymentLog(log))
+		if err == paymentlog.StopIteration {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (c *Client) StoreFailureLog(failure paymentlog.FailureLog) error {
+	ctx, cancel := c.context()
+	defer cancel()
+	_, err := c.Service.StoreFailureLog(ctx, toFailureLog(failure))
+	return clientError(err)
+}
+
+func (c *Client) ListFailureLogs(num, offset int) ([]paymentlog.FailureLog, error) {
+	ctx, cancel := c.context()
+	defer cancel()
+	failures, err := c.Service.ListFailureLogs(ctx, page(num, offset))
+	if err != nil {
+		return nil, clientError(err)
+	}
+	return fromFailureLogs(failures), nil
+}
+
+func (c *Client) ListFailureLogsSince(since time.Time) ([]paymentlog.FailureLog, error) {
+	ctx, cancel := c.context()
+	defer cancel()
+	failures, err := c.Service.ListFailureLogsSince(ctx, &SinceRequest{Timestamp: timestamp(since)})
+	if err != nil {
+		return nil, clientError(err)
+	}
+	return fromFailureLogs(failures), nil
+}
+
+func (c *Client) IterateFailureLogs(fn func(failure paymentlog.FailureLog) error) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := c.Service.StreamFailureLogs(ctx, &Page{})
+	if err != nil {
+		return clientError(err)
+	}
+	for {
+		failure, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return clientError(err)
+		}
+		err = fn(fromFailureLog(failure))
+		if err == paymentlog.StopIteration {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
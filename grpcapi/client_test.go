@@ -0,0 +1,191 @@
+package grpcapi
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"code.whipround.net/paymentlog"
+)
+
+func TestClientIsALogStore(t *testing.T) {
+	// this should refuse to compile if Client doesn't implement LogStore
+	var stores []paymentlog.LogStore
+	stores = append(stores, NewClient(nil, DefaultTimeout))
+}
+
+func TestClientAgainstMemoryStore(t *testing.T) {
+	memory := paymentlog.NewMemoryStore()
+	conn, stop := serve(t, memory)
+	defer stop()
+	client := NewClient(conn, DefaultTimeout)
+
+	p := testPaymentLog("test payment/log", time.Now())
+	p.ProcessorFee = 1
+	if err := client.StorePaymentLog(p); err != nil {
+		t.Errorf("Error storing payment log: %s", err)
+	}
+	if err := client.StorePaymentLog(p); err != paymentlog.AlreadyExists {
+		t.Errorf("Expected %s, got %v.", paymentlog.AlreadyExists, err)
+	}
+	invalid := testPaymentLog("invalid", time.Now())
+	invalid.UserID = ""
+	if err := client.StorePaymentLog(invalid); err != paymentlog.MissingUserID {
+		t.Errorf("Expected %s, got %v.", paymentlog.MissingUserID, err)
+	}
+	result, err := client.GetPaymentLog(p.ID)
+	if err != nil {
+		t.Errorf("Error retrieving payment log: %s", err)
+	}
+	if result.ID != p.ID || !result.Created.Equal(p.Created) || !result.Updated.IsZero() ||
+		result.AccountType != p.AccountType || result.ProcessorFee != p.ProcessorFee {
+		t.Errorf("Expected %+v, got %+v.", p, result)
+	}
+	if _, err := client.GetPaymentLog("not a payment log"); err != paymentlog.LogNotFound {
+		t.Errorf("Expected %s, got %v.", paymentlog.LogNotFound, err)
+	}
+	description, fee := "new description", uint(0)
+	change := paymentlog.PaymentLogChange{Description: &description, ProcessorFee: &fee}
+	if err := client.UpdatePaymentLog(p.ID, change); err != nil {
+		t.Errorf("Error updating payment log: %s", err)
+	}
+	if updated, _ := memory.GetPaymentLog(p.ID); updated.Description != description || updated.ProcessorFee != 0 || updated.Amount != p.Amount {
+		t.Errorf("Expected only the description and processor fee to change, got %+v.", updated)
+	}
+	for pos, id := range []string{"id1", "id2", "id3"} {
+		other := testPaymentLog(id, p.Created.Add(time.Duration(pos+1)*time.Hour))
+		if err := client.StorePaymentLog(other); err != nil {
+			t.Errorf("Error storing payment log: %s", err)
+		}
+	}
+	logs, err := client.ListPaymentLogsByProject("project-id", 2, 1)
+	if err != nil {
+		t.Errorf("Error listing payment logs: %s", err)
+	}
+	if len(logs) != 2 || logs[0].ID != "id2" || logs[1].ID != "id1" {
+		t.Errorf("Expected [id2 id1], got %+v.", logs)
+	}
+	logs, err = client.ListPaymentLogsByUser("user-id", 1, 0)
+	if err != nil || len(logs) != 1 || logs[0].ID != "id3" {
+		t.Errorf("Expected [id3], got %+v, %v.", logs, err)
+	}
+	seen := 0
+	err = client.IteratePaymentLogs(func(log paymentlog.PaymentLog) error {
+		seen++
+		return nil
+	})
+	if err != nil {
+		t.Errorf("Error iterating payment logs: %s", err)
+	}
+	if seen != 4 {
+		t.Errorf("Expected to iterate over 4 payment logs, saw %d.", seen)
+	}
+	if err := client.DeletePaymentLog(p.ID); err != nil {
+		t.Errorf("Error deleting payment log: %s", err)
+	}
+	if err := client.DeletePaymentLog(p.ID); err != paymentlog.LogNotFound {
+		t.Errorf("Expected %s, got %v.", paymentlog.LogNotFound, err)
+	}
+
+	f := paymentlog.FailureLog{
+		ID:                "id",
+		PaymentLogID:      "id1",
+		Source:            paymentlog.SourceBalanced,
+		FailureReason:     "you screwed up",
+		FailureReasonCode: "500",
+		Timestamp:         time.Now(),
+	}
+	if err := client.StoreFailureLog(f); err != nil {
+		t.Errorf("Error storing failure log: %s", err)
+	}
+	failures, err := client.ListFailureLogsSince(f.Timestamp.Add(-time.Second))
+	if err != nil {
+		t.Errorf("Error listing failure logs: %s", err)
+	}
+	if len(failures) != 1 || failures[0].ID != f.ID || failures[0].Source != f.Source || !failures[0].Timestamp.Equal(f.Timestamp) {
+		t.Errorf("Expected [%+v], got %+v.", f, failures)
+	}
+	failures, err = client.ListFailureLogs(10, 0)
+	if err != nil || len(failures) != 1 || failures[0].FailureReasonCode != f.FailureReasonCode {
+		t.Errorf("Expected [%+v], got %+v, %v.", f, failures, err)
+	}
+}
+
+func TestClientListsEverythingLikeMemoryStore(t *testing.T) {
+	memory := paymentlog.NewMemoryStore()
+	conn, stop := serve(t, memory)
+	defer stop()
+	client := NewClient(conn, DefaultTimeout)
+	now := time.Now()
+	for i := 0; i < 45; i++ {
+		id := fmt.Sprintf("id%02d", i)
+		memory.StorePaymentLog(testPaymentLog(id, now))
+		memory.StoreFailureLog(paymentlog.FailureLog{ID: id, PaymentLogID: id, Timestamp: now})
+	}
+	logs, err := client.ListPaymentLogs(0, 0)
+	if err != nil || len(logs) != 45 {
+		t.Errorf("Expected all 45 payment logs, got %d, %v.", len(logs), err)
+	}
+	failures, err := client.ListFailureLogs(0, 0)
+	if err != nil || len(failures) != 45 {
+		t.Errorf("Expected all 45 failure logs, got %d, %v.", len(failures), err)
+	}
+	seen := map[string]bool{}
+	err = client.IteratePaymentLogs(func(log paymentlog.PaymentLog) error {
+		seen[log.ID] = true
+		return nil
+	})
+	if err != nil || len(seen) != 45 {
+		t.Errorf("Expected to iterate over 45 distinct payment logs, saw %d, %v.", len(seen), err)
+	}
+	seen = map[string]bool{}
+	err = client.IterateFailureLogs(func(failure paymentlog.FailureLog) error {
+		seen[failure.ID] = true
+		return nil
+	})
+	if err != nil || len(seen) != 45 {
+		t.Errorf("Expected to iterate over 45 distinct failure logs, saw %d, %v.", len(seen), err)
+	}
+}
+
+func TestClientStopsIterating(t *testing.T) {
+	memory := paymentlog.NewMemoryStore()
+	conn, stop := serve(t, memory)
+	defer stop()
+	client := NewClient(conn, DefaultTimeout)
+	now := time.Now()
+	for i := 0; i < 10; i++ {
+		memory.StorePaymentLog(testPaymentLog(fmt.Sprintf("id%d", i), now.Add(time.Duration(i)*time.Minute)))
+	}
+	seen := 0
+	err := client.IteratePaymentLogs(func(log paymentlog.PaymentLog) error {
+		seen++
+		if seen == 3 {
+			return paymentlog.StopIteration
+		}
+		return nil
+	})
+	if err != nil || seen != 3 {
+		t.Errorf("Expected to stop after 3 payment logs, saw %d, %v.", seen, err)
+	}
+	broken := errors.New("Broken callback.")
+	err = client.IteratePaymentLogs(func(log paymentlog.PaymentLog) error {
+		return broken
+	})
+	if err != broken {
+		t.Errorf("Expected %s, got %v.", broken, err)
+	}
+	if _, err := client.GetPaymentLog("id9"); err != nil {
+		t.Errorf("Expected the connection to survive a cancelled stream, got %s.", err)
+	}
+}
+
+func TestClientTimeout(t *testing.T) {
+	conn, stop := serve(t, paymentlog.NewMemoryStore())
+	defer stop()
+	client := NewClient(conn, time.Nanosecond)
+	if _, err := client.GetPaymentLog("id"); err == nil || err == paymentlog.LogNotFound {
+		t.Errorf("Expected a timeout error, got %v.", err)
+	}
+}
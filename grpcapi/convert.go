@@ -0,0 +1,207 @@
+package grpcapi
+
+import (
+	"time"
+
+	"code.whipround.net/paymentlog"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// zero times are sent as unset timestamps, and unset timestamps come back
+// as zero times
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+func fromTimestamp(t *timestamppb.Timestamp) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return t.AsTime()
+}
+
+func toPaymentLog(log paymentlog.PaymentLog) *PaymentLog {
+	return &PaymentLog{
+		Id:           log.ID,
+		Amount:       uint64(log.Amount),
+		Description:  log.Description,
+		Source:       log.Source,
+		SourceId:     log.SourceID,
+		Created:      timestamp(log.Created),
+		Updated:      timestamp(log.Updated),
+		Status:       log.Status,
+		Currency:     log.Currency,
+		ProjectId:    log.ProjectID,
+		UserId:       log.UserID,
+		AccountId:    log.AccountID,
+		AccountType:  log.AccountType,
+		ProcessorFee: uint64(log.ProcessorFee),
+		PlatformFee:  uint64(log.PlatformFee),
+		ChargedBack:  uint64(log.ChargedBack),
+	}
+}
+
+func fromPaymentLog(log *PaymentLog) paymentlog.PaymentLog {
+	return paymentlog.PaymentLog{
+		ID:           log.GetId(),
+		Amount:       uint(log.GetAmount()),
+		Description:  log.GetDescription(),
+		Source:       log.GetSource(),
+		SourceID:     log.GetSourceId(),
+		Created:      fromTimestamp(log.GetCreated()),
+		Updated:      fromTimestamp(log.GetUpdated()),
+		Status:       log.GetStatus(),
+		Currency:     log.GetCurrency(),
+		ProjectID:    log.GetProjectId(),
+		UserID:       log.GetUserId(),
+		AccountID:    log.GetAccountId(),
+		AccountType:  log.GetAccountType(),
+		ProcessorFee: uint(log.GetProcessorFee()),
+		PlatformFee:  uint(log.GetPlatformFee()),
+		ChargedBack:  uint(log.GetChargedBack()),
+	}
+}
+
+func toPaymentLogs(logs []paymentlog.PaymentLog) *PaymentLogs {
+	result := &PaymentLogs{PaymentLogs: make([]*PaymentLog, 0, len(logs))}
+	for _, log := range logs {
+		result.PaymentLogs = append(result.PaymentLogs, toPaymentLog(log))
+	}
+	return result
+}
+
+func fromPaymentLogs(logs *PaymentLogs) []paymentlog.PaymentLog {
+	result := make([]paymentlog.PaymentLog, 0, len(logs.GetPaymentLogs()))
+	for _, log := range logs.GetPaymentLogs() {
+		result = append(result, fromPaymentLog(log))
+	}
+	return result
+}
+
+func toUint(v uint) *wrapperspb.UInt64Value {
+	return wrapperspb.UInt64(uint64(v))
+}
+
+func toPaymentLogChange(change paymentlog.PaymentLogChange) *PaymentLogChange {
+	result := &PaymentLogChange{}
+	if change.Amount != nil {
+		result.Amount = toUint(*change.Amount)
+	}
+	if change.Description != nil {
+		result.Description = wrapperspb.String(*change.Description)
+	}
+	if change.Source != nil {
+		result.Source = wrapperspb.String(*change.Source)
+	}
+	if change.SourceID != nil {
+		result.SourceId = wrapperspb.String(*change.SourceID)
+	}
+	if change.Created != nil {
+		result.Created = timestamppb.New(*change.Created)
+	}
+	if change.Updated != nil {
+		result.Updated = timestamppb.New(*change.Updated)
+	}
+	if change.Status != nil {
+		result.Status = wrapperspb.String(*change.Status)
+	}
+	if change.Currency != nil {
+		result.Currency = wrapperspb.String(*change.Currency)
+	}
+	if change.ProcessorFee != nil {
+		result.ProcessorFee = toUint(*change.ProcessorFee)
+	}
+	if change.PlatformFee != nil {
+		result.PlatformFee = toUint(*change.PlatformFee)
+	}
+	return result
+}
+
+func fromUint(v *wrapperspb.UInt64Value) *uint {
+	if v == nil {
+		return nil
+	}
+	result := uint(v.GetValue())
+	return &result
+}
+
+func fromString(v *wrapperspb.StringValue) *string {
+	if v == nil {
+		return nil
+	}
+	result := v.GetValue()
+	return &result
+}
+
+func fromTime(t *timestamppb.Timestamp) *time.Time {
+	if t == nil {
+		return nil
+	}
+	result := t.AsTime()
+	return &result
+}
+
+func fromPaymentLogChange(change *PaymentLogChange) paymentlog.PaymentLogChange {
+	return paymentlog.PaymentLogChange{
+		Amount:       fromUint(change.GetAmount()),
+		Description:  fromString(change.GetDescription()),
+		Source:       fromString(change.GetSource()),
+		SourceID:     fromString(change.GetSourceId()),
+		Created:      fromTime(change.GetCreated()),
+		Updated:      fromTime(change.GetUpdated()),
+		Status:       fromString(change.GetStatus()),
+		Currency:     fromString(change.GetCurrency()),
+		ProcessorFee: fromUint(change.GetProcessorFee()),
+		PlatformFee:  fromUint(change.GetPlatformFee()),
+	}
+}
+
+func toFailureLog(failure paymentlog.FailureLog) *FailureLog {
+	return &FailureLog{
+		Id:                failure.ID,
+		PaymentLogId:      failure.PaymentLogID,
+		FailureReason:     failure.FailureReason,
+		FailureReasonCode: failure.FailureReasonCode,
+		Timestamp:         timestamp(failure.Timestamp),
+		Source:            failure.Source,
+		State:             failure.State,
+		Resolver:          failure.Resolver,
+		ResolutionNote:    failure.ResolutionNote,
+		StateUpdated:      timestamp(failure.StateUpdated),
+	}
+}
+
+func fromFailureLog(failure *FailureLog) paymentlog.FailureLog {
+	return paymentlog.FailureLog{
+		ID:                failure.GetId(),
+		PaymentLogID:      failure.GetPaymentLogId(),
+		FailureReason:     failure.GetFailureReason(),
+		FailureReasonCode: failure.GetFailureReasonCode(),
+		Timestamp:         fromTimestamp(failure.GetTimestamp()),
+		Source:            failure.GetSource(),
+		State:             failure.GetState(),
+		Resolver:          failure.GetResolver(),
+		ResolutionNote:    failure.GetResolutionNote(),
+		StateUpdated:      fromTimestamp(failure.GetStateUpdated()),
+	}
+}
+
+func toFailureLogs(failures []paymentlog.FailureLog) *FailureLogs {
+	result := &FailureLogs{FailureLogs: make([]*FailureLog, 0, len(failures))}
+	for _, failure := range failures {
+		result.FailureLogs = append(result.FailureLogs, toFailureLog(failure))
+	}
+	return result
+}
+
+func fromFailureLogs(failures *FailureLogs) []paymentlog.FailureLog {
+	result := make([]paymentlog.FailureLog, 0, len(failures.GetFailureLogs()))
+	for _, failure := range failures.GetFailureLogs() {
+		result = append(result, fromFailureLog(failure))
+	}
+	return result
+}
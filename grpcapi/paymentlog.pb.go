@@ -0,0 +1,1009 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.9
+// 	protoc        (unknown)
+// source: paymentlog.proto
+
+package grpcapi
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	wrapperspb "google.golang.org/protobuf/types/known/wrapperspb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type PaymentLog struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Amount        uint64                 `protobuf:"varint,2,opt,name=amount,proto3" json:"amount,omitempty"`
+	Description   string                 `protobuf:"bytes,3,opt,name=description,proto3" json:"description,omitempty"`
+	Source        string                 `protobuf:"bytes,4,opt,name=source,proto3" json:"source,omitempty"`
+	SourceId      string                 `protobuf:"bytes,5,opt,name=source_id,json=sourceId,proto3" json:"source_id,omitempty"`
+	Created       *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=created,proto3" json:"created,omitempty"`
+	Updated       *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=updated,proto3" json:"updated,omitempty"`
+	Status        string                 `protobuf:"bytes,8,opt,name=status,proto3" json:"status,omitempty"`
+	Currency      string                 `protobuf:"bytes,9,opt,name=currency,proto3" json:"currency,omitempty"`
+	ProjectId     string                 `protobuf:"bytes,10,opt,name=project_id,json=projectId,proto3" json:"project_id,omitempty"`
+	UserId        string                 `protobuf:"bytes,11,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	AccountId     string                 `protobuf:"bytes,12,opt,name=account_id,json=accountId,proto3" json:"account_id,omitempty"`
+	AccountType   string                 `protobuf:"bytes,13,opt,name=account_type,json=accountType,proto3" json:"account_type,omitempty"`
+	ProcessorFee  uint64                 `protobuf:"varint,14,opt,name=processor_fee,json=processorFee,proto3" json:"processor_fee,omitempty"`
+	PlatformFee   uint64                 `protobuf:"varint,15,opt,name=platform_fee,json=platformFee,proto3" json:"platform_fee,omitempty"`
+	ChargedBack   uint64                 `protobuf:"varint,16,opt,name=charged_back,json=chargedBack,proto3" json:"charged_back,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PaymentLog) Reset() {
+	*x = PaymentLog{}
+	mi := &file_paymentlog_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PaymentLog) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PaymentLog) ProtoMessage() {}
+
+func (x *PaymentLog) ProtoReflect() protoreflect.Message {
+	mi := &file_paymentlog_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PaymentLog.ProtoReflect.Descriptor instead.
+func (*PaymentLog) Descriptor() ([]byte, []int) {
+	return file_paymentlog_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *PaymentLog) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *PaymentLog) GetAmount() uint64 {
+	if x != nil {
+		return x.Amount
+	}
+	return 0
+}
+
+func (x *PaymentLog) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *PaymentLog) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *PaymentLog) GetSourceId() string {
+	if x != nil {
+		return x.SourceId
+	}
+	return ""
+}
+
+func (x *PaymentLog) GetCreated() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Created
+	}
+	return nil
+}
+
+func (x *PaymentLog) GetUpdated() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Updated
+	}
+	return nil
+}
+
+func (x *PaymentLog) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *PaymentLog) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+func (x *PaymentLog) GetProjectId() string {
+	if x != nil {
+		return x.ProjectId
+	}
+	return ""
+}
+
+func (x *PaymentLog) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *PaymentLog) GetAccountId() string {
+	if x != nil {
+		return x.AccountId
+	}
+	return ""
+}
+
+func (x *PaymentLog) GetAccountType() string {
+	if x != nil {
+		return x.AccountType
+	}
+	return ""
+}
+
+func (x *PaymentLog) GetProcessorFee() uint64 {
+	if x != nil {
+		return x.ProcessorFee
+	}
+	return 0
+}
+
+func (x *PaymentLog) GetPlatformFee() uint64 {
+	if x != nil {
+		return x.PlatformFee
+	}
+	return 0
+}
+
+func (x *PaymentLog) GetChargedBack() uint64 {
+	if x != nil {
+		return x.ChargedBack
+	}
+	return 0
+}
+
+// PaymentLogChange uses wrapper types so that an unset field can be told
+// apart from a field being set to its zero value.
+type PaymentLogChange struct {
+	state         protoimpl.MessageState  `protogen:"open.v1"`
+	Amount        *wrapperspb.UInt64Value `protobuf:"bytes,1,opt,name=amount,proto3" json:"amount,omitempty"`
+	Description   *wrapperspb.StringValue `protobuf:"bytes,2,opt,name=description,proto3" json:"description,omitempty"`
+	Source        *wrapperspb.StringValue `protobuf:"bytes,3,opt,name=source,proto3" json:"source,omitempty"`
+	SourceId      *wrapperspb.StringValue `protobuf:"bytes,4,opt,name=source_id,json=sourceId,proto3" json:"source_id,omitempty"`
+	Created       *timestamppb.Timestamp  `protobuf:"bytes,5,opt,name=created,proto3" json:"created,omitempty"`
+	Updated       *timestamppb.Timestamp  `protobuf:"bytes,6,opt,name=updated,proto3" json:"updated,omitempty"`
+	Status        *wrapperspb.StringValue `protobuf:"bytes,7,opt,name=status,proto3" json:"status,omitempty"`
+	Currency      *wrapperspb.StringValue `protobuf:"bytes,8,opt,name=currency,proto3" json:"currency,omitempty"`
+	ProcessorFee  *wrapperspb.UInt64Value `protobuf:"bytes,9,opt,name=processor_fee,json=processorFee,proto3" json:"processor_fee,omitempty"`
+	PlatformFee   *wrapperspb.UInt64Value `protobuf:"bytes,10,opt,name=platform_fee,json=platformFee,proto3" json:"platform_fee,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PaymentLogChange) Reset() {
+	*x = PaymentLogChange{}
+	mi := &file_paymentlog_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PaymentLogChange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PaymentLogChange) ProtoMessage() {}
+
+func (x *PaymentLogChange) ProtoReflect() protoreflect.Message {
+	mi := &file_paymentlog_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PaymentLogChange.ProtoReflect.Descriptor instead.
+func (*PaymentLogChange) Descriptor() ([]byte, []int) {
+	return file_paymentlog_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *PaymentLogChange) GetAmount() *wrapperspb.UInt64Value {
+	if x != nil {
+		return x.Amount
+	}
+	return nil
+}
+
+func (x *PaymentLogChange) GetDescription() *wrapperspb.StringValue {
+	if x != nil {
+		return x.Description
+	}
+	return nil
+}
+
+func (x *PaymentLogChange) GetSource() *wrapperspb.StringValue {
+	if x != nil {
+		return x.Source
+	}
+	return nil
+}
+
+func (x *PaymentLogChange) GetSourceId() *wrapperspb.StringValue {
+	if x != nil {
+		return x.SourceId
+	}
+	return nil
+}
+
+func (x *PaymentLogChange) GetCreated() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Created
+	}
+	return nil
+}
+
+func (x *PaymentLogChange) GetUpdated() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Updated
+	}
+	return nil
+}
+
+func (x *PaymentLogChange) GetStatus() *wrapperspb.StringValue {
+	if x != nil {
+		return x.Status
+	}
+	return nil
+}
+
+func (x *PaymentLogChange) GetCurrency() *wrapperspb.StringValue {
+	if x != nil {
+		return x.Currency
+	}
+	return nil
+}
+
+func (x *PaymentLogChange) GetProcessorFee() *wrapperspb.UInt64Value {
+	if x != nil {
+		return x.ProcessorFee
+	}
+	return nil
+}
+
+func (x *PaymentLogChange) GetPlatformFee() *wrapperspb.UInt64Value {
+	if x != nil {
+		return x.PlatformFee
+	}
+	return nil
+}
+
+type FailureLog struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	Id                string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	PaymentLogId      string                 `protobuf:"bytes,2,opt,name=payment_log_id,json=paymentLogId,proto3" json:"payment_log_id,omitempty"`
+	FailureReason     string                 `protobuf:"bytes,3,opt,name=failure_reason,json=failureReason,proto3" json:"failure_reason,omitempty"`
+	FailureReasonCode string                 `protobuf:"bytes,4,opt,name=failure_reason_code,json=failureReasonCode,proto3" json:"failure_reason_code,omitempty"`
+	Timestamp         *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Source            string                 `protobuf:"bytes,6,opt,name=source,proto3" json:"source,omitempty"`
+	State             string                 `protobuf:"bytes,7,opt,name=state,proto3" json:"state,omitempty"`
+	Resolver          string                 `protobuf:"bytes,8,opt,name=resolver,proto3" json:"resolver,omitempty"`
+	ResolutionNote    string                 `protobuf:"bytes,9,opt,name=resolution_note,json=resolutionNote,proto3" json:"resolution_note,omitempty"`
+	StateUpdated      *timestamppb.Timestamp `protobuf:"bytes,10,opt,name=state_updated,json=stateUpdated,proto3" json:"state_updated,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *FailureLog) Reset() {
+	*x = FailureLog{}
+	mi := &file_paymentlog_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FailureLog) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FailureLog) ProtoMessage() {}
+
+func (x *FailureLog) ProtoReflect() protoreflect.Message {
+	mi := &file_paymentlog_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FailureLog.ProtoReflect.Descriptor instead.
+func (*FailureLog) Descriptor() ([]byte, []int) {
+	return file_paymentlog_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *FailureLog) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *FailureLog) GetPaymentLogId() string {
+	if x != nil {
+		return x.PaymentLogId
+	}
+	return ""
+}
+
+func (x *FailureLog) GetFailureReason() string {
+	if x != nil {
+		return x.FailureReason
+	}
+	return ""
+}
+
+func (x *FailureLog) GetFailureReasonCode() string {
+	if x != nil {
+		return x.FailureReasonCode
+	}
+	return ""
+}
+
+func (x *FailureLog) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+func (x *FailureLog) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *FailureLog) GetState() string {
+	if x != nil {
+		return x.State
+	}
+	return ""
+}
+
+func (x *FailureLog) GetResolver() string {
+	if x != nil {
+		return x.Resolver
+	}
+	return ""
+}
+
+func (x *FailureLog) GetResolutionNote() string {
+	if x != nil {
+		return x.ResolutionNote
+	}
+	return ""
+}
+
+func (x *FailureLog) GetStateUpdated() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StateUpdated
+	}
+	return nil
+}
+
+type PaymentLogID struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PaymentLogID) Reset() {
+	*x = PaymentLogID{}
+	mi := &file_paymentlog_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PaymentLogID) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PaymentLogID) ProtoMessage() {}
+
+func (x *PaymentLogID) ProtoReflect() protoreflect.Message {
+	mi := &file_paymentlog_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PaymentLogID.ProtoReflect.Descriptor instead.
+func (*PaymentLogID) Descriptor() ([]byte, []int) {
+	return file_paymentlog_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *PaymentLogID) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type UpdatePaymentLogRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Change        *PaymentLogChange      `protobuf:"bytes,2,opt,name=change,proto3" json:"change,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdatePaymentLogRequest) Reset() {
+	*x = UpdatePaymentLogRequest{}
+	mi := &file_paymentlog_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdatePaymentLogRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdatePaymentLogRequest) ProtoMessage() {}
+
+func (x *UpdatePaymentLogRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_paymentlog_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdatePaymentLogRequest.ProtoReflect.Descriptor instead.
+func (*UpdatePaymentLogRequest) Descriptor() ([]byte, []int) {
+	return file_paymentlog_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *UpdatePaymentLogRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *UpdatePaymentLogRequest) GetChange() *PaymentLogChange {
+	if x != nil {
+		return x.Change
+	}
+	return nil
+}
+
+type Page struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Num           int32                  `protobuf:"varint,1,opt,name=num,proto3" json:"num,omitempty"`
+	Offset        int32                  `protobuf:"varint,2,opt,name=offset,proto3" json:"offset,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Page) Reset() {
+	*x = Page{}
+	mi := &file_paymentlog_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Page) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Page) ProtoMessage() {}
+
+func (x *Page) ProtoReflect() protoreflect.Message {
+	mi := &file_paymentlog_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Page.ProtoReflect.Descriptor instead.
+func (*Page) Descriptor() ([]byte, []int) {
+	return file_paymentlog_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Page) GetNum() int32 {
+	if x != nil {
+		return x.Num
+	}
+	return 0
+}
+
+func (x *Page) GetOffset() int32 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+type ListByProjectRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ProjectId     string                 `protobuf:"bytes,1,opt,name=project_id,json=projectId,proto3" json:"project_id,omitempty"`
+	Page          *Page                  `protobuf:"bytes,2,opt,name=page,proto3" json:"page,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListByProjectRequest) Reset() {
+	*x = ListByProjectRequest{}
+	mi := &file_paymentlog_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListByProjectRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListByProjectRequest) ProtoMessage() {}
+
+func (x *ListByProjectRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_paymentlog_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListByProjectRequest.ProtoReflect.Descriptor instead.
+func (*ListByProjectRequest) Descriptor() ([]byte, []int) {
+	return file_paymentlog_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ListByProjectRequest) GetProjectId() string {
+	if x != nil {
+		return x.ProjectId
+	}
+	return ""
+}
+
+func (x *ListByProjectRequest) GetPage() *Page {
+	if x != nil {
+		return x.Page
+	}
+	return nil
+}
+
+type ListByUserRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Page          *Page                  `protobuf:"bytes,2,opt,name=page,proto3" json:"page,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListByUserRequest) Reset() {
+	*x = ListByUserRequest{}
+	mi := &file_paymentlog_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListByUserRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListByUserRequest) ProtoMessage() {}
+
+func (x *ListByUserRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_paymentlog_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListByUserRequest.ProtoReflect.Descriptor instead.
+func (*ListByUserRequest) Descriptor() ([]byte, []int) {
+	return file_paymentlog_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ListByUserRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *ListByUserRequest) GetPage() *Page {
+	if x != nil {
+		return x.Page
+	}
+	return nil
+}
+
+type SinceRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Timestamp     *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SinceRequest) Reset() {
+	*x = SinceRequest{}
+	mi := &file_paymentlog_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SinceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SinceRequest) ProtoMessage() {}
+
+func (x *SinceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_paymentlog_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SinceRequest.ProtoReflect.Descriptor instead.
+func (*SinceRequest) Descriptor() ([]byte, []int) {
+	return file_paymentlog_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *SinceRequest) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+type PaymentLogs struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PaymentLogs   []*PaymentLog          `protobuf:"bytes,1,rep,name=payment_logs,json=paymentLogs,proto3" json:"payment_logs,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PaymentLogs) Reset() {
+	*x = PaymentLogs{}
+	mi := &file_paymentlog_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PaymentLogs) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PaymentLogs) ProtoMessage() {}
+
+func (x *PaymentLogs) ProtoReflect() protoreflect.Message {
+	mi := &file_paymentlog_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PaymentLogs.ProtoReflect.Descriptor instead.
+func (*PaymentLogs) Descriptor() ([]byte, []int) {
+	return file_paymentlog_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *PaymentLogs) GetPaymentLogs() []*PaymentLog {
+	if x != nil {
+		return x.PaymentLogs
+	}
+	return nil
+}
+
+type FailureLogs struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	FailureLogs   []*FailureLog          `protobuf:"bytes,1,rep,name=failure_logs,json=failureLogs,proto3" json:"failure_logs,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FailureLogs) Reset() {
+	*x = FailureLogs{}
+	mi := &file_paymentlog_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FailureLogs) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FailureLogs) ProtoMessage() {}
+
+func (x *FailureLogs) ProtoReflect() protoreflect.Message {
+	mi := &file_paymentlog_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FailureLogs.ProtoReflect.Descriptor instead.
+func (*FailureLogs) Descriptor() ([]byte, []int) {
+	return file_paymentlog_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *FailureLogs) GetFailureLogs() []*FailureLog {
+	if x != nil {
+		return x.FailureLogs
+	}
+	return nil
+}
+
+var File_paymentlog_proto protoreflect.FileDescriptor
+
+const file_paymentlog_proto_rawDesc = "" +
+	"\n" +
+	"\x10paymentlog.proto\x12\n" +
+	"paymentlog\x1a\x1fgoogle/protobuf/timestamp.proto\x1a\x1egoogle/protobuf/wrappers.proto\x1a\x1bgoogle/protobuf/empty.proto\"\x90\x04\n" +
+	"\n" +
+	"PaymentLog\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x16\n" +
+	"\x06amount\x18\x02 \x01(\x04R\x06amount\x12 \n" +
+	"\vdescription\x18\x03 \x01(\tR\vdescription\x12\x16\n" +
+	"\x06source\x18\x04 \x01(\tR\x06source\x12\x1b\n" +
+	"\tsource_id\x18\x05 \x01(\tR\bsourceId\x124\n" +
+	"\acreated\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\acreated\x124\n" +
+	"\aupdated\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\aupdated\x12\x16\n" +
+	"\x06status\x18\b \x01(\tR\x06status\x12\x1a\n" +
+	"\bcurrency\x18\t \x01(\tR\bcurrency\x12\x1d\n" +
+	"\n" +
+	"project_id\x18\n" +
+	" \x01(\tR\tprojectId\x12\x17\n" +
+	"\auser_id\x18\v \x01(\tR\x06userId\x12\x1d\n" +
+	"\n" +
+	"account_id\x18\f \x01(\tR\taccountId\x12!\n" +
+	"\faccount_type\x18\r \x01(\tR\vaccountType\x12#\n" +
+	"\rprocessor_fee\x18\x0e \x01(\x04R\fprocessorFee\x12!\n" +
+	"\fplatform_fee\x18\x0f \x01(\x04R\vplatformFee\x12!\n" +
+	"\fcharged_back\x18\x10 \x01(\x04R\vchargedBack\"\xd9\x04\n" +
+	"\x10PaymentLogChange\x124\n" +
+	"\x06amount\x18\x01 \x01(\v2\x1c.google.protobuf.UInt64ValueR\x06amount\x12>\n" +
+	"\vdescription\x18\x02 \x01(\v2\x1c.google.protobuf.StringValueR\vdescription\x124\n" +
+	"\x06source\x18\x03 \x01(\v2\x1c.google.protobuf.StringValueR\x06source\x129\n" +
+	"\tsource_id\x18\x04 \x01(\v2\x1c.google.protobuf.StringValueR\bsourceId\x124\n" +
+	"\acreated\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\acreated\x124\n" +
+	"\aupdated\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\aupdated\x124\n" +
+	"\x06status\x18\a \x01(\v2\x1c.google.protobuf.StringValueR\x06status\x128\n" +
+	"\bcurrency\x18\b \x01(\v2\x1c.google.protobuf.StringValueR\bcurrency\x12A\n" +
+	"\rprocessor_fee\x18\t \x01(\v2\x1c.google.protobuf.UInt64ValueR\fprocessorFee\x12?\n" +
+	"\fplatform_fee\x18\n" +
+	" \x01(\v2\x1c.google.protobuf.UInt64ValueR\vplatformFee\"\x87\x03\n" +
+	"\n" +
+	"FailureLog\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12$\n" +
+	"\x0epayment_log_id\x18\x02 \x01(\tR\fpaymentLogId\x12%\n" +
+	"\x0efailure_reason\x18\x03 \x01(\tR\rfailureReason\x12.\n" +
+	"\x13failure_reason_code\x18\x04 \x01(\tR\x11failureReasonCode\x128\n" +
+	"\ttimestamp\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\x12\x16\n" +
+	"\x06source\x18\x06 \x01(\tR\x06source\x12\x14\n" +
+	"\x05state\x18\a \x01(\tR\x05state\x12\x1a\n" +
+	"\bresolver\x18\b \x01(\tR\bresolver\x12'\n" +
+	"\x0fresolution_note\x18\t \x01(\tR\x0eresolutionNote\x12?\n" +
+	"\rstate_updated\x18\n" +
+	" \x01(\v2\x1a.google.protobuf.TimestampR\fstateUpdated\"\x1e\n" +
+	"\fPaymentLogID\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"_\n" +
+	"\x17UpdatePaymentLogRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x124\n" +
+	"\x06change\x18\x02 \x01(\v2\x1c.paymentlog.PaymentLogChangeR\x06change\"0\n" +
+	"\x04Page\x12\x10\n" +
+	"\x03num\x18\x01 \x01(\x05R\x03num\x12\x16\n" +
+	"\x06offset\x18\x02 \x01(\x05R\x06offset\"[\n" +
+	"\x14ListByProjectRequest\x12\x1d\n" +
+	"\n" +
+	"project_id\x18\x01 \x01(\tR\tprojectId\x12$\n" +
+	"\x04page\x18\x02 \x01(\v2\x10.paymentlog.PageR\x04page\"R\n" +
+	"\x11ListByUserRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12$\n" +
+	"\x04page\x18\x02 \x01(\v2\x10.paymentlog.PageR\x04page\"H\n" +
+	"\fSinceRequest\x128\n" +
+	"\ttimestamp\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\"H\n" +
+	"\vPaymentLogs\x129\n" +
+	"\fpayment_logs\x18\x01 \x03(\v2\x16.paymentlog.PaymentLogR\vpaymentLogs\"H\n" +
+	"\vFailureLogs\x129\n" +
+	"\ffailure_logs\x18\x01 \x03(\v2\x16.paymentlog.FailureLogR\vfailureLogs2\xd7\b\n" +
+	"\bLogStore\x12A\n" +
+	"\x0fStorePaymentLog\x12\x16.paymentlog.PaymentLog\x1a\x16.google.protobuf.Empty\x12O\n" +
+	"\x10UpdatePaymentLog\x12#.paymentlog.UpdatePaymentLogRequest\x1a\x16.google.protobuf.Empty\x12D\n" +
+	"\x10DeletePaymentLog\x12\x18.paymentlog.PaymentLogID\x1a\x16.google.protobuf.Empty\x12A\n" +
+	"\rGetPaymentLog\x12\x18.paymentlog.PaymentLogID\x1a\x16.paymentlog.PaymentLog\x12U\n" +
+	"\x18ListPaymentLogsByProject\x12 .paymentlog.ListByProjectRequest\x1a\x17.paymentlog.PaymentLogs\x12O\n" +
+	"\x15ListPaymentLogsByUser\x12\x1d.paymentlog.ListByUserRequest\x1a\x17.paymentlog.PaymentLogs\x12<\n" +
+	"\x0fListPaymentLogs\x12\x10.paymentlog.Page\x1a\x17.paymentlog.PaymentLogs\x12A\n" +
+	"\x0fStoreFailureLog\x12\x16.paymentlog.FailureLog\x1a\x16.google.protobuf.Empty\x12<\n" +
+	"\x0fListFailureLogs\x12\x10.paymentlog.Page\x1a\x17.paymentlog.FailureLogs\x12I\n" +
+	"\x14ListFailureLogsSince\x12\x18.paymentlog.SinceRequest\x1a\x17.paymentlog.FailureLogs\x12X\n" +
+	"\x1aStreamPaymentLogsByProject\x12 .paymentlog.ListByProjectRequest\x1a\x16.paymentlog.PaymentLog0\x01\x12R\n" +
+	"\x17StreamPaymentLogsByUser\x12\x1d.paymentlog.ListByUserRequest\x1a\x16.paymentlog.PaymentLog0\x01\x12?\n" +
+	"\x11StreamPaymentLogs\x12\x10.paymentlog.Page\x1a\x16.paymentlog.PaymentLog0\x01\x12?\n" +
+	"\x11StreamFailureLogs\x12\x10.paymentlog.Page\x1a\x16.paymentlog.FailureLog0\x01\x12L\n" +
+	"\x16StreamFailureLogsSince\x12\x18.paymentlog.SinceRequest\x1a\x16.paymentlog.FailureLog0\x01B'Z%code.whipround.net/paymentlog/grpcapib\x06proto3"
+
+var (
+	file_paymentlog_proto_rawDescOnce sync.Once
+	file_paymentlog_proto_rawDescData []byte
+)
+
+func file_paymentlog_proto_rawDescGZIP() []byte {
+	file_paymentlog_proto_rawDescOnce.Do(func() {
+		file_paymentlog_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_paymentlog_proto_rawDesc), len(file_paymentlog_proto_rawDesc)))
+	})
+	return file_paymentlog_proto_rawDescData
+}
+
+var file_paymentlog_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
+var file_paymentlog_proto_goTypes = []any{
+	(*PaymentLog)(nil),              // 0: paymentlog.PaymentLog
+	(*PaymentLogChange)(nil),        // 1: paymentlog.PaymentLogChange
+	(*FailureLog)(nil),              // 2: paymentlog.FailureLog
+	(*PaymentLogID)(nil),            // 3: paymentlog.PaymentLogID
+	(*UpdatePaymentLogRequest)(nil), // 4: paymentlog.UpdatePaymentLogRequest
+	(*Page)(nil),                    // 5: paymentlog.Page
+	(*ListByProjectRequest)(nil),    // 6: paymentlog.ListByProjectRequest
+	(*ListByUserRequest)(nil),       // 7: paymentlog.ListByUserRequest
+	(*SinceRequest)(nil),            // 8: paymentlog.SinceRequest
+	(*PaymentLogs)(nil),             // 9: paymentlog.PaymentLogs
+	(*FailureLogs)(nil),             // 10: paymentlog.FailureLogs
+	(*timestamppb.Timestamp)(nil),   // 11: google.protobuf.Timestamp
+	(*wrapperspb.UInt64Value)(nil),  // 12: google.protobuf.UInt64Value
+	(*wrapperspb.StringValue)(nil),  // 13: google.protobuf.StringValue
+	(*emptypb.Empty)(nil),           // 14: google.protobuf.Empty
+}
+var file_paymentlog_proto_depIdxs = []int32{
+	11, // 0: paymentlog.PaymentLog.created:type_name -> google.protobuf.Timestamp
+	11, // 1: paymentlog.PaymentLog.updated:type_name -> google.protobuf.Timestamp
+	12, // 2: paymentlog.PaymentLogChange.amount:type_name -> google.protobuf.UInt64Value
+	13, // 3: paymentlog.PaymentLogChange.description:type_name -> google.protobuf.StringValue
+	13, // 4: paymentlog.PaymentLogChange.source:type_name -> google.protobuf.StringValue
+	13, // 5: paymentlog.PaymentLogChange.source_id:type_name -> google.protobuf.StringValue
+	11, // 6: paymentlog.PaymentLogChange.created:type_name -> google.protobuf.Timestamp
+	11, // 7: paymentlog.PaymentLogChange.updated:type_name -> google.protobuf.Timestamp
+	13, // 8: paymentlog.PaymentLogChange.status:type_name -> google.protobuf.StringValue
+	13, // 9: paymentlog.PaymentLogChange.currency:type_name -> google.protobuf.StringValue
+	12, // 10: paymentlog.PaymentLogChange.processor_fee:type_name -> google.protobuf.UInt64Value
+	12, // 11: paymentlog.PaymentLogChange.platform_fee:type_name -> google.protobuf.UInt64Value
+	11, // 12: paymentlog.FailureLog.timestamp:type_name -> google.protobuf.Timestamp
+	11, // 13: paymentlog.FailureLog.state_updated:type_name -> google.protobuf.Timestamp
+	1,  // 14: paymentlog.UpdatePaymentLogRequest.change:type_name -> paymentlog.PaymentLogChange
+	5,  // 15: paymentlog.ListByProjectRequest.page:type_name -> paymentlog.Page
+	5,  // 16: paymentlog.ListByUserRequest.page:type_name -> paymentlog.Page
+	11, // 17: paymentlog.SinceRequest.timestamp:type_name -> google.protobuf.Timestamp
+	0,  // 18: paymentlog.PaymentLogs.payment_logs:type_name -> paymentlog.PaymentLog
+	2,  // 19: paymentlog.FailureLogs.failure_logs:type_name -> paymentlog.FailureLog
+	0,  // 20: paymentlog.LogStore.StorePaymentLog:input_type -> paymentlog.PaymentLog
+	4,  // 21: paymentlog.LogStore.UpdatePaymentLog:input_type -> paymentlog.UpdatePaymentLogRequest
+	3,  // 22: paymentlog.LogStore.DeletePaymentLog:input_type -> paymentlog.PaymentLogID
+	3,  // 23: paymentlog.LogStore.GetPaymentLog:input_type -> paymentlog.PaymentLogID
+	6,  // 24: paymentlog.LogStore.ListPaymentLogsByProject:input_type -> paymentlog.ListByProjectRequest
+	7,  // 25: paymentlog.LogStore.ListPaymentLogsByUser:input_type -> paymentlog.ListByUserRequest
+	5,  // 26: paymentlog.LogStore.ListPaymentLogs:input_type -> paymentlog.Page
+	2,  // 27: paymentlog.LogStore.StoreFailureLog:input_type -> paymentlog.FailureLog
+	5,  // 28: paymentlog.LogStore.ListFailureLogs:input_type -> paymentlog.Page
+	8,  // 29: paymentlog.LogStore.ListFailureLogsSince:input_type -> paymentlog.SinceRequest
+	6,  // 30: paymentlog.LogStore.StreamPaymentLogsByProject:input_type -> paymentlog.ListByProjectRequest
+	7,  // 31: paymentlog.LogStore.StreamPaymentLogsByUser:input_type -> paymentlog.ListByUserRequest
+	5,  // 32: paymentlog.LogStore.StreamPaymentLogs:input_type -> paymentlog.Page
+	5,  // 33: paymentlog.LogStore.StreamFailureLogs:input_type -> paymentlog.Page
+	8,  // 34: paymentlog.LogStore.StreamFailureLogsSince:input_type -> paymentlog.SinceRequest
+	14, // 35: paymentlog.LogStore.StorePaymentLog:output_type -> google.protobuf.Empty
+	14, // 36: paymentlog.LogStore.UpdatePaymentLog:output_type -> google.protobuf.Empty
+	14, // 37: paymentlog.LogStore.DeletePaymentLog:output_type -> google.protobuf.Empty
+	0,  // 38: paymentlog.LogStore.GetPaymentLog:output_type -> paymentlog.PaymentLog
+	9,  // 39: paymentlog.LogStore.ListPaymentLogsByProject:output_type -> paymentlog.PaymentLogs
+	9,  // 40: paymentlog.LogStore.ListPaymentLogsByUser:output_type -> paymentlog.PaymentLogs
+	9,  // 41: paymentlog.LogStore.ListPaymentLogs:output_type -> paymentlog.PaymentLogs
+	14, // 42: paymentlog.LogStore.StoreFailureLog:output_type -> google.protobuf.Empty
+	10, // 43: paymentlog.LogStore.ListFailureLogs:output_type -> paymentlog.FailureLogs
+	10, // 44: paymentlog.LogStore.ListFailureLogsSince:output_type -> paymentlog.FailureLogs
+	0,  // 45: paymentlog.LogStore.StreamPaymentLogsByProject:output_type -> paymentlog.PaymentLog
+	0,  // 46: paymentlog.LogStore.StreamPaymentLogsByUser:output_type -> paymentlog.PaymentLog
+	0,  // 47: paymentlog.LogStore.StreamPaymentLogs:output_type -> paymentlog.PaymentLog
+	2,  // 48: paymentlog.LogStore.StreamFailureLogs:output_type -> paymentlog.FailureLog
+	2,  // 49: paymentlog.LogStore.StreamFailureLogsSince:output_type -> paymentlog.FailureLog
+	35, // [35:50] is the sub-list for method output_type
+	20, // [20:35] is the sub-list for method input_type
+	20, // [20:20] is the sub-list for extension type_name
+	20, // [20:20] is the sub-list for extension extendee
+	0,  // [0:20] is the sub-list for field type_name
+}
+
+func init() { file_paymentlog_proto_init() }
+func file_paymentlog_proto_init() {
+	if File_paymentlog_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_paymentlog_proto_rawDesc), len(file_paymentlog_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   11,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_paymentlog_proto_goTypes,
+		DependencyIndexes: file_paymentlog_proto_depIdxs,
+		MessageInfos:      file_paymentlog_proto_msgTypes,
+	}.Build()
+	File_paymentlog_proto = out.File
+	file_paymentlog_proto_goTypes = nil
+	file_paymentlog_proto_depIdxs = nil
+}
@@ -0,0 +1,686 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.0
+// - protoc             (unknown)
+// source: paymentlog.proto
+
+package grpcapi
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	LogStore_StorePaymentLog_FullMethodName            = "/paymentlog.LogStore/StorePaymentLog"
+	LogStore_UpdatePaymentLog_FullMethodName           = "/paymentlog.LogStore/UpdatePaymentLog"
+	LogStore_DeletePaymentLog_FullMethodName           = "/paymentlog.LogStore/DeletePaymentLog"
+	LogStore_GetPaymentLog_FullMethodName              = "/paymentlog.LogStore/GetPaymentLog"
+	LogStore_ListPaymentLogsByProject_FullMethodName   = "/paymentlog.LogStore/ListPaymentLogsByProject"
+	LogStore_ListPaymentLogsByUser_FullMethodName      = "/paymentlog.LogStore/ListPaymentLogsByUser"
+	LogStore_ListPaymentLogs_FullMethodName            = "/paymentlog.LogStore/ListPaymentLogs"
+	LogStore_StoreFailureLog_FullMethodName            = "/paymentlog.LogStore/StoreFailureLog"
+	LogStore_ListFailureLogs_FullMethodName            = "/paymentlog.LogStore/ListFailureLogs"
+	LogStore_ListFailureLogsSince_FullMethodName       = "/paymentlog.LogStore/ListFailureLogsSince"
+	LogStore_StreamPaymentLogsByProject_FullMethodName = "/paymentlog.LogStore/StreamPaymentLogsByProject"
+	LogStore_StreamPaymentLogsByUser_FullMethodName    = "/paymentlog.LogStore/StreamPaymentLogsByUser"
+	LogStore_StreamPaymentLogs_FullMethodName          = "/paymentlog.LogStore/StreamPaymentLogs"
+	LogStore_StreamFailureLogs_FullMethodName          = "/paymentlog.LogStore/StreamFailureLogs"
+	LogStore_StreamFailureLogsSince_FullMethodName     = "/paymentlog.LogStore/StreamFailureLogsSince"
+)
+
+// LogStoreClient is the client API for LogStore service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// LogStore mirrors the paymentlog.LogStore interface. Errors are returned as
+// gRPC statuses with the error text as the status message: the Missing*
+// errors as INVALID_ARGUMENT, LogNotFound as NOT_FOUND and AlreadyExists as
+// ALREADY_EXISTS.
+type LogStoreClient interface {
+	StorePaymentLog(ctx context.Context, in *PaymentLog, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	UpdatePaymentLog(ctx context.Context, in *UpdatePaymentLogRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	DeletePaymentLog(ctx context.Context, in *PaymentLogID, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	GetPaymentLog(ctx context.Context, in *PaymentLogID, opts ...grpc.CallOption) (*PaymentLog, error)
+	ListPaymentLogsByProject(ctx context.Context, in *ListByProjectRequest, opts ...grpc.CallOption) (*PaymentLogs, error)
+	ListPaymentLogsByUser(ctx context.Context, in *ListByUserRequest, opts ...grpc.CallOption) (*PaymentLogs, error)
+	ListPaymentLogs(ctx context.Context, in *Page, opts ...grpc.CallOption) (*PaymentLogs, error)
+	StoreFailureLog(ctx context.Context, in *FailureLog, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	ListFailureLogs(ctx context.Context, in *Page, opts ...grpc.CallOption) (*FailureLogs, error)
+	ListFailureLogsSince(ctx context.Context, in *SinceRequest, opts ...grpc.CallOption) (*FailureLogs, error)
+	// Server-streaming variants of the list and since-queries. The
+	// IteratePaymentLogs and IterateFailureLogs methods of LogStore map onto
+	// StreamPaymentLogs and StreamFailureLogs with an empty page.
+	StreamPaymentLogsByProject(ctx context.Context, in *ListByProjectRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[PaymentLog], error)
+	StreamPaymentLogsByUser(ctx context.Context, in *ListByUserRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[PaymentLog], error)
+	StreamPaymentLogs(ctx context.Context, in *Page, opts ...grpc.CallOption) (grpc.ServerStreamingClient[PaymentLog], error)
+	StreamFailureLogs(ctx context.Context, in *Page, opts ...grpc.CallOption) (grpc.ServerStreamingClient[FailureLog], error)
+	StreamFailureLogsSince(ctx context.Context, in *SinceRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[FailureLog], error)
+}
+
+type logStoreClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewLogStoreClient(cc grpc.ClientConnInterface) LogStoreClient {
+	return &logStoreClient{cc}
+}
+
+func (c *logStoreClient) StorePaymentLog(ctx context.Context, in *PaymentLog, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, LogStore_StorePaymentLog_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *logStoreClient) UpdatePaymentLog(ctx context.Context, in *UpdatePaymentLogRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, LogStore_UpdatePaymentLog_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *logStoreClient) DeletePaymentLog(ctx context.Context, in *PaymentLogID, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, LogStore_DeletePaymentLog_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *logStoreClient) GetPaymentLog(ctx context.Context, in *PaymentLogID, opts ...grpc.CallOption) (*PaymentLog, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PaymentLog)
+	err := c.cc.Invoke(ctx, LogStore_GetPaymentLog_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *logStoreClient) ListPaymentLogsByProject(ctx context.Context, in *ListByProjectRequest, opts ...grpc.CallOption) (*PaymentLogs, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PaymentLogs)
+	err := c.cc.Invoke(ctx, LogStore_ListPaymentLogsByProject_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *logStoreClient) ListPaymentLogsByUser(ctx context.Context, in *ListByUserRequest, opts ...grpc.CallOption) (*PaymentLogs, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PaymentLogs)
+	err := c.cc.Invoke(ctx, LogStore_ListPaymentLogsByUser_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *logStoreClient) ListPaymentLogs(ctx context.Context, in *Page, opts ...grpc.CallOption) (*PaymentLogs, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PaymentLogs)
+	err := c.cc.Invoke(ctx, LogStore_ListPaymentLogs_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *logStoreClient) StoreFailureLog(ctx context.Context, in *FailureLog, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, LogStore_StoreFailureLog_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *logStoreClient) ListFailureLogs(ctx context.Context, in *Page, opts ...grpc.CallOption) (*FailureLogs, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(FailureLogs)
+	err := c.cc.Invoke(ctx, LogStore_ListFailureLogs_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *logStoreClient) ListFailureLogsSince(ctx context.Context, in *SinceRequest, opts ...grpc.CallOption) (*FailureLogs, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(FailureLogs)
+	err := c.cc.Invoke(ctx, LogStore_ListFailureLogsSince_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *logStoreClient) StreamPaymentLogsByProject(ctx context.Context, in *ListByProjectRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[PaymentLog], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &LogStore_ServiceDesc.Streams[0], LogStore_StreamPaymentLogsByProject_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ListByProjectRequest, PaymentLog]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type LogStore_StreamPaymentLogsByProjectClient = grpc.ServerStreamingClient[PaymentLog]
+
+func (c *logStoreClient) StreamPaymentLogsByUser(ctx context.Context, in *ListByUserRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[PaymentLog], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &LogStore_ServiceDesc.Streams[1], LogStore_StreamPaymentLogsByUser_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ListByUserRequest, PaymentLog]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type LogStore_StreamPaymentLogsByUserClient = grpc.ServerStreamingClient[PaymentLog]
+
+func (c *logStoreClient) StreamPaymentLogs(ctx context.Context, in *Page, opts ...grpc.CallOption) (grpc.ServerStreamingClient[PaymentLog], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &LogStore_ServiceDesc.Streams[2], LogStore_StreamPaymentLogs_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[Page, PaymentLog]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type LogStore_StreamPaymentLogsClient = grpc.ServerStreamingClient[PaymentLog]
+
+func (c *logStoreClient) StreamFailureLogs(ctx context.Context, in *Page, opts ...grpc.CallOption) (grpc.ServerStreamingClient[FailureLog], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &LogStore_ServiceDesc.Streams[3], LogStore_StreamFailureLogs_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[Page, FailureLog]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type LogStore_StreamFailureLogsClient = grpc.ServerStreamingClient[FailureLog]
+
+func (c *logStoreClient) StreamFailureLogsSince(ctx context.Context, in *SinceRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[FailureLog], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &LogStore_ServiceDesc.Streams[4], LogStore_StreamFailureLogsSince_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SinceRequest, FailureLog]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type LogStore_StreamFailureLogsSinceClient = grpc.ServerStreamingClient[FailureLog]
+
+// LogStoreServer is the server API for LogStore service.
+// All implementations must embed UnimplementedLogStoreServer
+// for forward compatibility.
+//
+// LogStore mirrors the paymentlog.LogStore interface. Errors are returned as
+// gRPC statuses with the error text as the status message: the Missing*
+// errors as INVALID_ARGUMENT, LogNotFound as NOT_FOUND and AlreadyExists as
+// ALREADY_EXISTS.
+type LogStoreServer interface {
+	StorePaymentLog(context.Context, *PaymentLog) (*emptypb.Empty, error)
+	UpdatePaymentLog(context.Context, *UpdatePaymentLogRequest) (*emptypb.Empty, error)
+	DeletePaymentLog(context.Context, *PaymentLogID) (*emptypb.Empty, error)
+	GetPaymentLog(context.Context, *PaymentLogID) (*PaymentLog, error)
+	ListPaymentLogsByProject(context.Context, *ListByProjectRequest) (*PaymentLogs, error)
+	ListPaymentLogsByUser(context.Context, *ListByUserRequest) (*PaymentLogs, error)
+	ListPaymentLogs(context.Context, *Page) (*PaymentLogs, error)
+	StoreFailureLog(context.Context, *FailureLog) (*emptypb.Empty, error)
+	ListFailureLogs(context.Context, *Page) (*FailureLogs, error)
+	ListFailureLogsSince(context.Context, *SinceRequest) (*FailureLogs, error)
+	// Server-streaming variants of the list and since-queries. The
+	// IteratePaymentLogs and IterateFailureLogs methods of LogStore map onto
+	// StreamPaymentLogs and StreamFailureLogs with an empty page.
+	StreamPaymentLogsByProject(*ListByProjectRequest, grpc.ServerStreamingServer[PaymentLog]) error
+	StreamPaymentLogsByUser(*ListByUserRequest, grpc.ServerStreamingServer[PaymentLog]) error
+	StreamPaymentLogs(*Page, grpc.ServerStreamingServer[PaymentLog]) error
+	StreamFailureLogs(*Page, grpc.ServerStreamingServer[FailureLog]) error
+	StreamFailureLogsSince(*SinceRequest, grpc.ServerStreamingServer[FailureLog]) error
+	mustEmbedUnimplementedLogStoreServer()
+}
+
+// UnimplementedLogStoreServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedLogStoreServer struct{}
+
+func (UnimplementedLogStoreServer) StorePaymentLog(context.Context, *PaymentLog) (*emptypb.Empty, error) {
+	return nil, status.Error(codes.Unimplemented, "method StorePaymentLog not implemented")
+}
+func (UnimplementedLogStoreServer) UpdatePaymentLog(context.Context, *UpdatePaymentLogRequest) (*emptypb.Empty, error) {
+	return nil, status.Error(codes.Unimplemented, "method UpdatePaymentLog not implemented")
+}
+func (UnimplementedLogStoreServer) DeletePaymentLog(context.Context, *PaymentLogID) (*emptypb.Empty, error) {
+	return nil, status.Error(codes.Unimplemented, "method DeletePaymentLog not implemented")
+}
+func (UnimplementedLogStoreServer) GetPaymentLog(context.Context, *PaymentLogID) (*PaymentLog, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetPaymentLog not implemented")
+}
+func (UnimplementedLogStoreServer) ListPaymentLogsByProject(context.Context, *ListByProjectRequest) (*PaymentLogs, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListPaymentLogsByProject not implemented")
+}
+func (UnimplementedLogStoreServer) ListPaymentLogsByUser(context.Context, *ListByUserRequest) (*PaymentLogs, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListPaymentLogsByUser not implemented")
+}
+func (UnimplementedLogStoreServer) ListPaymentLogs(context.Context, *Page) (*PaymentLogs, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListPaymentLogs not implemented")
+}
+func (UnimplementedLogStoreServer) StoreFailureLog(context.Context, *FailureLog) (*emptypb.Empty, error) {
+	return nil, status.Error(codes.Unimplemented, "method StoreFailureLog not implemented")
+}
+func (UnimplementedLogStoreServer) ListFailureLogs(context.Context, *Page) (*FailureLogs, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListFailureLogs not implemented")
+}
+func (UnimplementedLogStoreServer) ListFailureLogsSince(context.Context, *SinceRequest) (*FailureLogs, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListFailureLogsSince not implemented")
+}
+func (UnimplementedLogStoreServer) StreamPaymentLogsByProject(*ListByProjectRequest, grpc.ServerStreamingServer[PaymentLog]) error {
+	return status.Error(codes.Unimplemented, "method StreamPaymentLogsByProject not implemented")
+}
+func (UnimplementedLogStoreServer) StreamPaymentLogsByUser(*ListByUserRequest, grpc.ServerStreamingServer[PaymentLog]) error {
+	return status.Error(codes.Unimplemented, "method StreamPaymentLogsByUser not implemented")
+}
+func (UnimplementedLogStoreServer) StreamPaymentLogs(*Page, grpc.ServerStreamingServer[PaymentLog]) error {
+	return status.Error(codes.Unimplemented, "method StreamPaymentLogs not implemented")
+}
+func (UnimplementedLogStoreServer) StreamFailureLogs(*Page, grpc.ServerStreamingServer[FailureLog]) error {
+	return status.Error(codes.Unimplemented, "method StreamFailureLogs not implemented")
+}
+func (UnimplementedLogStoreServer) StreamFailureLogsSince(*SinceRequest, grpc.ServerStreamingServer[FailureLog]) error {
+	return status.Error(codes.Unimplemented, "method StreamFailureLogsSince not implemented")
+}
+func (UnimplementedLogStoreServer) mustEmbedUnimplementedLogStoreServer() {}
+func (UnimplementedLogStoreServer) testEmbeddedByValue()                  {}
+
+// UnsafeLogStoreServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to LogStoreServer will
+// result in compilation errors.
+type UnsafeLogStoreServer interface {
+	mustEmbedUnimplementedLogStoreServer()
+}
+
+func RegisterLogStoreServer(s grpc.ServiceRegistrar, srv LogStoreServer) {
+	// If the following call panics, it indicates UnimplementedLogStoreServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&LogStore_ServiceDesc, srv)
+}
+
+func _LogStore_StorePaymentLog_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PaymentLog)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LogStoreServer).StorePaymentLog(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LogStore_StorePaymentLog_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LogStoreServer).StorePaymentLog(ctx, req.(*PaymentLog))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LogStore_UpdatePaymentLog_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdatePaymentLogRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LogStoreServer).UpdatePaymentLog(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LogStore_UpdatePaymentLog_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LogStoreServer).UpdatePaymentLog(ctx, req.(*UpdatePaymentLogRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LogStore_DeletePaymentLog_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PaymentLogID)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LogStoreServer).DeletePaymentLog(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LogStore_DeletePaymentLog_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LogStoreServer).DeletePaymentLog(ctx, req.(*PaymentLogID))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LogStore_GetPaymentLog_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PaymentLogID)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LogStoreServer).GetPaymentLog(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LogStore_GetPaymentLog_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LogStoreServer).GetPaymentLog(ctx, req.(*PaymentLogID))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LogStore_ListPaymentLogsByProject_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListByProjectRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LogStoreServer).ListPaymentLogsByProject(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LogStore_ListPaymentLogsByProject_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LogStoreServer).ListPaymentLogsByProject(ctx, req.(*ListByProjectRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LogStore_ListPaymentLogsByUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListByUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LogStoreServer).ListPaymentLogsByUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LogStore_ListPaymentLogsByUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LogStoreServer).ListPaymentLogsByUser(ctx, req.(*ListByUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LogStore_ListPaymentLogs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Page)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LogStoreServer).ListPaymentLogs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LogStore_ListPaymentLogs_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LogStoreServer).ListPaymentLogs(ctx, req.(*Page))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LogStore_StoreFailureLog_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FailureLog)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LogStoreServer).StoreFailureLog(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LogStore_StoreFailureLog_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LogStoreServer).StoreFailureLog(ctx, req.(*FailureLog))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LogStore_ListFailureLogs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Page)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LogStoreServer).ListFailureLogs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LogStore_ListFailureLogs_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LogStoreServer).ListFailureLogs(ctx, req.(*Page))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LogStore_ListFailureLogsSince_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SinceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LogStoreServer).ListFailureLogsSince(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LogStore_ListFailureLogsSince_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LogStoreServer).ListFailureLogsSince(ctx, req.(*SinceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LogStore_StreamPaymentLogsByProject_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListByProjectRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(LogStoreServer).StreamPaymentLogsByProject(m, &grpc.GenericServerStream[ListByProjectRequest, PaymentLog]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type LogStore_StreamPaymentLogsByProjectServer = grpc.ServerStreamingServer[PaymentLog]
+
+func _LogStore_StreamPaymentLogsByUser_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListByUserRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(LogStoreServer).StreamPaymentLogsByUser(m, &grpc.GenericServerStream[ListByUserRequest, PaymentLog]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type LogStore_StreamPaymentLogsByUserServer = grpc.ServerStreamingServer[PaymentLog]
+
+func _LogStore_StreamPaymentLogs_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(Page)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(LogStoreServer).StreamPaymentLogs(m, &grpc.GenericServerStream[Page, PaymentLog]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type LogStore_StreamPaymentLogsServer = grpc.ServerStreamingServer[PaymentLog]
+
+func _LogStore_StreamFailureLogs_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(Page)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(LogStoreServer).StreamFailureLogs(m, &grpc.GenericServerStream[Page, FailureLog]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type LogStore_StreamFailureLogsServer = grpc.ServerStreamingServer[FailureLog]
+
+func _LogStore_StreamFailureLogsSince_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SinceRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(LogStoreServer).StreamFailureLogsSince(m, &grpc.GenericServerStream[SinceRequest, FailureLog]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type LogStore_StreamFailureLogsSinceServer = grpc.ServerStreamingServer[FailureLog]
+
+// LogStore_ServiceDesc is the grpc.ServiceDesc for LogStore service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var LogStore_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "paymentlog.LogStore",
+	HandlerType: (*LogStoreServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "StorePaymentLog",
+			Handler:    _LogStore_StorePaymentLog_Handler,
+		},
+		{
+			MethodName: "UpdatePaymentLog",
+			Handler:    _LogStore_UpdatePaymentLog_Handler,
+		},
+		{
+			MethodName: "DeletePaymentLog",
+			Handler:    _LogStore_DeletePaymentLog_Handler,
+		},
+		{
+			MethodName: "GetPaymentLog",
+			Handler:    _LogStore_GetPaymentLog_Handler,
+		},
+		{
+			MethodName: "ListPaymentLogsByProject",
+			Handler:    _LogStore_ListPaymentLogsByProject_Handler,
+		},
+		{
+			MethodName: "ListPaymentLogsByUser",
+			Handler:    _LogStore_ListPaymentLogsByUser_Handler,
+		},
+		{
+			MethodName: "ListPaymentLogs",
+			Handler:    _LogStore_ListPaymentLogs_Handler,
+		},
+		{
+			MethodName: "StoreFailureLog",
+			Handler:    _LogStore_StoreFailureLog_Handler,
+		},
+		{
+			MethodName: "ListFailureLogs",
+			Handler:    _LogStore_ListFailureLogs_Handler,
+		},
+		{
+			MethodName: "ListFailureLogsSince",
+			Handler:    _LogStore_ListFailureLogsSince_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamPaymentLogsByProject",
+			Handler:       _LogStore_StreamPaymentLogsByProject_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "StreamPaymentLogsByUser",
+			Handler:       _LogStore_StreamPaymentLogsByUser_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "StreamPaymentLogs",
+			Handler:       _LogStore_StreamPaymentLogs_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "StreamFailureLogs",
+			Handler:       _LogStore_StreamFailureLogs_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "StreamFailureLogsSince",
+			Handler:       _LogStore_StreamFailureLogsSince_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "paymentlog.proto",
+}
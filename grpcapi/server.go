@@ -0,0 +1,212 @@
+// Package grpcapi serves a paymentlog.LogStore over gRPC and implements one
+// against that service. The service is defined in paymentlog.proto.
+package grpcapi
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative paymentlog.proto
+
+import (
+	"context"
+
+	"code.whipround.net/paymentlog"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+var invalidArguments = []error{
+	paymentlog.MissingID,
+	paymentlog.MissingAmount,
+	paymentlog.MissingSource,
+	paymentlog.MissingSourceID,
+	paymentlog.MissingCreated,
+	paymentlog.MissingStatus,
+	paymentlog.MissingCurrency,
+	paymentlog.MissingProjectID,
+	paymentlog.MissingUserID,
+	paymentlog.MissingAccountType,
+	paymentlog.MissingAccountID,
+	paymentlog.FeesExceedAmount,
+}
+
+func statusCode(err error) codes.Code {
+	switch err {
+	case paymentlog.LogNotFound, paymentlog.FailureLogNotFound:
+		return codes.NotFound
+	case paymentlog.AlreadyExists:
+		return codes.AlreadyExists
+	}
+	for _, invalid := range invalidArguments {
+		if err == invalid {
+			return codes.InvalidArgument
+		}
+	}
+	return codes.Internal
+}
+
+// statusError wraps store errors in a status, keeping the error text as the
+// status message so that Client can map it back to the paymentlog error.
+func statusError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return status.Error(statusCode(err), err.Error())
+}
+
+// Server implements LogStoreServer against a paymentlog.LogStore. Register
+// it with RegisterLogStoreServer.
+type Server struct {
+	UnimplementedLogStoreServer
+	store paymentlog.LogStore
+}
+
+func NewServer(store paymentlog.LogStore) *Server {
+	return &Server{store: store}
+}
+
+func (s *Server) StorePaymentLog(ctx context.Context, req *PaymentLog) (*emptypb.Empty, error) {
+	log := fromPaymentLog(req)
+	if err := log.Validate(); err != nil {
+		return nil, statusError(err)
+	}
+	return &emptypb.Empty{}, statusError(s.store.StorePaymentLog(log))
+}
+
+func (s *Server) UpdatePaymentLog(ctx context.Context, req *UpdatePaymentLogRequest) (*emptypb.Empty, error) {
+	err := s.store.UpdatePaymentLog(req.GetId(), fromPaymentLogChange(req.GetChange()))
+	return &emptypb.Empty{}, statusError(err)
+}
+
+func (s *Server) DeletePaymentLog(ctx context.Context, req *PaymentLogID) (*emptypb.Empty, error) {
+	return &emptypb.Empty{}, statusError(s.store.DeletePaymentLog(req.GetId()))
+}
+
+func (s *Server) GetPaymentLog(ctx context.Context, req *PaymentLogID) (*PaymentLog, error) {
+	log, err := s.store.GetPaymentLog(req.GetId())
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return toPaymentLog(log), nil
+}
+
+func (s *Server) ListPaymentLogsByProject(ctx context.Context, req *ListByProjectRequest) (*PaymentLogs, error) {
+	page := req.GetPage()
+	logs, err := s.store.ListPaymentLogsByProject(req.GetProjectId(), int(page.GetNum()), int(page.GetOffset()))
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return toPaymentLogs(logs), nil
+}
+
+func (s *Server) ListPaymentLogsByUser(ctx context.Context, req *ListByUserRequest) (*PaymentLogs, error) {
+	page := req.GetPage()
+	logs, err := s.store.ListPaymentLogsByUser(req.GetUserId(), int(page.GetNum()), int(page.GetOffset()))
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return toPaymentLogs(logs), nil
+}
+
+func (s *Server) ListPaymentLogs(ctx context.Context, page *Page) (*PaymentLogs, error) {
+	logs, err := s.store.ListPaymentLogs(int(page.GetNum()), int(page.GetOffset()))
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return toPaymentLogs(logs), nil
+}
+
+func (s *Server) StoreFailureLog(ctx context.Context, failure *FailureLog) (*emptypb.Empty, error) {
+	return &emptypb.Empty{}, statusError(s.store.StoreFailureLog(fromFailureLog(failure)))
+}
+
+func (s *Server) ListFailureLogs(ctx context.Context, page *Page) (*FailureLogs, error) {
+	failures, err := s.store.ListFailureLogs(int(page.GetNum()), int(page.GetOffset()))
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return toFailureLogs(failures), nil
+}
+
+func (s *Server) ListFailureLogsSince(ctx context.Context, req *SinceRequest) (*FailureLogs, error) {
+	failures, err := s.store.ListFailureLogsSince(fromTimestamp(req.GetTimestamp()))
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return toFailureLogs(failures), nil
+}
+
+func sendPaymentLogs(logs []paymentlog.PaymentLog, err error, stream LogStore_StreamPaymentLogsServer) error {
+	if err != nil {
+		return statusError(err)
+	}
+	for _, log := range logs {
+		if err := stream.Send(toPaymentLog(log)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sendFailureLogs(failures []paymentlog.FailureLog, err error, stream LogStore_StreamFailureLogsServer) error {
+	if err != nil {
+		return statusError(err)
+	}
+	for _, failure := range failures {
+		if err := stream.Send(toFailureLog(failure)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Server) StreamPaymentLogsByProject(req *ListByProjectRequest, stream LogStore_StreamPaymentLogsByProjectServer) error {
+	page := req.GetPage()
+	logs, err := s.store.ListPaymentLogsByProject(req.GetProjectId(), int(page.GetNum()), int(page.GetOffset()))
+	return sendPaymentLogs(logs, err, stream)
+}
+
+func (s *Server) StreamPaymentLogsByUser(req *ListByUserRequest, stream LogStore_StreamPaymentLogsByUserServer) error {
+	page := req.GetPage()
+	logs, err := s.store.ListPaymentLogsByUser(req.GetUserId(), int(page.GetNum()), int(page.GetOffset()))
+	return sendPaymentLogs(logs, err, stream)
+}
+
+// StreamPaymentLogs iterates over the store when asked for every payment
+// log, rather than listing them all first.
+func (s *Server) StreamPaymentLogs(page *Page, stream LogStore_StreamPaymentLogsServer) error {
+	if page.GetNum() > 0 || page.GetOffset() > 0 {
+		logs, err := s.store.ListPaymentLogs(int(page.GetNum()), int(page.GetOffset()))
+		return sendPaymentLogs(logs, err, stream)
+	}
+	var sendErr error
+	err := s.store.IteratePaymentLogs(func(log paymentlog.PaymentLog) error {
+		sendErr = stream.Send(toPaymentLog(log))
+		return sendErr
+	})
+	if sendErr != nil {
+		return sendErr
+	}
+	return statusError(err)
+}
+
+// StreamFailureLogs iterates over the store when asked for every failure
+// log, rather than listing them all first.
+func (s *Server) StreamFailureLogs(page *Page, stream LogStore_StreamFailureLogsServer) error {
+	if page.GetNum() > 0 || page.GetOffset() > 0 {
+		failures, err := s.store.ListFailureLogs(int(page.GetNum()), int(page.GetOffset()))
+		return sendFailureLogs(failures, err, stream)
+	}
+	var sendErr error
+	err := s.store.IterateFailureLogs(func(failure paymentlog.FailureLog) error {
+		sendErr = stream.Send(toFailureLog(failure))
+		return sendErr
+	})
+	if sendErr != nil {
+		return sendErr
+	}
+	return statusError(err)
+}
+
+func (s *Server) StreamFailureLogsSince(req *SinceRequest, stream LogStore_StreamFailureLogsSinceServer) error {
+	failures, err := s.store.ListFailureLogsSince(fromTimestamp(req.GetTimestamp()))
+	return sendFailureLogs(failures, err, stream)
+}
@@ -0,0 +1,186 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"code.whipround.net/paymentlog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func testPaymentLog(id string, created time.Time) paymentlog.PaymentLog {
+	return paymentlog.PaymentLog{
+		ID:          id,
+		Amount:      1,
+		Source:      paymentlog.SourceBalanced,
+		SourceID:    "balanced-id",
+		Created:     created,
+		Status:      paymentlog.StatusPending,
+		Currency:    paymentlog.CurrencyUSD,
+		ProjectID:   "project-id",
+		UserID:      "user-id",
+		AccountID:   "account-id",
+		AccountType: "google",
+	}
+}
+
+// serve runs a Server for store on an in-process listener and returns a
+// connection to it, along with a function that closes both.
+func serve(t *testing.T, store paymentlog.LogStore) (*grpc.ClientConn, func()) {
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	RegisterLogStoreServer(server, NewServer(store))
+	go server.Serve(listener)
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Error connecting to server: %s", err)
+	}
+	return conn, func() {
+		conn.Close()
+		server.Stop()
+	}
+}
+
+type brokenStore struct {
+	*paymentlog.MemoryStore
+}
+
+var errBroken = errors.New("Broken store.")
+
+func (brokenStore) GetPaymentLog(id string) (paymentlog.PaymentLog, error) {
+	return paymentlog.PaymentLog{}, errBroken
+}
+
+func (brokenStore) IterateFailureLogs(fn func(failure paymentlog.FailureLog) error) error {
+	return errBroken
+}
+
+func TestServerStatusCodes(t *testing.T) {
+	conn, stop := serve(t, brokenStore{paymentlog.NewMemoryStore()})
+	defer stop()
+	client := NewLogStoreClient(conn)
+	ctx := context.Background()
+
+	p := toPaymentLog(testPaymentLog("id", time.Now()))
+	if _, err := client.StorePaymentLog(ctx, p); err != nil {
+		t.Errorf("Error storing payment log: %s", err)
+	}
+	_, err := client.StorePaymentLog(ctx, p)
+	if s := status.Convert(err); s.Code() != codes.AlreadyExists || s.Message() != paymentlog.AlreadyExists.Error() {
+		t.Errorf("Expected %s with %q, got %v.", codes.AlreadyExists, paymentlog.AlreadyExists, err)
+	}
+	_, err = client.StorePaymentLog(ctx, &PaymentLog{Id: "invalid"})
+	if s := status.Convert(err); s.Code() != codes.InvalidArgument || s.Message() != paymentlog.MissingAmount.Error() {
+		t.Errorf("Expected %s with %q, got %v.", codes.InvalidArgument, paymentlog.MissingAmount, err)
+	}
+	_, err = client.DeletePaymentLog(ctx, &PaymentLogID{Id: "not a payment log"})
+	if s := status.Convert(err); s.Code() != codes.NotFound || s.Message() != paymentlog.LogNotFound.Error() {
+		t.Errorf("Expected %s with %q, got %v.", codes.NotFound, paymentlog.LogNotFound, err)
+	}
+	_, err = client.GetPaymentLog(ctx, &PaymentLogID{Id: "id"})
+	if s := status.Convert(err); s.Code() != codes.Internal || s.Message() != errBroken.Error() {
+		t.Errorf("Expected %s with %q, got %v.", codes.Internal, errBroken, err)
+	}
+	stream, err := client.StreamFailureLogs(ctx, &Page{})
+	if err != nil {
+		t.Fatalf("Error opening stream: %s", err)
+	}
+	if _, err := stream.Recv(); status.Code(err) != codes.Internal {
+		t.Errorf("Expected %s from the stream, got %v.", codes.Internal, err)
+	}
+}
+
+func TestStreamingLists(t *testing.T) {
+	store := paymentlog.NewMemoryStore()
+	conn, stop := serve(t, store)
+	defer stop()
+	client := NewLogStoreClient(conn)
+	ctx := context.Background()
+	now := time.Now()
+	for pos, id := range []string{"id1", "id2", "id3"} {
+		created := now.Add(time.Duration(pos) * time.Hour)
+		store.StorePaymentLog(testPaymentLog(id, created))
+		store.StoreFailureLog(paymentlog.FailureLog{ID: id, PaymentLogID: id, Timestamp: created})
+	}
+	other := testPaymentLog("id4", now.Add(-time.Hour))
+	other.ProjectID, other.UserID = "other-project", "other-user"
+	store.StorePaymentLog(other)
+
+	expectations := map[string]struct {
+		stream func() (grpc.ServerStreamingClient[PaymentLog], error)
+		ids    []string
+	}{
+		"by project": {func() (grpc.ServerStreamingClient[PaymentLog], error) {
+			return client.StreamPaymentLogsByProject(ctx, &ListByProjectRequest{ProjectId: "project-id", Page: &Page{Num: 2}})
+		}, []string{"id3", "id2"}},
+		"by user": {func() (grpc.ServerStreamingClient[PaymentLog], error) {
+			return client.StreamPaymentLogsByUser(ctx, &ListByUserRequest{UserId: "other-user"})
+		}, []string{"id4"}},
+		"paged": {func() (grpc.ServerStreamingClient[PaymentLog], error) {
+			return client.StreamPaymentLogs(ctx, &Page{Num: 2, Offset: 1})
+		}, []string{"id2", "id1"}},
+		"everything": {func() (grpc.ServerStreamingClient[PaymentLog], error) {
+			return client.StreamPaymentLogs(ctx, &Page{})
+		}, []string{"id3", "id2", "id1", "id4"}},
+	}
+	for name, expectation := range expectations {
+		stream, err := expectation.stream()
+		if err != nil {
+			t.Errorf("%s: error opening stream: %s", name, err)
+			continue
+		}
+		var ids []string
+		for {
+			log, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Errorf("%s: error receiving payment log: %s", name, err)
+				break
+			}
+			ids = append(ids, log.GetId())
+		}
+		if len(ids) != len(expectation.ids) {
+			t.Errorf("%s: expected %v, got %v.", name, expectation.ids, ids)
+			continue
+		}
+		for i := range ids {
+			if ids[i] != expectation.ids[i] {
+				t.Errorf("%s: expected %v, got %v.", name, expectation.ids, ids)
+				break
+			}
+		}
+	}
+
+	stream, err := client.StreamFailureLogsSince(ctx, &SinceRequest{Timestamp: timestamp(now.Add(time.Minute))})
+	if err != nil {
+		t.Fatalf("Error opening stream: %s", err)
+	}
+	seen := 0
+	for {
+		_, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Error receiving failure log: %s", err)
+		}
+		seen++
+	}
+	if seen != 2 {
+		t.Errorf("Expected 2 failure logs since %s, got %d.", now.Add(time.Minute), seen)
+	}
+}
@@ -0,0 +1,290 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"code.whipround.net/paymentlog"
+)
+
+const (
+	DefaultTimeout    = 10 * time.Second
+	DefaultRetries    = 2
+	DefaultRetryDelay = 100 * time.Millisecond
+)
+
+type UnexpectedResponse struct {
+	StatusCode int
+	Message    string
+}
+
+func (u UnexpectedResponse) Error() string {
+	return fmt.Sprintf("Unexpected response from payment log server (%d): %s", u.StatusCode, u.Message)
+}
+
+// Client implements paymentlog.LogStore against a Server. Idempotent calls
+// (GET and DELETE) are retried on transport errors and 502, 503 and 504
+// responses. A retried DELETE that finds nothing to delete succeeds, since
+// an earlier attempt may have deleted it.
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client
+	Retries    int
+	RetryDelay time.Duration
+}
+
+func NewClient(baseURL string, timeout time.Duration) *Client {
+	return &Client{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		HTTPClient: &http.Client{Timeout: timeout},
+		Retries:    DefaultRetries,
+		RetryDelay: DefaultRetryDelay,
+	}
+}
+
+func responseError(code int, msg string) error {
+	switch code {
+	case http.StatusNotFound:
+		if msg == paymentlog.LogNotFound.Error() {
+			return paymentlog.LogNotFound
+		}
+		if msg == paymentlog.FailureLogNotFound.Error() {
+			return paymentlog.FailureLogNotFound
+		}
+	case http.StatusConflict:
+		if msg == paymentlog.AlreadyExists.Error() {
+			return paymentlog.AlreadyExists
+		}
+		if msg == paymentlog.InvalidFailureTransition.Error() {
+			return paymentlog.InvalidFailureTransition
+		}
+	case http.StatusNotImplemented:
+		if msg == paymentlog.FailureStatesUnsupported.Error() {
+			return paymentlog.FailureStatesUnsupported
+		}
+	case http.StatusBadRequest:
+		for _, missing := range missingErrors {
+			if msg == missing.Error() {
+				return missing
+			}
+		}
+	}
+	return UnexpectedResponse{StatusCode: code, Message: msg}
+}
+
+func retryable(code int) bool {
+	return code == http.StatusBadGateway || code == http.StatusServiceUnavailable || code == http.StatusGatewayTimeout
+}
+
+func (c *Client) do(method, path string, body interface{}, expected int, result interface{}) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		payload, err = json.Marshal(body)
+		if err != nil {
+			return err
+		}
+	}
+	attempts := 1
+	if method == "GET" || method == "DELETE" {
+		attempts += c.Retries
+	}
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(c.RetryDelay)
+		}
+		var retry bool
+		retry, err = c.attempt(method, path, payload, expected, result)
+		if method == "DELETE" && attempt > 0 && err == paymentlog.LogNotFound {
+			return nil
+		}
+		if !retry {
+			return err
+		}
+	}
+	return err
+}
+
+func (c *Client) attempt(method, path string, payload []byte, expected int, result interface{}) (retry bool, err error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequest(method, c.BaseURL+path, body)
+	if err != nil {
+		return false, err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != expected {
+		var errResp errorResponse
+		raw, _ := ioutil.ReadAll(resp.Body)
+		if json.Unmarshal(raw, &errResp) != nil || errResp.Error == "" {
+			errResp.Error = strings.TrimSpace(string(raw))
+		}
+		return retryable(resp.StatusCode), responseError(resp.StatusCode, errResp.Error)
+	}
+	if result == nil {
+		return false, nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return false, errors.New("Error decoding payment log server response: " + err.Error())
+	}
+	return false, nil
+}
+
+// pageQuery always sends num, since the server pages by default; a num of
+// 0 or less lists everything, as it does for a LogStore.
+func pageQuery(values url.Values, num, offset int) string {
+	if num < 0 {
+		num = 0
+	}
+	values.Set("num", strconv.Itoa(num))
+	if offset > 0 {
+		values.Set("offset", strconv.Itoa(offset))
+	}
+	return values.Encode()
+}
+
+func (c *Client) StorePaymentLog(log paymentlog.PaymentLog) error {
+	return c.do("POST", "/payments", log, http.StatusCreated, nil)
+}
+
+func (c *Client) UpdatePaymentLog(id string, change paymentlog.PaymentLogChange) error {
+	return c.do("PATCH", "/payments/"+url.PathEscape(id), change, http.StatusOK, nil)
+}
+
+func (c *Client) DeletePaymentLog(id string) error {
+	return c.do("DELETE", "/payments/"+url.PathEscape(id), nil, http.StatusNoContent, nil)
+}
+
+func (c *Client) GetPaymentLog(id string) (paymentlog.PaymentLog, error) {
+	var log paymentlog.PaymentLog
+	err := c.do("GET", "/payments/"+url.PathEscape(id), nil, http.StatusOK, &log)
+	return log, err
+}
+
+func (c *Client) ListPaymentLogsByProject(campaignID string, num, offset int) ([]paymentlog.PaymentLog, error) {
+	var logs []paymentlog.PaymentLog
+	err := c.do("GET", "/payments?"+pageQuery(url.Values{"project_id": {campaignID}}, num, offset), nil, http.StatusOK, &logs)
+	return logs, err
+}
+
+func (c *Client) ListPaymentLogsByUser(userID string, num, offset int) ([]paymentlog.PaymentLog, error) {
+	var logs []paymentlog.PaymentLog
+	err := c.do("GET", "/payments?"+pageQuery(url.Values{"user_id": {userID}}, num, offset), nil, http.StatusOK, &logs)
+	return logs, err
+}
+
+func (c *Client) ListPaymentLogs(num, offset int) ([]paymentlog.PaymentLog, error) {
+	var logs []paymentlog.PaymentLog
+	err := c.do("GET", "/payments?"+pageQuery(url.Values{}, num, offset), nil, http.StatusOK, &logs)
+	return logs, err
+}
+
+func (c *Client) IteratePaymentLogs(fn func(log paymentlog.PaymentLog) error) error {
+	// logs stored while iterating push later pages back, so skip any log
+	// the previous page already returned
+	seen := map[string]bool{}
+	for offset := 0; ; offset += DefaultPageSize {
+		logs, err := c.ListPaymentLogs(DefaultPageSize, offset)
+		if err != nil {
+			return err
+		}
+		page := make(map[string]bool, len(logs))
+		for _, log := range logs {
+			page[log.ID] = true
+			if seen[log.ID] {
+				continue
+			}
+			err := fn(log)
+			if err == paymentlog.StopIteration {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if len(logs) < DefaultPageSize {
+			return nil
+		}
+		seen = page
+	}
+}
+
+func (c *Client) StoreFailureLog(failure paymentlog.FailureLog) error {
+	return c.do("POST", "/failures", failure, http.StatusCreated, nil)
+}
+
+func (c *Client) ListFailureLogs(num, offset int) ([]paymentlog.FailureLog, error) {
+	var logs []paymentlog.FailureLog
+	err := c.do("GET", "/failures?"+pageQuery(url.Values{}, num, offset), nil, http.StatusOK, &logs)
+	return logs, err
+}
+
+func (c *Client) ListFailureLogsSince(timestamp time.Time) ([]paymentlog.FailureLog, error) {
+	var logs []paymentlog.FailureLog
+	query := url.Values{"since": {timestamp.Format(time.RFC3339Nano)}}
+	err := c.do("GET", "/failures?"+query.Encode(), nil, http.StatusOK, &logs)
+	return logs, err
+}
+
+func (c *Client) IterateFailureLogs(fn func(failure paymentlog.FailureLog) error) error {
+	// logs stored while iterating push later pages back, so skip any log
+	// the previous page already returned
+	seen := map[string]bool{}
+	for offset := 0; ; offset += DefaultPageSize {
+		logs, err := c.ListFailureLogs(DefaultPageSize, offset)
+		if err != nil {
+			return err
+		}
+		page := make(map[string]bool, len(logs))
+		for _, log := range logs {
+			page[log.ID] = true
+			if seen[log.ID] {
+				continue
+			}
+			err := fn(log)
+			if err == paymentlog.StopIteration {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if len(logs) < DefaultPageSize {
+			return nil
+		}
+		seen = page
+	}
+}
+
+func (c *Client) UpdateFailureLogState(id string, change paymentlog.FailureStateChange) error {
+	return c.do("PUT", "/failures/"+url.PathEscape(id)+"/state", change, http.StatusNoContent, nil)
+}
+
+func (c *Client) ListOpenFailureLogs(num, offset int) ([]paymentlog.FailureLog, error) {
+	var logs []paymentlog.FailureLog
+	err := c.do("GET", "/failures/open?"+pageQuery(url.Values{}, num, offset), nil, http.StatusOK, &logs)
+	return logs, err
+}
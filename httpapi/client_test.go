@@ -0,0 +1,249 @@
+package httpapi
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"code.whipround.net/paymentlog"
+)
+
+func TestClientIsALogStore(t *testing.T) {
+	// this should refuse to compile if Client doesn't implement LogStore
+	var stores []paymentlog.LogStore
+	stores = append(stores, NewClient("http://localhost", DefaultTimeout))
+	var states []paymentlog.FailureStateStore
+	states = append(states, NewClient("http://localhost", DefaultTimeout))
+}
+
+func TestClientAgainstMemoryStore(t *testing.T) {
+	memory := paymentlog.NewMemoryStore()
+	server := httptest.NewServer(NewServer(memory))
+	defer server.Close()
+	client := NewClient(server.URL, DefaultTimeout)
+
+	p := testPaymentLog("test payment/log", time.Now())
+	if err := client.StorePaymentLog(p); err != nil {
+		t.Errorf("Error storing payment log: %s", err)
+	}
+	if err := client.StorePaymentLog(p); err != paymentlog.AlreadyExists {
+		t.Errorf("Expected %s, got %v.", paymentlog.AlreadyExists, err)
+	}
+	invalid := testPaymentLog("invalid", time.Now())
+	invalid.UserID = ""
+	if err := client.StorePaymentLog(invalid); err != paymentlog.MissingUserID {
+		t.Errorf("Expected %s, got %v.", paymentlog.MissingUserID, err)
+	}
+	result, err := client.GetPaymentLog(p.ID)
+	if err != nil {
+		t.Errorf("Error retrieving payment log: %s", err)
+	}
+	if result.ID != p.ID || !result.Created.Equal(p.Created) || result.AccountType != p.AccountType {
+		t.Errorf("Expected %+v, got %+v.", p, result)
+	}
+	if _, err := client.GetPaymentLog("not a payment log"); err != paymentlog.LogNotFound {
+		t.Errorf("Expected %s, got %v.", paymentlog.LogNotFound, err)
+	}
+	description := "new description"
+	if err := client.UpdatePaymentLog(p.ID, paymentlog.PaymentLogChange{Description: &description}); err != nil {
+		t.Errorf("Error updating payment log: %s", err)
+	}
+	if updated, _ := memory.GetPaymentLog(p.ID); updated.Description != description {
+		t.Errorf("Expected description to be %q, got %q.", description, updated.Description)
+	}
+	for pos, id := range []string{"id1", "id2", "id3"} {
+		other := testPaymentLog(id, p.Created.Add(time.Duration(pos+1)*time.Hour))
+		if err := client.StorePaymentLog(other); err != nil {
+			t.Errorf("Error storing payment log: %s", err)
+		}
+	}
+	logs, err := client.ListPaymentLogsByProject("project-id", 2, 1)
+	if err != nil {
+		t.Errorf("Error listing payment logs: %s", err)
+	}
+	if len(logs) != 2 || logs[0].ID != "id2" || logs[1].ID != "id1" {
+		t.Errorf("Expected [id2 id1], got %+v.", logs)
+	}
+	seen := 0
+	err = client.IteratePaymentLogs(func(log paymentlog.PaymentLog) error {
+		seen++
+		return nil
+	})
+	if err != nil {
+		t.Errorf("Error iterating payment logs: %s", err)
+	}
+	if seen != 4 {
+		t.Errorf("Expected to iterate over 4 payment logs, saw %d.", seen)
+	}
+	if err := client.DeletePaymentLog(p.ID); err != nil {
+		t.Errorf("Error deleting payment log: %s", err)
+	}
+	if err := client.DeletePaymentLog(p.ID); err != paymentlog.LogNotFound {
+		t.Errorf("Expected %s, got %v.", paymentlog.LogNotFound, err)
+	}
+
+	f := paymentlog.FailureLog{
+		ID:                "id",
+		PaymentLogID:      "id1",
+		FailureReason:     "you screwed up",
+		FailureReasonCode: "500",
+		Timestamp:         time.Now(),
+	}
+	if err := client.StoreFailureLog(f); err != nil {
+		t.Errorf("Error storing failure log: %s", err)
+	}
+	failures, err := client.ListFailureLogsSince(f.Timestamp.Add(-time.Second))
+	if err != nil {
+		t.Errorf("Error listing failure logs: %s", err)
+	}
+	if len(failures) != 1 || failures[0].ID != f.ID {
+		t.Errorf("Expected [%s], got %+v.", f.ID, failures)
+	}
+	change := paymentlog.FailureStateChange{State: paymentlog.FailureResolved, Resolver: "support", Note: "retried"}
+	if err := client.UpdateFailureLogState(f.ID, change); err != nil {
+		t.Errorf("Error resolving failure log: %s", err)
+	}
+	if err := client.UpdateFailureLogState(f.ID, change); err != paymentlog.InvalidFailureTransition {
+		t.Errorf("Expected %s, got %v.", paymentlog.InvalidFailureTransition, err)
+	}
+	if err := client.UpdateFailureLogState("not a failure log", change); err != paymentlog.FailureLogNotFound {
+		t.Errorf("Expected %s, got %v.", paymentlog.FailureLogNotFound, err)
+	}
+	if err := client.UpdateFailureLogState(f.ID, paymentlog.FailureStateChange{State: paymentlog.FailureIgnored}); err != paymentlog.MissingResolver {
+		t.Errorf("Expected %s, got %v.", paymentlog.MissingResolver, err)
+	}
+	open, err := client.ListOpenFailureLogs(10, 0)
+	if err != nil || len(open) != 0 {
+		t.Errorf("Expected no open failure logs, got %+v, %v.", open, err)
+	}
+}
+
+func TestClientListsEverythingLikeMemoryStore(t *testing.T) {
+	memory := paymentlog.NewMemoryStore()
+	server := httptest.NewServer(NewServer(memory))
+	defer server.Close()
+	client := NewClient(server.URL, DefaultTimeout)
+	now := time.Now()
+	for i := 0; i < 45; i++ {
+		id := fmt.Sprintf("id%02d", i)
+		memory.StorePaymentLog(testPaymentLog(id, now))
+		memory.StoreFailureLog(paymentlog.FailureLog{ID: id, PaymentLogID: id, Timestamp: now})
+	}
+	logs, err := client.ListPaymentLogs(0, 0)
+	if err != nil || len(logs) != 45 {
+		t.Errorf("Expected all 45 payment logs, got %d, %v.", len(logs), err)
+	}
+	failures, err := client.ListFailureLogs(0, 0)
+	if err != nil || len(failures) != 45 {
+		t.Errorf("Expected all 45 failure logs, got %d, %v.", len(failures), err)
+	}
+	seen := map[string]bool{}
+	err = client.IteratePaymentLogs(func(log paymentlog.PaymentLog) error {
+		seen[log.ID] = true
+		return nil
+	})
+	if err != nil || len(seen) != 45 {
+		t.Errorf("Expected to iterate over 45 distinct payment logs, saw %d, %v.", len(seen), err)
+	}
+	seen = map[string]bool{}
+	err = client.IterateFailureLogs(func(failure paymentlog.FailureLog) error {
+		seen[failure.ID] = true
+		return nil
+	})
+	if err != nil || len(seen) != 45 {
+		t.Errorf("Expected to iterate over 45 distinct failure logs, saw %d, %v.", len(seen), err)
+	}
+}
+
+func TestClientRetriesIdempotentCalls(t *testing.T) {
+	var lock sync.Mutex
+	requests := 0
+	api := NewServer(paymentlog.NewMemoryStore())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		requests++
+		flaky := requests%2 == 1
+		lock.Unlock()
+		if flaky {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		api.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	client := NewClient(server.URL, DefaultTimeout)
+	client.RetryDelay = 0
+
+	_, err := client.ListPaymentLogs(10, 0)
+	if err != nil {
+		t.Errorf("Expected the retried call to succeed, got %s.", err)
+	}
+	if requests != 2 {
+		t.Errorf("Expected 2 requests, got %d.", requests)
+	}
+	err = client.StorePaymentLog(testPaymentLog("id", time.Now()))
+	if resp, ok := err.(UnexpectedResponse); !ok || resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected an unretried %d response, got %v.", http.StatusServiceUnavailable, err)
+	}
+	if requests != 3 {
+		t.Errorf("Expected 3 requests, got %d.", requests)
+	}
+}
+
+func TestClientRetriesDeletesThatSucceeded(t *testing.T) {
+	store := paymentlog.NewMemoryStore()
+	if err := store.StorePaymentLog(testPaymentLog("id", time.Now())); err != nil {
+		t.Fatalf("Error storing payment log: %s", err)
+	}
+	var lock sync.Mutex
+	requests := 0
+	api := NewServer(store)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		requests++
+		dropped := requests == 1
+		lock.Unlock()
+		if !dropped {
+			api.ServeHTTP(w, r)
+			return
+		}
+		// delete, then drop the connection before responding
+		api.ServeHTTP(httptest.NewRecorder(), r)
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Errorf("Error hijacking connection: %s", err)
+			return
+		}
+		conn.Close()
+	}))
+	defer server.Close()
+	client := NewClient(server.URL, DefaultTimeout)
+	client.RetryDelay = 0
+
+	if err := client.DeletePaymentLog("id"); err != nil {
+		t.Errorf("Expected the retried delete to succeed, got %v.", err)
+	}
+	if requests != 2 {
+		t.Errorf("Expected 2 requests, got %d.", requests)
+	}
+	if err := client.DeletePaymentLog("id"); err != paymentlog.LogNotFound {
+		t.Errorf("Expected %s without a retry, got %v.", paymentlog.LogNotFound, err)
+	}
+}
+
+func TestClientTimeout(t *testing.T) {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer server.Close()
+	defer close(done)
+	client := NewClient(server.URL, 10*time.Millisecond)
+	client.Retries = 0
+	if _, err := client.GetPaymentLog("id"); err == nil {
+		t.Errorf("Expected a timeout error, got nil.")
+	}
+}
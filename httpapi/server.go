@@ -0,0 +1,384 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"code.whipround.net/paymentlog"
+)
+
+const DefaultPageSize = 20
+
+var missingErrors = []error{
+	paymentlog.MissingID,
+	paymentlog.MissingAmount,
+	paymentlog.MissingSource,
+	paymentlog.MissingSourceID,
+	paymentlog.MissingCreated,
+	paymentlog.MissingStatus,
+	paymentlog.MissingCurrency,
+	paymentlog.MissingProjectID,
+	paymentlog.MissingUserID,
+	paymentlog.MissingAccountType,
+	paymentlog.MissingAccountID,
+	paymentlog.FeesExceedAmount,
+	paymentlog.MissingResolver,
+	paymentlog.InvalidFailureState,
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func statusCode(err error) int {
+	switch err {
+	case paymentlog.LogNotFound, paymentlog.FailureLogNotFound:
+		return http.StatusNotFound
+	case paymentlog.AlreadyExists, paymentlog.InvalidFailureTransition:
+		return http.StatusConflict
+	case paymentlog.FailureStatesUnsupported:
+		return http.StatusNotImplemented
+	}
+	for _, missing := range missingErrors {
+		if err == missing {
+			return http.StatusBadRequest
+		}
+	}
+	return http.StatusInternalServerError
+}
+
+type Server struct {
+	store paymentlog.LogStore
+}
+
+func NewServer(store paymentlog.LogStore) *Server {
+	return &Server{store: store}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/payments":
+		switch r.Method {
+		case "GET":
+			s.listPaymentLogs(w, r)
+		case "POST":
+			s.storePaymentLog(w, r)
+		default:
+			methodNotAllowed(w, "GET, POST")
+		}
+	case strings.HasPrefix(r.URL.Path, "/payments/") && len(r.URL.Path) > len("/payments/"):
+		id := strings.TrimPrefix(r.URL.Path, "/payments/")
+		switch r.Method {
+		case "GET":
+			s.getPaymentLog(w, r, id)
+		case "PATCH":
+			s.updatePaymentLog(w, r, id)
+		case "DELETE":
+			s.deletePaymentLog(w, r, id)
+		default:
+			methodNotAllowed(w, "GET, PATCH, DELETE")
+		}
+	case r.URL.Path == "/failures":
+		switch r.Method {
+		case "GET":
+			s.listFailureLogs(w, r)
+		case "POST":
+			s.storeFailureLog(w, r)
+		default:
+			methodNotAllowed(w, "GET, POST")
+		}
+	case r.URL.Path == "/failures/open":
+		if r.Method != "GET" {
+			methodNotAllowed(w, "GET")
+			return
+		}
+		s.listOpenFailureLogs(w, r)
+	case strings.HasPrefix(r.URL.Path, "/failures/") && strings.HasSuffix(r.URL.Path, "/state") &&
+		len(r.URL.Path) > len("/failures//state"):
+		if r.Method != "PUT" {
+			methodNotAllowed(w, "PUT")
+			return
+		}
+		s.updateFailureLogState(w, r, strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/failures/"), "/state"))
+	case r.URL.Path == "/analytics/failures":
+		if r.Method != "GET" {
+			methodNotAllowed(w, "GET")
+			return
+		}
+		s.failureRates(w, r)
+	case r.URL.Path == "/analytics/projects":
+		if r.Method != "GET" {
+			methodNotAllowed(w, "GET")
+			return
+		}
+		s.projectTotals(w, r)
+	default:
+		writeError(w, http.StatusNotFound, "Not found.")
+	}
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, errorResponse{Error: msg})
+}
+
+func writeStoreError(w http.ResponseWriter, err error) {
+	writeError(w, statusCode(err), err.Error())
+}
+
+func methodNotAllowed(w http.ResponseWriter, allowed string) {
+	w.Header().Set("Allow", allowed)
+	writeError(w, http.StatusMethodNotAllowed, "Method not allowed.")
+}
+
+// pagination reads the num and offset parameters. num defaults to
+// DefaultPageSize; num=0 lists everything, as a num of 0 does for a LogStore.
+func pagination(r *http.Request) (num, offset int, err error) {
+	num, offset = DefaultPageSize, 0
+	if v := r.URL.Query().Get("num"); v != "" {
+		num, err = strconv.Atoi(v)
+		if err != nil || num < 0 {
+			return 0, 0, errInvalidParam("num")
+		}
+	}
+	if v := r.URL.Query().Get("offset"); v != "" {
+		offset, err = strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return 0, 0, errInvalidParam("offset")
+		}
+	}
+	return num, offset, nil
+}
+
+type errInvalidParam string
+
+func (e errInvalidParam) Error() string {
+	return "Invalid " + string(e) + " parameter."
+}
+
+func (s *Server) storePaymentLog(w http.ResponseWriter, r *http.Request) {
+	var log paymentlog.PaymentLog
+	if err := json.NewDecoder(r.Body).Decode(&log); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body.")
+		return
+	}
+	if err := log.Validate(); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if err := s.store.StorePaymentLog(log); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, log)
+}
+
+func (s *Server) getPaymentLog(w http.ResponseWriter, r *http.Request, id string) {
+	log, err := s.store.GetPaymentLog(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, log)
+}
+
+func (s *Server) updatePaymentLog(w http.ResponseWriter, r *http.Request, id string) {
+	var change paymentlog.PaymentLogChange
+	if err := json.NewDecoder(r.Body).Decode(&change); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body.")
+		return
+	}
+	if err := s.store.UpdatePaymentLog(id, change); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	log, err := s.store.GetPaymentLog(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, log)
+}
+
+func (s *Server) deletePaymentLog(w http.ResponseWriter, r *http.Request, id string) {
+	if err := s.store.DeletePaymentLog(id); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) listPaymentLogs(w http.ResponseWriter, r *http.Request) {
+	num, offset, err := pagination(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	projectID := r.URL.Query().Get("project_id")
+	userID := r.URL.Query().Get("user_id")
+	var logs []paymentlog.PaymentLog
+	switch {
+	case projectID != "" && userID != "":
+		writeError(w, http.StatusBadRequest, "Only one of project_id and user_id may be specified.")
+		return
+	case projectID != "":
+		logs, err = s.store.ListPaymentLogsByProject(projectID, num, offset)
+	case userID != "":
+		logs, err = s.store.ListPaymentLogsByUser(userID, num, offset)
+	default:
+		logs, err = s.store.ListPaymentLogs(num, offset)
+	}
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, logs)
+}
+
+func (s *Server) storeFailureLog(w http.ResponseWriter, r *http.Request) {
+	var failure paymentlog.FailureLog
+	if err := json.NewDecoder(r.Body).Decode(&failure); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body.")
+		return
+	}
+	if err := s.store.StoreFailureLog(failure); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, failure)
+}
+
+func (s *Server) listFailureLogs(w http.ResponseWriter, r *http.Request) {
+	var logs []paymentlog.FailureLog
+	if since := r.URL.Query().Get("since"); since != "" {
+		timestamp, err := time.Parse(time.RFC3339Nano, since)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, errInvalidParam("since").Error())
+			return
+		}
+		logs, err = s.store.ListFailureLogsSince(timestamp)
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, logs)
+		return
+	}
+	num, offset, err := pagination(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if v := r.URL.Query().Get("category"); v != "" {
+		category, err := paymentlog.ParseFailureCategory(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, errInvalidParam("category").Error())
+			return
+		}
+		logs, err = paymentlog.ListFailureLogsByCategory(s.store, category, num, offset)
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, logs)
+		return
+	}
+	logs, err = s.store.ListFailureLogs(num, offset)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, logs)
+}
+
+func (s *Server) updateFailureLogState(w http.ResponseWriter, r *http.Request, id string) {
+	var change paymentlog.FailureStateChange
+	if err := json.NewDecoder(r.Body).Decode(&change); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body.")
+		return
+	}
+	states, ok := s.store.(paymentlog.FailureStateStore)
+	if !ok {
+		writeStoreError(w, paymentlog.FailureStatesUnsupported)
+		return
+	}
+	if err := states.UpdateFailureLogState(id, change); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) listOpenFailureLogs(w http.ResponseWriter, r *http.Request) {
+	num, offset, err := pagination(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	states, ok := s.store.(paymentlog.FailureStateStore)
+	if !ok {
+		writeStoreError(w, paymentlog.FailureStatesUnsupported)
+		return
+	}
+	logs, err := states.ListOpenFailureLogs(num, offset)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, logs)
+}
+
+func (s *Server) failureRates(w http.ResponseWriter, r *http.Request) {
+	var opts paymentlog.FailureRateOptions
+	var err error
+	query := r.URL.Query()
+	if opts.Since, err = time.Parse(time.RFC3339Nano, query.Get("since")); err != nil {
+		writeError(w, http.StatusBadRequest, errInvalidParam("since").Error())
+		return
+	}
+	if opts.Until, err = time.Parse(time.RFC3339Nano, query.Get("until")); err != nil {
+		writeError(w, http.StatusBadRequest, errInvalidParam("until").Error())
+		return
+	}
+	if v := query.Get("bucket"); v != "" {
+		if opts.Bucket, err = time.ParseDuration(v); err != nil || opts.Bucket <= 0 {
+			writeError(w, http.StatusBadRequest, errInvalidParam("bucket").Error())
+			return
+		}
+	}
+	if opts.GroupBy, err = paymentlog.ParseDimension(query.Get("group_by")); err != nil {
+		writeError(w, http.StatusBadRequest, errInvalidParam("group_by").Error())
+		return
+	}
+	series, err := paymentlog.FailureRates(s.store, opts)
+	if err == paymentlog.InvalidAnalyticsWindow {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, series)
+}
+
+func (s *Server) projectTotals(w http.ResponseWriter, r *http.Request) {
+	var filters []paymentlog.PaymentLogFilter
+	if projectID := r.URL.Query().Get("project_id"); projectID != "" {
+		filters = append(filters, paymentlog.ProjectFilter(projectID))
+	}
+	totals, err := paymentlog.ProjectTotals(s.store, filters...)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, totals)
+}
@@ -0,0 +1,325 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"code.whipround.net/paymentlog"
+	_ "code.whipround.net/paymentlog/balanced"
+)
+
+func testPaymentLog(id string, created time.Time) paymentlog.PaymentLog {
+	return paymentlog.PaymentLog{
+		ID:          id,
+		Amount:      1,
+		Source:      paymentlog.SourceBalanced,
+		SourceID:    "balanced-id",
+		Created:     created,
+		Status:      paymentlog.StatusPending,
+		Currency:    paymentlog.CurrencyUSD,
+		ProjectID:   "project-id",
+		UserID:      "user-id",
+		AccountID:   "account-id",
+		AccountType: "google",
+	}
+}
+
+func request(t *testing.T, handler http.Handler, method, path string, body interface{}) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatalf("Error encoding request body: %s", err)
+		}
+	}
+	r, err := http.NewRequest(method, path, &buf)
+	if err != nil {
+		t.Fatalf("Error creating request: %s", err)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestStoringPaymentLogOverHTTP(t *testing.T) {
+	store := paymentlog.NewMemoryStore()
+	server := NewServer(store)
+	p := testPaymentLog("test-payment-log", time.Now())
+	w := request(t, server, "POST", "/payments", p)
+	if w.Code != http.StatusCreated {
+		t.Errorf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	if _, err := store.GetPaymentLog(p.ID); err != nil {
+		t.Errorf("Error retrieving stored payment log: %s", err)
+	}
+	w = request(t, server, "POST", "/payments", p)
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected status %d for a duplicate, got %d: %s", http.StatusConflict, w.Code, w.Body.String())
+	}
+	p.ID = "other-payment-log"
+	p.Amount = 0
+	w = request(t, server, "POST", "/payments", p)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an invalid payment log, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+	var resp errorResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Errorf("Error decoding error response: %s", err)
+	}
+	if resp.Error != paymentlog.MissingAmount.Error() {
+		t.Errorf("Expected error %q, got %q.", paymentlog.MissingAmount.Error(), resp.Error)
+	}
+}
+
+func TestGettingAndPatchingPaymentLogOverHTTP(t *testing.T) {
+	store := paymentlog.NewMemoryStore()
+	server := NewServer(store)
+	p := testPaymentLog("test-payment-log", time.Now())
+	store.StorePaymentLog(p)
+	w := request(t, server, "GET", "/payments/"+p.ID, nil)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var result paymentlog.PaymentLog
+	if err := json.NewDecoder(w.Body).Decode(&result); err != nil {
+		t.Errorf("Error decoding payment log: %s", err)
+	}
+	if result.ID != p.ID || !result.Created.Equal(p.Created) {
+		t.Errorf("Expected %+v, got %+v.", p, result)
+	}
+	status := "succeeded"
+	w = request(t, server, "PATCH", "/payments/"+p.ID, paymentlog.PaymentLogChange{Status: &status})
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	updated, err := store.GetPaymentLog(p.ID)
+	if err != nil {
+		t.Errorf("Error retrieving payment log: %s", err)
+	}
+	if updated.Status != status || updated.Amount != p.Amount {
+		t.Errorf("Expected only the status to change, got %+v.", updated)
+	}
+	fee := p.Amount + 1
+	w = request(t, server, "PATCH", "/payments/"+p.ID, paymentlog.PaymentLogChange{ProcessorFee: &fee})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for fees exceeding the amount, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+	w = request(t, server, "PATCH", "/payments/not-a-payment-log", paymentlog.PaymentLogChange{Status: &status})
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d: %s", http.StatusNotFound, w.Code, w.Body.String())
+	}
+	w = request(t, server, "GET", "/payments/not-a-payment-log", nil)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d: %s", http.StatusNotFound, w.Code, w.Body.String())
+	}
+}
+
+func TestDeletingPaymentLogOverHTTP(t *testing.T) {
+	store := paymentlog.NewMemoryStore()
+	server := NewServer(store)
+	p := testPaymentLog("test-payment-log", time.Now())
+	store.StorePaymentLog(p)
+	w := request(t, server, "DELETE", "/payments/"+p.ID, nil)
+	if w.Code != http.StatusNoContent {
+		t.Errorf("Expected status %d, got %d: %s", http.StatusNoContent, w.Code, w.Body.String())
+	}
+	w = request(t, server, "DELETE", "/payments/"+p.ID, nil)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d: %s", http.StatusNotFound, w.Code, w.Body.String())
+	}
+}
+
+func TestListingPaymentLogsOverHTTP(t *testing.T) {
+	store := paymentlog.NewMemoryStore()
+	server := NewServer(store)
+	now := time.Now()
+	for pos, id := range []string{"id1", "id2", "id3", "id4"} {
+		p := testPaymentLog(id, now.Add(time.Duration(pos)*time.Hour))
+		if pos%2 == 1 {
+			p.ProjectID = "other-project-id"
+			p.UserID = "other-user-id"
+		}
+		store.StorePaymentLog(p)
+	}
+	tests := map[string][]string{
+		"/payments":                             []string{"id4", "id3", "id2", "id1"},
+		"/payments?num=2&offset=1":              []string{"id3", "id2"},
+		"/payments?project_id=project-id":       []string{"id3", "id1"},
+		"/payments?user_id=other-user-id&num=1": []string{"id4"},
+	}
+	for path, expectation := range tests {
+		w := request(t, server, "GET", path, nil)
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: expected status %d, got %d: %s", path, http.StatusOK, w.Code, w.Body.String())
+			continue
+		}
+		var results []paymentlog.PaymentLog
+		if err := json.NewDecoder(w.Body).Decode(&results); err != nil {
+			t.Errorf("%s: error decoding payment logs: %s", path, err)
+			continue
+		}
+		if len(results) != len(expectation) {
+			t.Errorf("%s: expected %d payment logs, got %d.", path, len(expectation), len(results))
+			continue
+		}
+		for pos, id := range expectation {
+			if results[pos].ID != id {
+				t.Errorf("%s: expected result %d to be %s, got %s.", path, pos, id, results[pos].ID)
+			}
+		}
+	}
+	w := request(t, server, "GET", "/payments?num=nope", nil)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an invalid num, got %d.", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestFailureLogsOverHTTP(t *testing.T) {
+	store := paymentlog.NewMemoryStore()
+	server := NewServer(store)
+	now := time.Now()
+	for pos, id := range []string{"id1", "id2", "id3"} {
+		failure := paymentlog.FailureLog{
+			ID:                id,
+			PaymentLogID:      "payment-log",
+			FailureReason:     "you screwed up",
+			FailureReasonCode: "500",
+			Timestamp:         now.Add(time.Duration(pos) * time.Hour),
+		}
+		if id == "id2" {
+			failure.Source, failure.FailureReasonCode = paymentlog.SourceBalanced, "insufficient-funds"
+		}
+		w := request(t, server, "POST", "/failures", failure)
+		if w.Code != http.StatusCreated {
+			t.Errorf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+		}
+	}
+	w := request(t, server, "POST", "/failures", paymentlog.FailureLog{ID: "id1"})
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected status %d for a duplicate, got %d: %s", http.StatusConflict, w.Code, w.Body.String())
+	}
+	changes := []struct {
+		path   string
+		change paymentlog.FailureStateChange
+		code   int
+	}{
+		{"/failures/id2/state", paymentlog.FailureStateChange{State: paymentlog.FailureAcknowledged, Resolver: "support"}, http.StatusNoContent},
+		{"/failures/id2/state", paymentlog.FailureStateChange{State: paymentlog.FailureAcknowledged, Resolver: "support"}, http.StatusConflict},
+		{"/failures/id1/state", paymentlog.FailureStateChange{State: paymentlog.FailureResolved}, http.StatusBadRequest},
+		{"/failures/missing/state", paymentlog.FailureStateChange{State: paymentlog.FailureResolved, Resolver: "support"}, http.StatusNotFound},
+	}
+	for _, c := range changes {
+		w := request(t, server, "PUT", c.path, c.change)
+		if w.Code != c.code {
+			t.Errorf("%s %+v: expected status %d, got %d: %s", c.path, c.change, c.code, w.Code, w.Body.String())
+		}
+	}
+	tests := map[string][]string{
+		"/failures/open":           []string{"id1", "id3"},
+		"/failures":                []string{"id3", "id2", "id1"},
+		"/failures?num=1&offset=1": []string{"id2"},
+		"/failures?since=" + url.QueryEscape(now.Add(time.Minute).Format(time.RFC3339Nano)): []string{"id3", "id2"},
+		"/failures?category=insufficient_funds":                                             []string{"id2"},
+		"/failures?category=unknown&num=1":                                                  []string{"id3"},
+	}
+	for path, expectation := range tests {
+		w := request(t, server, "GET", path, nil)
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: expected status %d, got %d: %s", path, http.StatusOK, w.Code, w.Body.String())
+			continue
+		}
+		var results []paymentlog.FailureLog
+		if err := json.NewDecoder(w.Body).Decode(&results); err != nil {
+			t.Errorf("%s: error decoding failure logs: %s", path, err)
+			continue
+		}
+		if len(results) != len(expectation) {
+			t.Errorf("%s: expected %d failure logs, got %d.", path, len(expectation), len(results))
+			continue
+		}
+		for pos, id := range expectation {
+			if results[pos].ID != id {
+				t.Errorf("%s: expected result %d to be %s, got %s.", path, pos, id, results[pos].ID)
+			}
+		}
+	}
+	w = request(t, server, "GET", "/failures?category=bogus", nil)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an invalid category, got %d.", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestFailureRatesOverHTTP(t *testing.T) {
+	store := paymentlog.NewMemoryStore()
+	server := NewServer(store)
+	start := time.Date(2014, time.March, 1, 12, 0, 0, 0, time.UTC)
+	for pos, id := range []string{"id1", "id2"} {
+		w := request(t, server, "POST", "/payments", testPaymentLog(id, start.Add(time.Duration(pos)*time.Hour)))
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+		}
+	}
+	w := request(t, server, "POST", "/failures", paymentlog.FailureLog{ID: "failure", PaymentLogID: "id1", Timestamp: start})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	query := "since=" + url.QueryEscape(start.Format(time.RFC3339Nano)) + "&until=" + url.QueryEscape(start.Add(2*time.Hour).Format(time.RFC3339Nano))
+	w = request(t, server, "GET", "/analytics/failures?"+query+"&bucket=1h&group_by=project", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var series []paymentlog.FailureRateSeries
+	if err := json.NewDecoder(w.Body).Decode(&series); err != nil {
+		t.Fatalf("Error decoding failure rates: %s", err)
+	}
+	if len(series) != 1 || len(series[0].Points) != 2 || series[0].Points[0].Rate != 1 || series[0].Points[1].Attempts != 1 {
+		t.Errorf("Unexpected failure rates: %+v", series)
+	}
+	for _, path := range []string{"/analytics/failures", "/analytics/failures?" + query + "&bucket=soon", "/analytics/failures?" + query + "&group_by=user"} {
+		w = request(t, server, "GET", path, nil)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d.", path, http.StatusBadRequest, w.Code)
+		}
+	}
+}
+
+func TestProjectTotalsOverHTTP(t *testing.T) {
+	store := paymentlog.NewMemoryStore()
+	server := NewServer(store)
+	start := time.Date(2014, time.March, 1, 12, 0, 0, 0, time.UTC)
+	for pos, id := range []string{"id1", "id2"} {
+		log := testPaymentLog(id, start.Add(time.Duration(pos)*time.Hour))
+		log.Amount, log.Status = 1000, paymentlog.StatusSucceeded
+		if id == "id2" {
+			log.ProjectID = "other-project-id"
+		}
+		w := request(t, server, "POST", "/payments", log)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+		}
+	}
+	w := request(t, server, "PATCH", "/payments/id1", map[string]uint{"processor_fee": 59, "platform_fee": 50})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	w = request(t, server, "GET", "/analytics/projects?project_id=project-id", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var totals []paymentlog.ProjectTotal
+	if err := json.NewDecoder(w.Body).Decode(&totals); err != nil {
+		t.Fatalf("Error decoding project totals: %s", err)
+	}
+	expectation := paymentlog.ProjectTotal{ProjectID: "project-id", Currency: paymentlog.CurrencyUSD, Payments: 1, Gross: 1000, ProcessorFees: 59, PlatformFees: 50, Net: 891}
+	if len(totals) != 1 || totals[0] != expectation {
+		t.Errorf("Expected [%+v], got %+v.", expectation, totals)
+	}
+	w = request(t, server, "GET", "/analytics/projects", nil)
+	if err := json.NewDecoder(w.Body).Decode(&totals); err != nil || len(totals) != 2 {
+		t.Errorf("Expected totals for 2 projects, got %+v, %v.", totals, err)
+	}
+}
@@ -0,0 +1,159 @@
+package paymentlog
+
+import (
+	"encoding/json"
+	"time"
+)
+
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// MarshalJSON encodes the payment log using its json tags, leaving out
+// zero Created and Updated times instead of encoding them as
+// 0001-01-01T00:00:00Z.
+func (p PaymentLog) MarshalJSON() ([]byte, error) {
+	type paymentLog PaymentLog
+	return json.Marshal(struct {
+		paymentLog
+		Created *time.Time `json:"created,omitempty"`
+		Updated *time.Time `json:"updated,omitempty"`
+	}{
+		paymentLog: paymentLog(p),
+		Created:    timeOrNil(p.Created),
+		Updated:    timeOrNil(p.Updated),
+	})
+}
+
+func (f FailureLog) MarshalJSON() ([]byte, error) {
+	type failureLog FailureLog
+	return json.Marshal(struct {
+		failureLog
+		Timestamp    *time.Time `json:"timestamp,omitempty"`
+		StateUpdated *time.Time `json:"state_updated,omitempty"`
+	}{
+		failureLog:   failureLog(f),
+		Timestamp:    timeOrNil(f.Timestamp),
+		StateUpdated: timeOrNil(f.StateUpdated),
+	})
+}
+
+func (d Dispute) MarshalJSON() ([]byte, error) {
+	type dispute Dispute
+	return json.Marshal(struct {
+		dispute
+		EvidenceDue *time.Time `json:"evidence_due,omitempty"`
+		Updated     *time.Time `json:"updated,omitempty"`
+	}{
+		dispute:     dispute(d),
+		EvidenceDue: timeOrNil(d.EvidenceDue),
+		Updated:     timeOrNil(d.Updated),
+	})
+}
+
+func (s ProjectSummary) MarshalJSON() ([]byte, error) {
+	type projectSummary ProjectSummary
+	return json.Marshal(struct {
+		projectSummary
+		FirstContribution *time.Time `json:"first_contribution,omitempty"`
+		LastContribution  *time.Time `json:"last_contribution,omitempty"`
+	}{
+		projectSummary:    projectSummary(s),
+		FirstContribution: timeOrNil(s.FirstContribution),
+		LastContribution:  timeOrNil(s.LastContribution),
+	})
+}
+
+func changedTime(t *time.Time) (*json.RawMessage, error) {
+	if t == nil {
+		return nil, nil
+	}
+	raw := json.RawMessage("null")
+	if !t.IsZero() {
+		var err error
+		raw, err = json.Marshal(*t)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &raw, nil
+}
+
+// MarshalJSON leaves out fields that aren't being changed and encodes
+// changes to a zero time as null.
+func (c PaymentLogChange) MarshalJSON() ([]byte, error) {
+	type paymentLogChange PaymentLogChange
+	created, err := changedTime(c.Created)
+	if err != nil {
+		return nil, err
+	}
+	updated, err := changedTime(c.Updated)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(struct {
+		paymentLogChange
+		Created *json.RawMessage `json:"created,omitempty"`
+		Updated *json.RawMessage `json:"updated,omitempty"`
+	}{
+		paymentLogChange: paymentLogChange(c),
+		Created:          created,
+		Updated:          updated,
+	})
+}
+
+// UnmarshalJSON tells absent fields apart from fields explicitly set to
+// null. Absent fields are left nil, so they won't be changed; fields set to
+// null are set to their zero value, so they will be cleared.
+func (c *PaymentLogChange) UnmarshalJSON(data []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	change := PaymentLogChange{}
+	var err error
+	// json.Unmarshal leaves the value a pointer points to untouched when it
+	// decodes null, so each pointer starts out at its zero value.
+	for key, raw := range fields {
+		switch key {
+		case "amount":
+			change.Amount = new(uint)
+			err = json.Unmarshal(raw, change.Amount)
+		case "description":
+			change.Description = new(string)
+			err = json.Unmarshal(raw, change.Description)
+		case "source":
+			change.Source = new(string)
+			err = json.Unmarshal(raw, change.Source)
+		case "source_id":
+			change.SourceID = new(string)
+			err = json.Unmarshal(raw, change.SourceID)
+		case "created":
+			change.Created = new(time.Time)
+			err = json.Unmarshal(raw, change.Created)
+		case "updated":
+			change.Updated = new(time.Time)
+			err = json.Unmarshal(raw, change.Updated)
+		case "status":
+			change.Status = new(string)
+			err = json.Unmarshal(raw, change.Status)
+		case "currency":
+			change.Currency = new(string)
+			err = json.Unmarshal(raw, change.Currency)
+		case "processor_fee":
+			change.ProcessorFee = new(uint)
+			err = json.Unmarshal(raw, change.ProcessorFee)
+		case "platform_fee":
+			change.PlatformFee = new(uint)
+			err = json.Unmarshal(raw, change.PlatformFee)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	*c = change
+	return nil
+}
@@ -0,0 +1,146 @@
+package paymentlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden files in testdata")
+
+func checkGolden(t *testing.T, name string, v interface{}) {
+	result, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		t.Fatalf("Error encoding %s: %s", name, err)
+	}
+	result = append(result, '\n')
+	path := filepath.Join("testdata", name+".golden")
+	if *updateGolden {
+		if err := ioutil.WriteFile(path, result, 0644); err != nil {
+			t.Fatalf("Error updating %s: %s", path, err)
+		}
+	}
+	expectation, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Error reading %s: %s", path, err)
+	}
+	if !bytes.Equal(result, expectation) {
+		t.Errorf("Mismatch with %s. Expected:\n%s\ngot:\n%s", path, expectation, result)
+	}
+}
+
+func TestPaymentLogJSON(t *testing.T) {
+	p := PaymentLog{
+		ID:          "test-payment-log",
+		Amount:      1250,
+		Description: "a description",
+		Source:      SourceBalanced,
+		SourceID:    "balanced-id",
+		Created:     time.Date(2014, time.March, 1, 12, 0, 0, 0, time.UTC),
+		Status:      StatusPending,
+		Currency:    CurrencyUSD,
+		ProjectID:   "project-id",
+		UserID:      "user-id",
+		AccountID:   "account-id",
+		AccountType: "google",
+	}
+	checkGolden(t, "payment_log", p)
+	checkGolden(t, "payment_log_empty", PaymentLog{ID: "test-payment-log"})
+
+	encoded, err := json.Marshal(p)
+	if err != nil {
+		t.Fatalf("Error encoding payment log: %s", err)
+	}
+	var result PaymentLog
+	if err := json.Unmarshal(encoded, &result); err != nil {
+		t.Fatalf("Error decoding payment log: %s", err)
+	}
+	success, field, expectation, res := comparePaymentLogs(p, result)
+	if !success {
+		t.Errorf("Mismatch. Expected payment log %s to be %+v, got %+v.", field, expectation, res)
+	}
+}
+
+func TestFailureLogJSON(t *testing.T) {
+	f := FailureLog{
+		ID:                "id",
+		PaymentLogID:      "payment-log",
+		Source:            SourceBalanced,
+		FailureReason:     "you screwed up",
+		FailureReasonCode: "500",
+		Timestamp:         time.Date(2014, time.March, 1, 12, 0, 0, 0, time.FixedZone("EST", -5*60*60)),
+	}
+	checkGolden(t, "failure_log", f)
+
+	encoded, err := json.Marshal(f)
+	if err != nil {
+		t.Fatalf("Error encoding failure log: %s", err)
+	}
+	var result FailureLog
+	if err := json.Unmarshal(encoded, &result); err != nil {
+		t.Fatalf("Error decoding failure log: %s", err)
+	}
+	success, field, expectation, res := compareFailureLogs(f, result)
+	if !success {
+		t.Errorf("Mismatch. Expected failure log %s to be %+v, got %+v.", field, expectation, res)
+	}
+}
+
+func TestPaymentLogChangeJSON(t *testing.T) {
+	amount := uint(0)
+	status := "succeeded"
+	description := ""
+	updated := time.Date(2014, time.March, 1, 12, 0, 0, 0, time.UTC)
+	created := time.Time{}
+	checkGolden(t, "payment_log_change", PaymentLogChange{
+		Amount:      &amount,
+		Description: &description,
+		Status:      &status,
+		Created:     &created,
+		Updated:     &updated,
+	})
+}
+
+func TestDecodingPaymentLogChange(t *testing.T) {
+	var change PaymentLogChange
+	err := json.Unmarshal([]byte(`{"status": "succeeded", "description": null, "source": "", "updated": null}`), &change)
+	if err != nil {
+		t.Fatalf("Error decoding payment log change: %s", err)
+	}
+	if change.Status == nil || *change.Status != "succeeded" {
+		t.Errorf("Expected status to be set to succeeded, got %v.", change.Status)
+	}
+	if change.Description == nil || *change.Description != "" {
+		t.Errorf("Expected an explicit null description to clear the description, got %v.", change.Description)
+	}
+	if change.Source == nil || *change.Source != "" {
+		t.Errorf("Expected an empty source to clear the source, got %v.", change.Source)
+	}
+	if change.Updated == nil || !change.Updated.IsZero() {
+		t.Errorf("Expected an explicit null updated to clear the updated time, got %v.", change.Updated)
+	}
+	if change.Amount != nil || change.SourceID != nil || change.Created != nil || change.Currency != nil {
+		t.Errorf("Expected absent fields to be left nil, got %+v.", change)
+	}
+	err = json.Unmarshal([]byte(`{"amount": "lots"}`), &change)
+	if err == nil {
+		t.Errorf("Expected an error decoding an invalid amount.")
+	}
+
+	encoded, err := json.Marshal(change)
+	if err != nil {
+		t.Fatalf("Error encoding payment log change: %s", err)
+	}
+	var result PaymentLogChange
+	if err := json.Unmarshal(encoded, &result); err != nil {
+		t.Fatalf("Error decoding payment log change: %s", err)
+	}
+	fields, resultFields := change.Fields(), result.Fields()
+	if len(fields) != len(resultFields) {
+		t.Errorf("Expected fields %v to round trip, got %v.", fields, resultFields)
+	}
+}
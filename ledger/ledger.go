@@ -0,0 +1,535 @@
+// Package ledger derives double-entry journal entries from payment logs.
+//
+// Amounts are signed: debits are positive and credits negative, so every
+// entry sums to zero. Processor clearing accounts are assets, holding what
+// processors owe the platform; project escrow accounts are liabilities,
+// holding what the platform owes projects, so their balances are normally
+// negative. Contributions and refunds pass through the contributor's user
+// account, whose balance returns to zero once money has moved on.
+package ledger
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"code.whipround.net/paymentlog"
+)
+
+const (
+	EntryChargeCaptured = "charge_captured"
+	EntryChargeAdjusted = "charge_adjusted"
+	EntryContribution   = "contribution"
+	EntryFeeTaken       = "fee_taken"
+	EntryRefundIssued   = "refund_issued"
+	EntryChargeback     = "chargeback"
+	EntryFundsReturned  = "funds_returned"
+	EntryPayoutMade     = "payout_made"
+
+	PlatformRevenue = "platform:revenue"
+)
+
+var (
+	Unbalanced      = errors.New("Journal entry doesn't balance.")
+	EmptyEntry      = errors.New("Journal entry has no postings.")
+	MissingCurrency = errors.New("Missing journal entry currency.")
+	AlreadyRecorded = errors.New("Journal entry already recorded.")
+	UnknownPayment  = errors.New("Payment log hasn't been captured in the ledger.")
+)
+
+func UserAccount(userID string) string {
+	return "user:" + userID
+}
+
+func ProjectEscrow(projectID string) string {
+	return "project:" + projectID + ":escrow"
+}
+
+func ProcessorClearing(source string) string {
+	return "processor:" + source + ":clearing"
+}
+
+type Posting struct {
+	Account string `json:"account"`
+	Amount  int64  `json:"amount"`
+}
+
+type Entry struct {
+	ID           string    `json:"id"`
+	Type         string    `json:"type"`
+	PaymentLogID string    `json:"payment_log_id,omitempty"`
+	Currency     string    `json:"currency"`
+	Time         time.Time `json:"time"`
+	Postings     []Posting `json:"postings"`
+}
+
+func (e Entry) Validate() error {
+	if len(e.Postings) == 0 {
+		return EmptyEntry
+	}
+	if e.Currency == "" {
+		return MissingCurrency
+	}
+	var sum int64
+	for _, posting := range e.Postings {
+		sum += posting.Amount
+	}
+	if sum != 0 {
+		return Unbalanced
+	}
+	return nil
+}
+
+// recorded is what the ledger has recorded so far for a payment log.
+type recorded struct {
+	source       string
+	captured     bool
+	amount       uint
+	processorFee uint
+	platformFee  uint
+	refunded     uint
+	chargedBack  uint
+	entries      int
+}
+
+// Ledger is an in-memory journal. It's safe for concurrent use.
+type Ledger struct {
+	entries  []Entry
+	ids      map[string]bool
+	payments map[string]*recorded
+	sync.Mutex
+}
+
+func New() *Ledger {
+	return &Ledger{
+		ids:      map[string]bool{},
+		payments: map[string]*recorded{},
+	}
+}
+
+// Record adds an entry to the journal, rejecting entries that don't
+// balance and entries whose ID has already been recorded.
+func (l *Ledger) Record(entry Entry) error {
+	l.Lock()
+	defer l.Unlock()
+	return l.record(entry)
+}
+
+func (l *Ledger) record(entry Entry) error {
+	if err := entry.Validate(); err != nil {
+		return err
+	}
+	if l.ids[entry.ID] {
+		return AlreadyRecorded
+	}
+	l.ids[entry.ID] = true
+	pos := sort.Search(len(l.entries), func(i int) bool {
+		return l.entries[i].Time.After(entry.Time)
+	})
+	l.entries = append(l.entries, Entry{})
+	copy(l.entries[pos+1:], l.entries[pos:])
+	l.entries[pos] = entry
+	return nil
+}
+
+// transfer returns an entry debiting to and crediting from by amount, which
+// may be negative to reverse an earlier transfer.
+func transfer(kind string, log paymentlog.PaymentLog, at time.Time, amount int64, to, from string) Entry {
+	return Entry{
+		Type:         kind,
+		PaymentLogID: log.ID,
+		Currency:     log.Currency,
+		Time:         at,
+		Postings: []Posting{
+			{Account: to, Amount: amount},
+			{Account: from, Amount: -amount},
+		},
+	}
+}
+
+func paymentTime(log paymentlog.PaymentLog) time.Time {
+	if !log.Updated.IsZero() {
+		return log.Updated
+	}
+	return log.Created
+}
+
+// Apply records the entries for whatever has happened to a payment since
+// the ledger last saw it: the charge being captured, its amount being
+// corrected, fees being taken or adjusted, a refund and chargebacks.
+// Applying the same state twice records nothing. The entries are dated at,
+// or at the payment's last update if at is zero. Apply returns the entries
+// it recorded.
+func (l *Ledger) Apply(log paymentlog.PaymentLog, at time.Time) ([]Entry, error) {
+	if at.IsZero() {
+		at = paymentTime(log)
+	}
+	l.Lock()
+	defer l.Unlock()
+	state, ok := l.payments[log.ID]
+	if !ok {
+		state = &recorded{}
+	}
+	next := *state
+	entries := []Entry{}
+	captured := log.Status == paymentlog.StatusSucceeded || log.Status == paymentlog.StatusRefunded
+	if !captured && !state.captured {
+		return entries, nil
+	}
+	user, escrow, clearing := UserAccount(log.UserID), ProjectEscrow(log.ProjectID), ProcessorClearing(log.Source)
+	if !state.captured {
+		next.source, next.captured, next.amount = log.Source, true, log.Amount
+		amount := int64(log.Amount)
+		entries = append(entries,
+			transfer(EntryChargeCaptured, log, at, amount, clearing, user),
+			transfer(EntryContribution, log, at, amount, user, escrow),
+		)
+	}
+	if log.ProcessorFee != state.processorFee || log.PlatformFee != state.platformFee {
+		processorFee := int64(log.ProcessorFee) - int64(state.processorFee)
+		platformFee := int64(log.PlatformFee) - int64(state.platformFee)
+		next.processorFee, next.platformFee = log.ProcessorFee, log.PlatformFee
+		entry := Entry{
+			Type:         EntryFeeTaken,
+			PaymentLogID: log.ID,
+			Currency:     log.Currency,
+			Time:         at,
+			Postings:     []Posting{{Account: escrow, Amount: processorFee + platformFee}},
+		}
+		if processorFee != 0 {
+			entry.Postings = append(entry.Postings, Posting{Account: clearing, Amount: -processorFee})
+		}
+		if platformFee != 0 {
+			entry.Postings = append(entry.Postings, Posting{Account: PlatformRevenue, Amount: -platformFee})
+		}
+		entries = append(entries, entry)
+	}
+	if state.captured && log.Amount != state.amount {
+		amount := int64(log.Amount) - int64(state.amount)
+		next.amount = log.Amount
+		entries = append(entries, transfer(EntryChargeAdjusted, log, at, amount, clearing, escrow))
+	}
+	// Money is only returned once: a refund returns what hasn't already been
+	// charged back, and a chargeback after a refund returns nothing more.
+	chargedBack, refunded := minimum(log.ChargedBack, next.amount), uint(0)
+	if log.Status == paymentlog.StatusRefunded {
+		if state.refunded > 0 {
+			chargedBack = minimum(log.ChargedBack, next.amount-minimum(state.refunded, next.amount))
+		}
+		refunded = next.amount - chargedBack
+	}
+	if refunded != state.refunded {
+		amount := int64(refunded) - int64(state.refunded)
+		next.refunded = refunded
+		entries = append(entries,
+			transfer(EntryRefundIssued, log, at, amount, escrow, user),
+			transfer(EntryFundsReturned, log, at, amount, user, clearing),
+		)
+	}
+	if chargedBack != state.chargedBack {
+		amount := int64(chargedBack) - int64(state.chargedBack)
+		next.chargedBack = chargedBack
+		entries = append(entries,
+			transfer(EntryChargeback, log, at, amount, escrow, user),
+			transfer(EntryFundsReturned, log, at, amount, user, clearing),
+		)
+	}
+	for pos := range entries {
+		next.entries++
+		entries[pos].ID = fmt.Sprintf("%s/%d", log.ID, next.entries)
+		if err := entries[pos].Validate(); err != nil {
+			return nil, err
+		}
+	}
+	for _, entry := range entries {
+		if err := l.record(entry); err != nil {
+			return nil, err
+		}
+	}
+	l.payments[log.ID] = &next
+	return entries, nil
+}
+
+func minimum(a, b uint) uint {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func (r recorded) net() uint {
+	deductions := r.processorFee + r.platformFee + r.chargedBack
+	if deductions >= r.amount {
+		return 0
+	}
+	return r.amount - deductions
+}
+
+// ApplyPayout records paying out a payout's Amount from the project's
+// escrow, through the processors its payment logs were collected by, split
+// as its Sources were when it was paid out. Payouts without Sources are
+// split by the payment logs' net amounts in the ledger. Every payment log
+// must have been captured in the ledger.
+func (l *Ledger) ApplyPayout(payout paymentlog.Payout) (Entry, error) {
+	l.Lock()
+	defer l.Unlock()
+	bySource := map[string]int64{}
+	var split int64
+	for _, id := range payout.PaymentLogIDs {
+		state, ok := l.payments[id]
+		if !ok || !state.captured {
+			return Entry{}, UnknownPayment
+		}
+		bySource[state.source] += int64(state.net())
+		split += int64(state.net())
+	}
+	if len(payout.Sources) > 0 {
+		bySource, split = map[string]int64{}, 0
+		for source, amount := range payout.Sources {
+			bySource[source] = int64(amount)
+			split += int64(amount)
+		}
+	}
+	sources := make([]string, 0, len(bySource))
+	for source := range bySource {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	if len(sources) > 0 {
+		bySource[sources[0]] += int64(payout.Amount) - split
+	}
+	entry := Entry{
+		ID:       "payout/" + payout.ID,
+		Type:     EntryPayoutMade,
+		Currency: payout.Currency,
+		Time:     payout.Created,
+		Postings: []Posting{{Account: ProjectEscrow(payout.ProjectID), Amount: int64(payout.Amount)}},
+	}
+	for _, source := range sources {
+		entry.Postings = append(entry.Postings, Posting{Account: ProcessorClearing(source), Amount: -bySource[source]})
+	}
+	return entry, l.record(entry)
+}
+
+// Replay applies every payment log in store, oldest first, and then its
+// payouts if it's a PayoutStore. Since a payment log only holds its latest
+// state, Replay dates entries as best it can: a capture at the payment's
+// last update, or when it was created if it has since been refunded or
+// charged back, a refund at the payment's last update, and chargebacks
+// when their disputes were decided if store is a DisputeStore, or at the
+// payment's last update if it isn't.
+func (l *Ledger) Replay(store paymentlog.LogStore) error {
+	logs := []paymentlog.PaymentLog{}
+	projects := map[string]bool{}
+	err := store.IteratePaymentLogs(func(log paymentlog.PaymentLog) error {
+		logs = append(logs, log)
+		projects[log.ProjectID] = true
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	disputes, _ := store.(paymentlog.DisputeStore)
+	for pos := len(logs) - 1; pos >= 0; pos-- {
+		var decided []paymentlog.Dispute
+		if disputes != nil && logs[pos].ChargedBack > 0 {
+			if decided, err = disputes.ListDisputesByPaymentLog(logs[pos].ID); err != nil {
+				return err
+			}
+		}
+		if err := l.replay(logs[pos], decided); err != nil {
+			return err
+		}
+	}
+	payouts, ok := store.(paymentlog.PayoutStore)
+	if !ok {
+		return nil
+	}
+	for project := range projects {
+		results, err := payouts.ListPayouts(project, 0, 0)
+		if err != nil {
+			return err
+		}
+		for _, payout := range results {
+			if _, err := l.ApplyPayout(payout); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// replay applies a payment log one step at a time, so that its capture,
+// chargebacks and refund are dated separately.
+func (l *Ledger) replay(log paymentlog.PaymentLog, disputes []paymentlog.Dispute) error {
+	chargebacks := []paymentlog.Dispute{}
+	for _, dispute := range disputes {
+		if dispute.ChargedBack() {
+			chargebacks = append(chargebacks, dispute)
+		}
+	}
+	sort.Sort(byDecision(chargebacks))
+	updated, refunded := paymentTime(log), log.Status == paymentlog.StatusRefunded
+	step, at := log, updated
+	step.ChargedBack = 0
+	if refunded || (len(chargebacks) > 0 && decided(chargebacks[0]).Before(at)) {
+		at = log.Created
+	}
+	if refunded {
+		step.Status = paymentlog.StatusSucceeded
+	}
+	if _, err := l.Apply(step, at); err != nil {
+		return err
+	}
+	for _, dispute := range chargebacks {
+		if refunded && step.Status != log.Status && decided(dispute).After(updated) {
+			step.Status = log.Status
+			if _, err := l.Apply(step, updated); err != nil {
+				return err
+			}
+		}
+		step.ChargedBack += dispute.Amount
+		if _, err := l.Apply(step, decided(dispute)); err != nil {
+			return err
+		}
+	}
+	_, err := l.Apply(log, updated)
+	return err
+}
+
+// decided returns when a dispute was decided.
+func decided(dispute paymentlog.Dispute) time.Time {
+	if dispute.Updated.IsZero() {
+		return dispute.Created
+	}
+	return dispute.Updated
+}
+
+type byDecision []paymentlog.Dispute
+
+func (b byDecision) Len() int {
+	return len(b)
+}
+
+func (b byDecision) Swap(i, j int) {
+	b[i], b[j] = b[j], b[i]
+}
+
+func (b byDecision) Less(i, j int) bool {
+	if decided(b[i]).Equal(decided(b[j])) {
+		return b[i].ID < b[j].ID
+	}
+	return decided(b[i]).Before(decided(b[j]))
+}
+
+// Run applies payment logs and payouts as they're stored, until sub is
+// closed. Chargebacks are dated when their dispute was decided.
+func (l *Ledger) Run(sub *paymentlog.Subscription) error {
+	for event := range sub.Events() {
+		if event.Payout != nil {
+			if _, err := l.ApplyPayout(*event.Payout); err != nil {
+				return err
+			}
+			continue
+		}
+		if event.PaymentLog == nil || event.Type == paymentlog.EventPaymentLogDeleted {
+			continue
+		}
+		var at time.Time
+		if event.Dispute != nil {
+			at = decided(*event.Dispute)
+		}
+		if _, err := l.Apply(*event.PaymentLog, at); err != nil {
+			return err
+		}
+	}
+	return sub.Err()
+}
+
+// Entries returns the entries dated at or after since and before until,
+// oldest first. A zero since or until leaves that end of the range open.
+func (l *Ledger) Entries(since, until time.Time) []Entry {
+	l.Lock()
+	defer l.Unlock()
+	results := []Entry{}
+	for _, entry := range l.entries {
+		if !since.IsZero() && entry.Time.Before(since) {
+			continue
+		}
+		if !until.IsZero() && !entry.Time.Before(until) {
+			break
+		}
+		results = append(results, entry)
+	}
+	return results
+}
+
+// Balance returns an account's balance in currency as of at, counting
+// entries dated at or before it. A zero at counts every entry.
+func (l *Ledger) Balance(account, currency string, at time.Time) int64 {
+	l.Lock()
+	defer l.Unlock()
+	var balance int64
+	for _, entry := range l.entries {
+		if !at.IsZero() && entry.Time.After(at) {
+			break
+		}
+		if entry.Currency != currency {
+			continue
+		}
+		for _, posting := range entry.Postings {
+			if posting.Account == account {
+				balance += posting.Amount
+			}
+		}
+	}
+	return balance
+}
+
+type AccountBalance struct {
+	Account  string `json:"account"`
+	Currency string `json:"currency"`
+	Balance  int64  `json:"balance"`
+}
+
+// Balances returns every account's balance as of at, sorted by account and
+// then currency. Since every entry balances, the balances in each currency
+// sum to zero.
+func (l *Ledger) Balances(at time.Time) []AccountBalance {
+	l.Lock()
+	defer l.Unlock()
+	balances := map[[2]string]int64{}
+	for _, entry := range l.entries {
+		if !at.IsZero() && entry.Time.After(at) {
+			break
+		}
+		for _, posting := range entry.Postings {
+			balances[[2]string{posting.Account, entry.Currency}] += posting.Amount
+		}
+	}
+	results := make([]AccountBalance, 0, len(balances))
+	for key, balance := range balances {
+		results = append(results, AccountBalance{Account: key[0], Currency: key[1], Balance: balance})
+	}
+	sort.Sort(byAccount(results))
+	return results
+}
+
+type byAccount []AccountBalance
+
+func (b byAccount) Len() int {
+	return len(b)
+}
+
+func (b byAccount) Swap(i, j int) {
+	b[i], b[j] = b[j], b[i]
+}
+
+func (b byAccount) Less(i, j int) bool {
+	if b[i].Account == b[j].Account {
+		return b[i].Currency < b[j].Currency
+	}
+	return b[i].Account < b[j].Account
+}
@@ -0,0 +1,292 @@
+package ledger
+
+import (
+	"testing"
+	"time"
+
+	"code.whipround.net/paymentlog"
+)
+
+var start = time.Date(2014, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+func testPaymentLog(id string, amount uint, status string) paymentlog.PaymentLog {
+	return paymentlog.PaymentLog{
+		ID:          id,
+		Amount:      amount,
+		Source:      paymentlog.SourceBalanced,
+		SourceID:    id,
+		Created:     start,
+		Status:      status,
+		Currency:    paymentlog.CurrencyUSD,
+		ProjectID:   "project-id",
+		UserID:      "user-id",
+		AccountID:   "account-id",
+		AccountType: "google",
+	}
+}
+
+func checkBalances(t *testing.T, l *Ledger, at time.Time, expectations map[string]int64) {
+	var sum int64
+	for _, balance := range l.Balances(at) {
+		sum += balance.Balance
+	}
+	if sum != 0 {
+		t.Errorf("Expected balances at %s to sum to zero, got %d.", at, sum)
+	}
+	for account, expectation := range expectations {
+		if balance := l.Balance(account, paymentlog.CurrencyUSD, at); balance != expectation {
+			t.Errorf("Expected %s to be %d at %s, got %d.", account, expectation, at, balance)
+		}
+	}
+}
+
+func TestEntriesMustBalance(t *testing.T) {
+	l := New()
+	entries := map[error]Entry{
+		EmptyEntry:      {ID: "empty", Currency: paymentlog.CurrencyUSD},
+		MissingCurrency: {ID: "no-currency", Postings: []Posting{{"a", 1}, {"b", -1}}},
+		Unbalanced:      {ID: "unbalanced", Currency: paymentlog.CurrencyUSD, Postings: []Posting{{"a", 2}, {"b", -1}}},
+	}
+	for expectation, entry := range entries {
+		if err := l.Record(entry); err != expectation {
+			t.Errorf("Expected %s recording %+v, got %v.", expectation, entry, err)
+		}
+	}
+	entry := Entry{ID: "ok", Currency: paymentlog.CurrencyUSD, Time: start, Postings: []Posting{{"a", 5}, {"b", -3}, {"c", -2}}}
+	if err := l.Record(entry); err != nil {
+		t.Errorf("Error recording entry: %s", err)
+	}
+	if err := l.Record(entry); err != AlreadyRecorded {
+		t.Errorf("Expected %s, got %v.", AlreadyRecorded, err)
+	}
+}
+
+func TestPaymentLifecycle(t *testing.T) {
+	l := New()
+	user, escrow := UserAccount("user-id"), ProjectEscrow("project-id")
+	clearing := ProcessorClearing(paymentlog.SourceBalanced)
+
+	log := testPaymentLog("p1", 1000, paymentlog.StatusPending)
+	if entries, err := l.Apply(log, time.Time{}); err != nil || len(entries) != 0 {
+		t.Errorf("Expected no entries for a pending payment, got %+v, %v.", entries, err)
+	}
+	log.Status, log.Updated = paymentlog.StatusSucceeded, start.Add(time.Hour)
+	log.ProcessorFee, log.PlatformFee = 59, 50
+	entries, err := l.Apply(log, time.Time{})
+	if err != nil {
+		t.Fatalf("Error applying payment log: %s", err)
+	}
+	if len(entries) != 3 || entries[0].Type != EntryChargeCaptured || entries[1].Type != EntryContribution || entries[2].Type != EntryFeeTaken {
+		t.Errorf("Unexpected entries: %+v", entries)
+	}
+	if entries, _ := l.Apply(log, time.Time{}); len(entries) != 0 {
+		t.Errorf("Expected applying the same state again to record nothing, got %+v.", entries)
+	}
+	captured := start.Add(time.Hour)
+	checkBalances(t, l, captured, map[string]int64{
+		clearing:        941,
+		user:            0,
+		escrow:          -891,
+		PlatformRevenue: -50,
+	})
+
+	payout := paymentlog.Payout{ID: "po1", ProjectID: "project-id", Currency: paymentlog.CurrencyUSD, Amount: 891, PaymentLogIDs: []string{"p1"}, Created: start.Add(2 * time.Hour)}
+	if _, err := l.ApplyPayout(payout); err != nil {
+		t.Fatalf("Error applying payout: %s", err)
+	}
+	if _, err := l.ApplyPayout(paymentlog.Payout{ID: "po2", PaymentLogIDs: []string{"p2"}}); err != UnknownPayment {
+		t.Errorf("Expected %s, got %v.", UnknownPayment, err)
+	}
+	log.Status, log.Updated = paymentlog.StatusRefunded, start.Add(3*time.Hour)
+	if _, err := l.Apply(log, time.Time{}); err != nil {
+		t.Fatalf("Error applying payment log: %s", err)
+	}
+	checkBalances(t, l, time.Time{}, map[string]int64{
+		clearing:        -950,
+		user:            0,
+		escrow:          1000,
+		PlatformRevenue: -50,
+	})
+	// balances as of earlier points in time are unaffected
+	checkBalances(t, l, captured, map[string]int64{clearing: 941, escrow: -891})
+	checkBalances(t, l, start.Add(2*time.Hour), map[string]int64{clearing: 50, escrow: 0})
+	checkBalances(t, l, start, map[string]int64{clearing: 0, escrow: 0})
+
+	if entries := l.Entries(start.Add(2*time.Hour), start.Add(3*time.Hour)); len(entries) != 1 || entries[0].Type != EntryPayoutMade {
+		t.Errorf("Expected the payout, got %+v.", entries)
+	}
+}
+
+func TestRefundAfterChargeback(t *testing.T) {
+	l := New()
+	escrow, clearing := ProjectEscrow("project-id"), ProcessorClearing(paymentlog.SourceBalanced)
+	log := testPaymentLog("p1", 1000, paymentlog.StatusSucceeded)
+	if _, err := l.Apply(log, time.Time{}); err != nil {
+		t.Fatalf("Error applying payment log: %s", err)
+	}
+	log.ChargedBack, log.Updated = 400, start.Add(time.Hour)
+	if _, err := l.Apply(log, time.Time{}); err != nil {
+		t.Fatalf("Error applying payment log: %s", err)
+	}
+	log.Status, log.Updated = paymentlog.StatusRefunded, start.Add(2*time.Hour)
+	if _, err := l.Apply(log, time.Time{}); err != nil {
+		t.Fatalf("Error applying payment log: %s", err)
+	}
+	checkBalances(t, l, time.Time{}, map[string]int64{clearing: 0, escrow: 0})
+
+	// a chargeback after a refund has nothing left to return
+	log = testPaymentLog("p2", 1000, paymentlog.StatusRefunded)
+	if _, err := l.Apply(log, time.Time{}); err != nil {
+		t.Fatalf("Error applying payment log: %s", err)
+	}
+	log.ChargedBack = 400
+	if entries, err := l.Apply(log, time.Time{}); err != nil || len(entries) != 0 {
+		t.Errorf("Expected no entries for a chargeback after a refund, got %+v, %v.", entries, err)
+	}
+	checkBalances(t, l, time.Time{}, map[string]int64{clearing: 0, escrow: 0})
+}
+
+func TestAdjustingCapturedAmount(t *testing.T) {
+	l := New()
+	escrow, clearing := ProjectEscrow("project-id"), ProcessorClearing(paymentlog.SourceBalanced)
+	log := testPaymentLog("p1", 1000, paymentlog.StatusSucceeded)
+	if _, err := l.Apply(log, time.Time{}); err != nil {
+		t.Fatalf("Error applying payment log: %s", err)
+	}
+	log.Amount = 950
+	entries, err := l.Apply(log, time.Time{})
+	if err != nil || len(entries) != 1 || entries[0].Type != EntryChargeAdjusted {
+		t.Fatalf("Expected an adjustment, got %+v, %v.", entries, err)
+	}
+	checkBalances(t, l, time.Time{}, map[string]int64{clearing: 950, escrow: -950})
+	log.Status = paymentlog.StatusRefunded
+	if _, err := l.Apply(log, time.Time{}); err != nil {
+		t.Fatalf("Error applying payment log: %s", err)
+	}
+	checkBalances(t, l, time.Time{}, map[string]int64{clearing: 0, escrow: 0})
+}
+
+func TestLedgerFollowsStore(t *testing.T) {
+	store := paymentlog.NewMemoryStore()
+	if err := store.StorePaymentLog(testPaymentLog("p1", 1000, paymentlog.StatusSucceeded)); err != nil {
+		t.Fatalf("Error storing payment log: %s", err)
+	}
+	l := New()
+	if err := l.Replay(store); err != nil {
+		t.Fatalf("Error replaying store: %s", err)
+	}
+	sub, err := store.Subscribe(1, 10)
+	if err != nil {
+		t.Fatalf("Error subscribing: %s", err)
+	}
+	done := make(chan error)
+	go func() {
+		done <- l.Run(sub)
+	}()
+	if err := store.StorePaymentLog(testPaymentLog("p2", 500, paymentlog.StatusSucceeded)); err != nil {
+		t.Fatalf("Error storing payment log: %s", err)
+	}
+	decided := start.Add(48 * time.Hour)
+	dispute := paymentlog.Dispute{ID: "dp1", PaymentLogID: "p1", Amount: 400, Outcome: paymentlog.DisputeLost, Created: start.Add(24 * time.Hour), Updated: decided}
+	if err := store.StoreDispute(dispute); err != nil {
+		t.Fatalf("Error storing dispute: %s", err)
+	}
+	payout := paymentlog.Payout{ID: "po1", ProjectID: "project-id", Currency: paymentlog.CurrencyUSD, PaymentLogIDs: []string{"p2"}, Created: decided.Add(time.Hour)}
+	if err := store.StorePayout(payout); err != nil {
+		t.Fatalf("Error storing payout: %s", err)
+	}
+	escrow := ProjectEscrow("project-id")
+	deadline := time.Now().Add(time.Second)
+	for l.Balance(escrow, paymentlog.CurrencyUSD, time.Time{}) != -600 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected escrow to reach -600, got %d.", l.Balance(escrow, paymentlog.CurrencyUSD, time.Time{}))
+		}
+		time.Sleep(time.Millisecond)
+	}
+	sub.Close()
+	if err := <-done; err != nil {
+		t.Errorf("Expected Run to stop cleanly, got %s.", err)
+	}
+	if balance := l.Balance(escrow, paymentlog.CurrencyUSD, decided.Add(-time.Second)); balance != -1500 {
+		t.Errorf("Expected the chargeback to be dated when the dispute was decided, got a balance of %d before it.", balance)
+	}
+}
+
+func TestReplayingPayoutBeforeChargeback(t *testing.T) {
+	store := paymentlog.NewMemoryStore()
+	live := New()
+	sub, err := store.Subscribe(0, 10)
+	if err != nil {
+		t.Fatalf("Error subscribing: %s", err)
+	}
+	done := make(chan error)
+	go func() {
+		done <- live.Run(sub)
+	}()
+	if err := store.StorePaymentLog(testPaymentLog("p1", 1000, paymentlog.StatusSucceeded)); err != nil {
+		t.Fatalf("Error storing payment log: %s", err)
+	}
+	payout := paymentlog.Payout{ID: "po1", ProjectID: "project-id", Currency: paymentlog.CurrencyUSD, PaymentLogIDs: []string{"p1"}, Created: start.Add(time.Hour)}
+	if err := store.StorePayout(payout); err != nil {
+		t.Fatalf("Error storing payout: %s", err)
+	}
+	dispute := paymentlog.Dispute{ID: "dp1", PaymentLogID: "p1", Amount: 400, Outcome: paymentlog.DisputeLost, Created: start.Add(2 * time.Hour)}
+	if err := store.StoreDispute(dispute); err != nil {
+		t.Fatalf("Error storing dispute: %s", err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for len(live.Entries(time.Time{}, time.Time{})) < 5 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected 5 entries, got %+v.", live.Entries(time.Time{}, time.Time{}))
+		}
+		time.Sleep(time.Millisecond)
+	}
+	sub.Close()
+	if err := <-done; err != nil {
+		t.Errorf("Expected Run to stop cleanly, got %s.", err)
+	}
+
+	replayed := New()
+	if err := replayed.Replay(store); err != nil {
+		t.Fatalf("Error replaying store: %s", err)
+	}
+	escrow, clearing := ProjectEscrow("project-id"), ProcessorClearing(paymentlog.SourceBalanced)
+	for name, l := range map[string]*Ledger{"live": live, "replayed": replayed} {
+		checkBalances(t, l, time.Time{}, map[string]int64{escrow: 400, clearing: -400})
+		payouts := l.Entries(start.Add(time.Hour), start.Add(time.Hour+time.Second))
+		if len(payouts) != 1 || payouts[0].Postings[0].Amount != 1000 {
+			t.Errorf("%s: expected a payout of 1000, got %+v.", name, payouts)
+		}
+	}
+}
+
+func TestReplayDatesEntries(t *testing.T) {
+	store := paymentlog.NewMemoryStore()
+	charged := testPaymentLog("p1", 1000, paymentlog.StatusSucceeded)
+	charged.Updated = start.Add(time.Hour)
+	refunded := testPaymentLog("p2", 500, paymentlog.StatusRefunded)
+	refunded.Updated = start.Add(72 * time.Hour)
+	for _, log := range []paymentlog.PaymentLog{charged, refunded} {
+		if err := store.StorePaymentLog(log); err != nil {
+			t.Fatalf("Error storing payment log: %s", err)
+		}
+	}
+	decided := start.Add(48 * time.Hour)
+	dispute := paymentlog.Dispute{ID: "dp1", PaymentLogID: "p1", Amount: 400, Outcome: paymentlog.DisputeLost, Created: start.Add(24 * time.Hour), Updated: decided}
+	if err := store.StoreDispute(dispute); err != nil {
+		t.Fatalf("Error storing dispute: %s", err)
+	}
+	l := New()
+	if err := l.Replay(store); err != nil {
+		t.Fatalf("Error replaying store: %s", err)
+	}
+	escrow := ProjectEscrow("project-id")
+	checkBalances(t, l, start.Add(time.Hour), map[string]int64{escrow: -1500})
+	checkBalances(t, l, decided, map[string]int64{escrow: -1100})
+	checkBalances(t, l, time.Time{}, map[string]int64{escrow: -600})
+	for _, entry := range l.Entries(time.Time{}, time.Time{}) {
+		if entry.Type == EntryChargeback && !entry.Time.Equal(decided) {
+			t.Errorf("Expected the chargeback to be dated when the dispute was decided, got %s.", entry.Time)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package paymentlog
 
 import (
+	"sort"
 	"sync"
 	"time"
 )
@@ -8,23 +9,106 @@ import (
 type MemoryStore struct {
 	paymentLogs map[string]*PaymentLog
 	failureLogs map[string]*FailureLog
+	retries     map[string]*RetrySchedule
+	disputes    map[string]*Dispute
+	payouts     map[string]*Payout
+	paidOut     map[string]string
+	summaries   map[[2]string]*contributions
+	// created indexes the payment logs oldest first, for
+	// ListPaymentLogsSince.
+	created []createdKey
+	feed    *feed
 	sync.Mutex
 }
 
+type createdKey struct {
+	created time.Time
+	id      string
+}
+
+func (k createdKey) before(other createdKey) bool {
+	if k.created.Equal(other.created) {
+		return k.id < other.id
+	}
+	return k.created.Before(other.created)
+}
+
+func (store *MemoryStore) search(key createdKey) int {
+	return sort.Search(len(store.created), func(i int) bool {
+		return !store.created[i].before(key)
+	})
+}
+
+func (store *MemoryStore) index(log *PaymentLog) {
+	key := createdKey{log.Created, log.ID}
+	pos := store.search(key)
+	store.created = append(store.created, createdKey{})
+	copy(store.created[pos+1:], store.created[pos:])
+	store.created[pos] = key
+}
+
+func (store *MemoryStore) unindex(log *PaymentLog) {
+	if log == nil {
+		return
+	}
+	pos := store.search(createdKey{log.Created, log.ID})
+	if pos < len(store.created) && store.created[pos].id == log.ID {
+		store.created = append(store.created[:pos], store.created[pos+1:]...)
+	}
+}
+
 func NewMemoryStore() *MemoryStore {
 	return &MemoryStore{
 		paymentLogs: make(map[string]*PaymentLog),
 		failureLogs: make(map[string]*FailureLog),
+		retries:     make(map[string]*RetrySchedule),
+		disputes:    make(map[string]*Dispute),
+		payouts:     make(map[string]*Payout),
+		paidOut:     make(map[string]string),
+		summaries:   make(map[[2]string]*contributions),
+		feed:        newFeed(DefaultFeedRetention),
 	}
 }
 
+func (store *MemoryStore) Subscribe(after uint64, buffer int) (*Subscription, error) {
+	return store.feed.subscribe(after, buffer)
+}
+
+func (store *MemoryStore) Head() uint64 {
+	return store.feed.head()
+}
+
+// page returns the bounds of the page of num results at offset out of
+// length results. A num of 0 or less means all of them.
+func page(length, num, offset int) (start, end int) {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset >= length {
+		return length, length
+	}
+	if num <= 0 || num > length-offset {
+		return offset, length
+	}
+	return offset, offset + num
+}
+
 func (store *MemoryStore) StorePaymentLog(log PaymentLog) error {
 	store.Lock()
 	defer store.Unlock()
 	if _, ok := store.paymentLogs[log.ID]; ok {
 		return AlreadyExists
 	}
+	store.chargeBack(&log)
 	store.paymentLogs[log.ID] = &log
+	store.index(&log)
+	store.summarize(nil, &log)
+	created := log
+	store.feed.publish(Event{
+		Type:         EventPaymentLogCreated,
+		PaymentLogID: log.ID,
+		PaymentLog:   &created,
+	})
 	return nil
 }
 
@@ -34,40 +118,81 @@ func (store *MemoryStore) UpdatePaymentLog(id string, change PaymentLogChange) e
 	if _, ok := store.paymentLogs[id]; !ok {
 		return LogNotFound
 	}
+	before := *store.paymentLogs[id]
+	log := before
 	if change.Amount != nil {
-		store.paymentLogs[id].Amount = *change.Amount
+		log.Amount = *change.Amount
 	}
 	if change.Description != nil {
-		store.paymentLogs[id].Description = *change.Description
+		log.Description = *change.Description
 	}
 	if change.Source != nil {
-		store.paymentLogs[id].Source = *change.Source
+		log.Source = *change.Source
 	}
 	if change.SourceID != nil {
-		store.paymentLogs[id].SourceID = *change.SourceID
+		log.SourceID = *change.SourceID
 	}
 	if change.Created != nil {
-		store.paymentLogs[id].Created = *change.Created
+		log.Created = *change.Created
 	}
 	if change.Updated != nil {
-		store.paymentLogs[id].Updated = *change.Updated
+		log.Updated = *change.Updated
 	}
 	if change.Status != nil {
-		store.paymentLogs[id].Status = *change.Status
+		log.Status = *change.Status
 	}
 	if change.Currency != nil {
-		store.paymentLogs[id].Currency = *change.Currency
+		log.Currency = *change.Currency
+	}
+	if change.ProcessorFee != nil {
+		log.ProcessorFee = *change.ProcessorFee
+	}
+	if change.PlatformFee != nil {
+		log.PlatformFee = *change.PlatformFee
+	}
+	feesChanged := change.Amount != nil || change.ProcessorFee != nil || change.PlatformFee != nil
+	if feesChanged && log.Fees() > log.Amount {
+		return FeesExceedAmount
+	}
+	*store.paymentLogs[id] = log
+	if !log.Created.Equal(before.Created) {
+		store.unindex(&before)
+		store.index(&log)
 	}
+	store.summarize(&before, store.paymentLogs[id])
+	updated := *store.paymentLogs[id]
+	store.feed.publish(Event{
+		Type:          EventPaymentLogUpdated,
+		PaymentLogID:  id,
+		PaymentLog:    &updated,
+		Change:        &change,
+		ChangedFields: change.Fields(),
+	})
 	return nil
 }
 
 func (store *MemoryStore) DeletePaymentLog(id string) error {
 	store.Lock()
 	defer store.Unlock()
-	if _, ok := store.paymentLogs[id]; !ok {
+	log, ok := store.paymentLogs[id]
+	if !ok {
 		return LogNotFound
 	}
 	delete(store.paymentLogs, id)
+	store.unindex(log)
+	store.summarize(log, nil)
+	delete(store.retries, id)
+	delete(store.paidOut, id)
+	for disputeID, dispute := range store.disputes {
+		if dispute.PaymentLogID == id {
+			delete(store.disputes, disputeID)
+		}
+	}
+	store.feed.publish(Event{
+		Type:         EventPaymentLogDeleted,
+		PaymentLogID: id,
+		PaymentLog:   log,
+	})
 	return nil
 }
 
@@ -93,7 +218,8 @@ func (store *MemoryStore) ListPaymentLogsByProject(id string, num, offset int) (
 			results = append(results, *log)
 		}
 	}
-	return SortLogsByCreated(results), nil
+	start, end := page(len(results), num, offset)
+	return SortLogsByCreated(results)[start:end], nil
 }
 
 func (store *MemoryStore) ListPaymentLogsByUser(id string, num, offset int) ([]PaymentLog, error) {
@@ -108,7 +234,8 @@ func (store *MemoryStore) ListPaymentLogsByUser(id string, num, offset int) ([]P
 			results = append(results, *log)
 		}
 	}
-	return SortLogsByCreated(results), nil
+	start, end := page(len(results), num, offset)
+	return SortLogsByCreated(results)[start:end], nil
 }
 
 func (store *MemoryStore) ListPaymentLogs(num, offset int) ([]PaymentLog, error) {
@@ -121,6 +248,45 @@ func (store *MemoryStore) ListPaymentLogs(num, offset int) ([]PaymentLog, error)
 		}
 		results = append(results, *log)
 	}
+	start, end := page(len(results), num, offset)
+	return SortLogsByCreated(results)[start:end], nil
+}
+
+func (store *MemoryStore) IteratePaymentLogs(fn func(log PaymentLog) error) error {
+	store.Lock()
+	snapshot := make([]PaymentLog, 0, len(store.paymentLogs))
+	for _, log := range store.paymentLogs {
+		if log == nil {
+			continue
+		}
+		snapshot = append(snapshot, *log)
+	}
+	store.Unlock()
+	for _, log := range SortLogsByCreated(snapshot) {
+		err := fn(log)
+		if err == StopIteration {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListPaymentLogsSince only reads the payment logs created after timestamp.
+func (store *MemoryStore) ListPaymentLogsSince(timestamp time.Time) ([]PaymentLog, error) {
+	store.Lock()
+	defer store.Unlock()
+	pos := sort.Search(len(store.created), func(i int) bool {
+		return store.created[i].created.After(timestamp)
+	})
+	results := make([]PaymentLog, 0, len(store.created)-pos)
+	for _, key := range store.created[pos:] {
+		if log := store.paymentLogs[key.id]; log != nil {
+			results = append(results, *log)
+		}
+	}
 	return SortLogsByCreated(results), nil
 }
 
@@ -130,7 +296,16 @@ func (store *MemoryStore) StoreFailureLog(log FailureLog) error {
 	if _, ok := store.failureLogs[log.ID]; ok {
 		return AlreadyExists
 	}
+	if log.State != "" && !validFailureState(log.State) {
+		return InvalidFailureState
+	}
 	store.failureLogs[log.ID] = &log
+	stored := log
+	store.feed.publish(Event{
+		Type:         EventFailureLogStored,
+		PaymentLogID: log.PaymentLogID,
+		FailureLog:   &stored,
+	})
 	return nil
 }
 
@@ -144,7 +319,8 @@ func (store *MemoryStore) ListFailureLogs(num, offset int) ([]FailureLog, error)
 		}
 		results = append(results, *log)
 	}
-	return SortFailureLogs(results), nil
+	start, end := page(len(results), num, offset)
+	return SortFailureLogs(results)[start:end], nil
 }
 
 func (store *MemoryStore) ListFailureLogsSince(timestamp time.Time) ([]FailureLog, error) {
@@ -162,3 +338,352 @@ func (store *MemoryStore) ListFailureLogsSince(timestamp time.Time) ([]FailureLo
 	}
 	return SortFailureLogs(results), nil
 }
+
+func (store *MemoryStore) IterateFailureLogs(fn func(failure FailureLog) error) error {
+	store.Lock()
+	snapshot := make([]FailureLog, 0, len(store.failureLogs))
+	for _, log := range store.failureLogs {
+		if log == nil {
+			continue
+		}
+		snapshot = append(snapshot, *log)
+	}
+	store.Unlock()
+	for _, log := range SortFailureLogs(snapshot) {
+		err := fn(log)
+		if err == StopIteration {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (store *MemoryStore) UpdateFailureLogState(id string, change FailureStateChange) error {
+	store.Lock()
+	defer store.Unlock()
+	log, ok := store.failureLogs[id]
+	if !ok || log == nil {
+		return FailureLogNotFound
+	}
+	updated, err := log.ApplyStateChange(change)
+	if err != nil {
+		return err
+	}
+	store.failureLogs[id] = &updated
+	stored := updated
+	store.feed.publish(Event{
+		Type:         EventFailureLogUpdated,
+		PaymentLogID: updated.PaymentLogID,
+		FailureLog:   &stored,
+	})
+	return nil
+}
+
+func (store *MemoryStore) ListOpenFailureLogs(num, offset int) ([]FailureLog, error) {
+	store.Lock()
+	defer store.Unlock()
+	results := make([]FailureLog, 0)
+	for _, log := range store.failureLogs {
+		if log == nil || !log.Open() {
+			continue
+		}
+		results = append(results, *log)
+	}
+	sort.Sort(oldestFailures(results))
+	start, end := page(len(results), num, offset)
+	return results[start:end], nil
+}
+
+func (store *MemoryStore) SaveRetrySchedule(schedule RetrySchedule) error {
+	if schedule.PaymentLogID == "" {
+		return MissingID
+	}
+	store.Lock()
+	defer store.Unlock()
+	store.retries[schedule.PaymentLogID] = &schedule
+	return nil
+}
+
+func (store *MemoryStore) GetRetrySchedule(paymentLogID string) (RetrySchedule, error) {
+	store.Lock()
+	defer store.Unlock()
+	schedule, ok := store.retries[paymentLogID]
+	if !ok {
+		return RetrySchedule{}, RetryScheduleNotFound
+	}
+	return *schedule, nil
+}
+
+func (store *MemoryStore) DeleteRetrySchedule(paymentLogID string) error {
+	store.Lock()
+	defer store.Unlock()
+	if _, ok := store.retries[paymentLogID]; !ok {
+		return RetryScheduleNotFound
+	}
+	delete(store.retries, paymentLogID)
+	return nil
+}
+
+func (store *MemoryStore) ListDueRetries(now time.Time, num int) ([]RetrySchedule, error) {
+	store.Lock()
+	defer store.Unlock()
+	results := make([]RetrySchedule, 0)
+	for _, schedule := range store.retries {
+		if schedule.State != RetryScheduled || schedule.NextAttempt.After(now) {
+			continue
+		}
+		results = append(results, *schedule)
+	}
+	sort.Sort(retriesByNextAttempt(results))
+	if num > 0 && num < len(results) {
+		results = results[:num]
+	}
+	return results, nil
+}
+
+func (store *MemoryStore) ClaimRetry(paymentLogID string, now time.Time) (RetrySchedule, error) {
+	store.Lock()
+	defer store.Unlock()
+	schedule, ok := store.retries[paymentLogID]
+	if !ok {
+		return RetrySchedule{}, RetryScheduleNotFound
+	}
+	if schedule.State != RetryScheduled || schedule.NextAttempt.After(now) {
+		return *schedule, RetryNotDue
+	}
+	schedule.State = RetryInProgress
+	return *schedule, nil
+}
+
+// chargeBack brings the ChargedBack amount of a payment log in line with its
+// disputes. The store must be locked.
+func (store *MemoryStore) chargeBack(log *PaymentLog) {
+	var charged uint
+	for _, dispute := range store.disputes {
+		if dispute.PaymentLogID == log.ID && dispute.ChargedBack() {
+			charged += dispute.Amount
+		}
+	}
+	log.ChargedBack = charged
+}
+
+func (store *MemoryStore) StoreDispute(dispute Dispute) error {
+	if err := dispute.Validate(); err != nil {
+		return err
+	}
+	store.Lock()
+	defer store.Unlock()
+	if _, ok := store.disputes[dispute.ID]; ok {
+		return DisputeAlreadyExists
+	}
+	log, ok := store.paymentLogs[dispute.PaymentLogID]
+	if !ok || log == nil {
+		return LogNotFound
+	}
+	if dispute.Amount > log.Amount {
+		return DisputeExceedsPayment
+	}
+	store.disputes[dispute.ID] = &dispute
+	store.chargeBack(log)
+	stored, updated := dispute, *log
+	store.feed.publish(Event{
+		Type:         EventDisputeStored,
+		PaymentLogID: log.ID,
+		PaymentLog:   &updated,
+		Dispute:      &stored,
+	})
+	return nil
+}
+
+func (store *MemoryStore) UpdateDispute(id string, change DisputeChange) error {
+	store.Lock()
+	defer store.Unlock()
+	dispute, ok := store.disputes[id]
+	if !ok || dispute == nil {
+		return DisputeNotFound
+	}
+	changed := change.apply(*dispute)
+	if err := changed.Validate(); err != nil {
+		return err
+	}
+	log, ok := store.paymentLogs[changed.PaymentLogID]
+	if !ok || log == nil {
+		return LogNotFound
+	}
+	if changed.Amount > log.Amount {
+		return DisputeExceedsPayment
+	}
+	store.disputes[id] = &changed
+	store.chargeBack(log)
+	stored, updated := changed, *log
+	store.feed.publish(Event{
+		Type:         EventDisputeUpdated,
+		PaymentLogID: log.ID,
+		PaymentLog:   &updated,
+		Dispute:      &stored,
+	})
+	return nil
+}
+
+func (store *MemoryStore) GetDispute(id string) (Dispute, error) {
+	store.Lock()
+	defer store.Unlock()
+	dispute, ok := store.disputes[id]
+	if !ok || dispute == nil {
+		return Dispute{}, DisputeNotFound
+	}
+	return *dispute, nil
+}
+
+func (store *MemoryStore) ListDisputes(num, offset int) ([]Dispute, error) {
+	store.Lock()
+	defer store.Unlock()
+	results := make([]Dispute, 0, len(store.disputes))
+	for _, dispute := range store.disputes {
+		results = append(results, *dispute)
+	}
+	sort.Sort(sortedDisputes(results))
+	start, end := page(len(results), num, offset)
+	return results[start:end], nil
+}
+
+func (store *MemoryStore) ListDisputesByPaymentLog(paymentLogID string) ([]Dispute, error) {
+	store.Lock()
+	defer store.Unlock()
+	results := make([]Dispute, 0)
+	for _, dispute := range store.disputes {
+		if dispute.PaymentLogID == paymentLogID {
+			results = append(results, *dispute)
+		}
+	}
+	sort.Sort(sortedDisputes(results))
+	return results, nil
+}
+
+func (store *MemoryStore) StorePayout(payout Payout) error {
+	if err := payout.Validate(); err != nil {
+		return err
+	}
+	store.Lock()
+	defer store.Unlock()
+	if _, ok := store.payouts[payout.ID]; ok {
+		return PayoutAlreadyExists
+	}
+	payout.Amount, payout.Sources = 0, map[string]uint{}
+	seen := map[string]bool{}
+	for _, id := range payout.PaymentLogIDs {
+		log, ok := store.paymentLogs[id]
+		if !ok || log == nil {
+			return LogNotFound
+		}
+		if !payout.Payable(*log) {
+			return NotPayable
+		}
+		if _, ok := store.paidOut[id]; ok || seen[id] {
+			return AlreadyPaidOut
+		}
+		seen[id] = true
+		payout.Amount += log.NetAmount()
+		payout.Sources[log.Source] += log.NetAmount()
+	}
+	payout.PaymentLogIDs = append([]string(nil), payout.PaymentLogIDs...)
+	store.payouts[payout.ID] = &payout
+	for _, id := range payout.PaymentLogIDs {
+		store.paidOut[id] = payout.ID
+	}
+	stored := payout
+	store.feed.publish(Event{
+		Type:   EventPayoutStored,
+		Payout: &stored,
+	})
+	return nil
+}
+
+func (store *MemoryStore) GetPayout(id string) (Payout, error) {
+	store.Lock()
+	defer store.Unlock()
+	payout, ok := store.payouts[id]
+	if !ok || payout == nil {
+		return Payout{}, PayoutNotFound
+	}
+	return *payout, nil
+}
+
+func (store *MemoryStore) GetPayoutByPaymentLog(paymentLogID string) (Payout, error) {
+	store.Lock()
+	defer store.Unlock()
+	id, ok := store.paidOut[paymentLogID]
+	if !ok {
+		return Payout{}, PayoutNotFound
+	}
+	return *store.payouts[id], nil
+}
+
+func (store *MemoryStore) ListPayouts(projectID string, num, offset int) ([]Payout, error) {
+	store.Lock()
+	defer store.Unlock()
+	results := make([]Payout, 0)
+	for _, payout := range store.payouts {
+		if payout.ProjectID == projectID {
+			results = append(results, *payout)
+		}
+	}
+	sort.Sort(sortedPayouts(results))
+	start, end := page(len(results), num, offset)
+	return results[start:end], nil
+}
+
+func (store *MemoryStore) UnpaidBalances(projectID string) (map[string]uint, error) {
+	store.Lock()
+	defer store.Unlock()
+	balances := map[string]uint{}
+	for _, log := range store.paymentLogs {
+		if log == nil || log.ProjectID != projectID || log.Status != StatusSucceeded {
+			continue
+		}
+		if _, ok := store.paidOut[log.ID]; ok {
+			continue
+		}
+		balances[log.Currency] += log.NetAmount()
+	}
+	return balances, nil
+}
+
+// summarize keeps project summaries up to date as a payment log changes
+// from before to after. Either may be nil.
+func (store *MemoryStore) summarize(before, after *PaymentLog) {
+	if contributes(before) {
+		key := [2]string{before.ProjectID, before.Currency}
+		if c, ok := store.summaries[key]; ok {
+			c.remove(*before)
+			if c.summary.Contributions == 0 {
+				delete(store.summaries, key)
+			}
+		}
+	}
+	if contributes(after) {
+		key := [2]string{after.ProjectID, after.Currency}
+		c, ok := store.summaries[key]
+		if !ok {
+			c = &contributions{
+				summary: ProjectSummary{ProjectID: after.ProjectID, Currency: after.Currency},
+				logs:    make(map[string]PaymentLog),
+				users:   make(map[string]int),
+			}
+			store.summaries[key] = c
+		}
+		c.add(*after)
+	}
+}
+
+func (store *MemoryStore) GetProjectSummary(projectID, currency string) (ProjectSummary, error) {
+	store.Lock()
+	defer store.Unlock()
+	if c, ok := store.summaries[[2]string{projectID, currency}]; ok {
+		return c.summary, nil
+	}
+	return ProjectSummary{ProjectID: projectID, Currency: currency}, nil
+}
@@ -1,6 +1,10 @@
 package paymentlog
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
 	"testing"
 	"time"
 )
@@ -45,6 +49,15 @@ func comparePaymentLogs(expectation, result PaymentLog) (success bool, field str
 	if expectation.AccountType != result.AccountType {
 		return false, "account type", expectation.AccountType, result.AccountType
 	}
+	if expectation.ProcessorFee != result.ProcessorFee {
+		return false, "processor fee", expectation.ProcessorFee, result.ProcessorFee
+	}
+	if expectation.PlatformFee != result.PlatformFee {
+		return false, "platform fee", expectation.PlatformFee, result.PlatformFee
+	}
+	if expectation.ChargedBack != result.ChargedBack {
+		return false, "charged back", expectation.ChargedBack, result.ChargedBack
+	}
 	return true, "", nil, nil
 }
 
@@ -55,6 +68,9 @@ func compareFailureLogs(expectation, result FailureLog) (success bool, field str
 	if expectation.PaymentLogID != result.PaymentLogID {
 		return false, "payment log id", expectation.PaymentLogID, result.PaymentLogID
 	}
+	if expectation.Source != result.Source {
+		return false, "source", expectation.Source, result.Source
+	}
 	if expectation.FailureReason != result.FailureReason {
 		return false, "failure reason", expectation.FailureReason, result.FailureReason
 	}
@@ -552,6 +568,69 @@ func TestListingPaymentLogs(t *testing.T) {
 	}
 }
 
+func TestPaginatingPaymentLogsInMemory(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Now()
+	for pos, id := range []string{"id1", "id2", "id3", "id4", "id5"} {
+		store.paymentLogs[id] = &PaymentLog{ID: id, Created: now.Add(time.Duration(pos) * time.Hour)}
+	}
+	results, err := store.ListPaymentLogs(2, 1)
+	if err != nil {
+		t.Errorf("Error listing payment logs: %s", err)
+	}
+	if len(results) != 2 || results[0].ID != "id4" || results[1].ID != "id3" {
+		t.Errorf("Expected [id4 id3], got %+v.", results)
+	}
+	results, err = store.ListPaymentLogs(2, 4)
+	if err != nil {
+		t.Errorf("Error listing payment logs: %s", err)
+	}
+	if len(results) != 1 || results[0].ID != "id1" {
+		t.Errorf("Expected [id1], got %+v.", results)
+	}
+	results, err = store.ListPaymentLogs(2, 5)
+	if err != nil {
+		t.Errorf("Error listing payment logs: %s", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("Expected no payment logs past the end, got %+v.", results)
+	}
+}
+
+func TestPaginatingTiedPaymentLogsInMemory(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Now()
+	for i := 0; i < 45; i++ {
+		id := fmt.Sprintf("id%02d", i)
+		store.paymentLogs[id] = &PaymentLog{ID: id, Created: now}
+		store.failureLogs[id] = &FailureLog{ID: id, Timestamp: now}
+	}
+	seen, failures := map[string]bool{}, map[string]bool{}
+	for offset := 0; offset < 45; offset += 7 {
+		logs, err := store.ListPaymentLogs(7, offset)
+		if err != nil {
+			t.Fatalf("Error listing payment logs: %s", err)
+		}
+		for _, log := range logs {
+			seen[log.ID] = true
+		}
+		failureLogs, err := store.ListFailureLogs(7, offset)
+		if err != nil {
+			t.Fatalf("Error listing failure logs: %s", err)
+		}
+		for _, log := range failureLogs {
+			failures[log.ID] = true
+		}
+	}
+	if len(seen) != 45 || len(failures) != 45 {
+		t.Errorf("Expected pages to cover all 45 logs, got %d payment logs and %d failure logs.", len(seen), len(failures))
+	}
+	logs, _ := store.ListPaymentLogs(2, 0)
+	if len(logs) != 2 || logs[0].ID != "id00" || logs[1].ID != "id01" {
+		t.Errorf("Expected ties to be ordered by ID, got %+v.", logs)
+	}
+}
+
 func TestStoringFailureLogInMemory(t *testing.T) {
 	store := NewMemoryStore()
 	f := FailureLog{
@@ -693,8 +772,202 @@ func TestListingFailureLogsSince(t *testing.T) {
 	}
 }
 
+func TestListingPaymentLogsSince(t *testing.T) {
+	store := storeTestPayments(t,
+		testPayment{id: "p1"},
+		testPayment{id: "p2"},
+		testPayment{id: "p3"},
+		testPayment{id: "p4"},
+	)
+	// p1 was created at testPaymentsCreated, and each payment an hour later
+	since := testPaymentsCreated.Add(time.Hour)
+	expectIDs := func(expectation ...string) {
+		results, err := store.ListPaymentLogsSince(since)
+		if err != nil {
+			t.Fatalf("Error listing payment logs: %s", err)
+		}
+		ids := []string{}
+		for _, log := range results {
+			ids = append(ids, log.ID)
+		}
+		if strings.Join(ids, ",") != strings.Join(expectation, ",") {
+			t.Errorf("Expected %v, got %v.", expectation, ids)
+		}
+	}
+	expectIDs("p4", "p3")
+
+	moved := testPaymentsCreated.Add(5 * time.Hour)
+	if err := store.UpdatePaymentLog("p1", PaymentLogChange{Created: &moved}); err != nil {
+		t.Fatalf("Error updating payment log: %s", err)
+	}
+	if err := store.DeletePaymentLog("p3"); err != nil {
+		t.Fatalf("Error deleting payment log: %s", err)
+	}
+	expectIDs("p1", "p4")
+
+	var buf bytes.Buffer
+	if err := store.Snapshot(&buf); err != nil {
+		t.Fatalf("Error snapshotting memory store: %s", err)
+	}
+	loaded, err := LoadMemoryStore(&buf)
+	if err != nil {
+		t.Fatalf("Error loading memory store: %s", err)
+	}
+	store = loaded
+	expectIDs("p1", "p4")
+}
+
+func TestIteratingPaymentLogsInMemory(t *testing.T) {
+	store := NewMemoryStore()
+	logs := []PaymentLog{
+		PaymentLog{
+			ID:          "test-payment-log 1",
+			Amount:      1,
+			Source:      SourceBalanced,
+			SourceID:    "balanced-id",
+			Created:     time.Now(),
+			Status:      StatusPending,
+			Currency:    CurrencyUSD,
+			ProjectID:   "project-id",
+			UserID:      "user-id",
+			AccountID:   "account-id",
+			AccountType: "google",
+		}, PaymentLog{
+			ID:          "test-payment-log 2",
+			Amount:      1,
+			Source:      SourceBalanced,
+			SourceID:    "balanced-id",
+			Created:     time.Now().Add(time.Hour),
+			Status:      StatusPending,
+			Currency:    CurrencyUSD,
+			ProjectID:   "project-id",
+			UserID:      "other-user-id",
+			AccountID:   "account-id",
+			AccountType: "google",
+		}, PaymentLog{
+			ID:          "test-payment-log 3",
+			Amount:      1,
+			Source:      SourceBalanced,
+			SourceID:    "balanced-id",
+			Created:     time.Now().Add(time.Hour * 2),
+			Status:      StatusPending,
+			Currency:    CurrencyUSD,
+			ProjectID:   "other-project-id",
+			UserID:      "other-user-id",
+			AccountID:   "account-id",
+			AccountType: "google",
+		},
+	}
+	for pos, _ := range logs {
+		log := logs[pos]
+		store.paymentLogs[log.ID] = &log
+	}
+	logs = SortLogsByCreated(logs)
+	results := make([]PaymentLog, 0)
+	err := store.IteratePaymentLogs(func(log PaymentLog) error {
+		results = append(results, log)
+		// writers must not be blocked while we iterate
+		return store.StorePaymentLog(PaymentLog{ID: "written during iteration " + log.ID})
+	})
+	if err != nil {
+		t.Errorf("Error iterating payment logs: %s", err)
+	}
+	if len(results) != len(logs) {
+		t.Logf("Log results: %+v", results)
+		t.Logf("Log expectation: %+v", logs)
+		t.Errorf("Expected %d payment logs, got %d.", len(logs), len(results))
+	}
+	for pos, _ := range results {
+		success, field, expectation, result := comparePaymentLogs(logs[pos], results[pos])
+		if !success {
+			t.Errorf("Expected result %d %s to be %+v, got %+v.", pos, field, expectation, result)
+		}
+	}
+}
+
+func TestStoppingPaymentLogIterationInMemory(t *testing.T) {
+	store := NewMemoryStore()
+	for _, id := range []string{"id1", "id2", "id3"} {
+		store.paymentLogs[id] = &PaymentLog{ID: id, Created: time.Now()}
+	}
+	seen := 0
+	err := store.IteratePaymentLogs(func(log PaymentLog) error {
+		seen++
+		return StopIteration
+	})
+	if err != nil {
+		t.Errorf("Expected no error when stopping iteration, got %s.", err)
+	}
+	if seen != 1 {
+		t.Errorf("Expected iteration to stop after 1 payment log, saw %d.", seen)
+	}
+	expected := errors.New("callback error")
+	err = store.IteratePaymentLogs(func(log PaymentLog) error {
+		return expected
+	})
+	if err != expected {
+		t.Errorf("Expected %s from iteration, got %v.", expected, err)
+	}
+}
+
+func TestIteratingFailureLogsInMemory(t *testing.T) {
+	store := NewMemoryStore()
+	logs := []FailureLog{
+		FailureLog{
+			ID:                "id1",
+			PaymentLogID:      "payment-log-1",
+			FailureReason:     "you-screwed-up",
+			FailureReasonCode: "500",
+			Timestamp:         time.Now(),
+		},
+		FailureLog{
+			ID:                "id2",
+			PaymentLogID:      "payment-log-2",
+			FailureReason:     "you-screwed-up",
+			FailureReasonCode: "500",
+			Timestamp:         time.Now().Add(time.Hour),
+		},
+		FailureLog{
+			ID:                "id3",
+			PaymentLogID:      "payment-log-3",
+			FailureReason:     "you-screwed-up",
+			FailureReasonCode: "500",
+			Timestamp:         time.Now().Add(time.Minute),
+		},
+	}
+	for pos, _ := range logs {
+		log := logs[pos]
+		store.failureLogs[log.ID] = &log
+	}
+	logs = SortFailureLogs(logs)
+	results := make([]FailureLog, 0)
+	err := store.IterateFailureLogs(func(failure FailureLog) error {
+		results = append(results, failure)
+		if len(results) == 2 {
+			return StopIteration
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("Error iterating failure logs: %s", err)
+	}
+	if len(results) != 2 {
+		t.Errorf("Expected 2 failure logs, got %d.", len(results))
+	}
+	for pos, _ := range results {
+		success, field, expectation, result := compareFailureLogs(logs[pos], results[pos])
+		if !success {
+			t.Errorf("Expected result %d %s to be %+v, got %+v.", pos, field, expectation, result)
+		}
+	}
+}
+
 func TestMemstoreIsALogStore(t *testing.T) {
 	// this should refuse to compile if MemoryStore doesn't implement LogStore
 	var stores []LogStore
 	stores = append(stores, NewMemoryStore())
+	var windowed []PaymentLogsSinceStore
+	windowed = append(windowed, NewMemoryStore())
+	var states []FailureStateStore
+	states = append(states, NewMemoryStore(), Wrap(NewMemoryStore()).(FailureStateStore))
 }
@@ -0,0 +1,178 @@
+package paymentlog
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var DefaultLatencyBuckets = []float64{0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5}
+
+var errorLabels = map[error]string{
+	MissingID:          "missing_id",
+	MissingAmount:      "missing_amount",
+	MissingSource:      "missing_source",
+	MissingSourceID:    "missing_source_id",
+	MissingCreated:     "missing_created",
+	MissingStatus:      "missing_status",
+	MissingCurrency:    "missing_currency",
+	MissingProjectID:   "missing_project_id",
+	MissingUserID:      "missing_user_id",
+	MissingAccountType: "missing_account_type",
+	MissingAccountID:   "missing_account_id",
+	AlreadyExists:      "already_exists",
+	LogNotFound:        "not_found",
+	FeesExceedAmount:   "fees_exceed_amount",
+
+	FailureLogNotFound:       "failure_log_not_found",
+	InvalidFailureState:      "invalid_failure_state",
+	InvalidFailureTransition: "invalid_failure_transition",
+	MissingResolver:          "missing_resolver",
+	FailureStatesUnsupported: "failure_states_unsupported",
+}
+
+func errorLabel(err error) string {
+	if label, ok := errorLabels[err]; ok {
+		return label
+	}
+	return "other"
+}
+
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+type Metrics struct {
+	buckets   []float64
+	calls     map[Operation]uint64
+	errors    map[Operation]map[string]uint64
+	latencies map[Operation]*histogram
+	sync.Mutex
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{
+		buckets:   DefaultLatencyBuckets,
+		calls:     make(map[Operation]uint64),
+		errors:    make(map[Operation]map[string]uint64),
+		latencies: make(map[Operation]*histogram),
+	}
+}
+
+func NewInstrumentedStore(store LogStore, metrics *Metrics) LogStore {
+	return Wrap(store, metrics.Middleware())
+}
+
+func (m *Metrics) Middleware() Middleware {
+	return Middleware{
+		After: func(call Call, err error, elapsed time.Duration) {
+			m.observe(call.Op, err, elapsed)
+		},
+	}
+}
+
+func (m *Metrics) observe(op Operation, err error, elapsed time.Duration) {
+	m.Lock()
+	defer m.Unlock()
+	m.calls[op]++
+	if err != nil {
+		if _, ok := m.errors[op]; !ok {
+			m.errors[op] = make(map[string]uint64)
+		}
+		m.errors[op][errorLabel(err)]++
+	}
+	h, ok := m.latencies[op]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(m.buckets))}
+		m.latencies[op] = h
+	}
+	seconds := elapsed.Seconds()
+	for pos, bound := range m.buckets {
+		if seconds <= bound {
+			h.counts[pos]++
+		}
+	}
+	h.sum += seconds
+	h.count++
+}
+
+func (m *Metrics) Calls(op Operation) uint64 {
+	m.Lock()
+	defer m.Unlock()
+	return m.calls[op]
+}
+
+func (m *Metrics) Errors(op Operation, err error) uint64 {
+	m.Lock()
+	defer m.Unlock()
+	return m.errors[op][errorLabel(err)]
+}
+
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	m.WriteTo(w)
+}
+
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	m.Lock()
+	defer m.Unlock()
+	var written int64
+	printf := func(format string, args ...interface{}) error {
+		n, err := fmt.Fprintf(w, format, args...)
+		written += int64(n)
+		return err
+	}
+	if err := printf("# HELP paymentlog_store_calls_total Number of LogStore calls by method.\n# TYPE paymentlog_store_calls_total counter\n"); err != nil {
+		return written, err
+	}
+	for _, op := range Operations {
+		if err := printf("paymentlog_store_calls_total{method=%q} %d\n", op, m.calls[op]); err != nil {
+			return written, err
+		}
+	}
+	if err := printf("# HELP paymentlog_store_errors_total Number of LogStore errors by method and error.\n# TYPE paymentlog_store_errors_total counter\n"); err != nil {
+		return written, err
+	}
+	for _, op := range Operations {
+		labels := make([]string, 0, len(m.errors[op]))
+		for label := range m.errors[op] {
+			labels = append(labels, label)
+		}
+		sort.Strings(labels)
+		for _, label := range labels {
+			if err := printf("paymentlog_store_errors_total{method=%q,error=%q} %d\n", op, label, m.errors[op][label]); err != nil {
+				return written, err
+			}
+		}
+	}
+	if err := printf("# HELP paymentlog_store_latency_seconds Latency of LogStore calls by method.\n# TYPE paymentlog_store_latency_seconds histogram\n"); err != nil {
+		return written, err
+	}
+	for _, op := range Operations {
+		h, ok := m.latencies[op]
+		if !ok {
+			continue
+		}
+		for pos, bound := range m.buckets {
+			if err := printf("paymentlog_store_latency_seconds_bucket{method=%q,le=%q} %d\n", op, strconv.FormatFloat(bound, 'g', -1, 64), h.counts[pos]); err != nil {
+				return written, err
+			}
+		}
+		if err := printf("paymentlog_store_latency_seconds_bucket{method=%q,le=\"+Inf\"} %d\n", op, h.count); err != nil {
+			return written, err
+		}
+		if err := printf("paymentlog_store_latency_seconds_sum{method=%q} %s\n", op, strconv.FormatFloat(h.sum, 'g', -1, 64)); err != nil {
+			return written, err
+		}
+		if err := printf("paymentlog_store_latency_seconds_count{method=%q} %d\n", op, h.count); err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
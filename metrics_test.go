@@ -0,0 +1,74 @@
+package paymentlog
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestInstrumentedStoreCountsCalls(t *testing.T) {
+	metrics := NewMetrics()
+	store := NewInstrumentedStore(NewMemoryStore(), metrics)
+	store.StorePaymentLog(PaymentLog{ID: "id"})
+	store.StorePaymentLog(PaymentLog{ID: "id"})
+	store.GetPaymentLog("id")
+	store.GetPaymentLog("not a payment log")
+	if calls := metrics.Calls(OpStorePaymentLog); calls != 2 {
+		t.Errorf("Expected 2 %s calls, got %d.", OpStorePaymentLog, calls)
+	}
+	if errs := metrics.Errors(OpStorePaymentLog, AlreadyExists); errs != 1 {
+		t.Errorf("Expected 1 %s error, got %d.", AlreadyExists, errs)
+	}
+	if calls := metrics.Calls(OpGetPaymentLog); calls != 2 {
+		t.Errorf("Expected 2 %s calls, got %d.", OpGetPaymentLog, calls)
+	}
+	if errs := metrics.Errors(OpGetPaymentLog, LogNotFound); errs != 1 {
+		t.Errorf("Expected 1 %s error, got %d.", LogNotFound, errs)
+	}
+}
+
+func TestSentinelErrorsAreLabelled(t *testing.T) {
+	errs := []error{FeesExceedAmount, FailureLogNotFound, InvalidFailureState, InvalidFailureTransition, MissingResolver, FailureStatesUnsupported}
+	for _, err := range errs {
+		if label := errorLabel(err); label == "other" {
+			t.Errorf("Expected %s to have its own label, got %s.", err, label)
+		}
+	}
+	metrics := NewMetrics()
+	store := NewInstrumentedStore(NewMemoryStore(), metrics).(FailureStateStore)
+	store.UpdateFailureLogState("not a failure log", FailureStateChange{State: FailureResolved, Resolver: "support"})
+	if errs := metrics.Errors(OpUpdateFailureLogState, FailureLogNotFound); errs != 1 {
+		t.Errorf("Expected 1 %s error, got %d.", FailureLogNotFound, errs)
+	}
+}
+
+func TestServingMetrics(t *testing.T) {
+	metrics := NewMetrics()
+	store := NewInstrumentedStore(NewMemoryStore(), metrics)
+	store.GetPaymentLog("not a payment log")
+	w := httptest.NewRecorder()
+	r, err := http.NewRequest("GET", "/metrics", nil)
+	if err != nil {
+		t.Fatalf("Error creating request: %s", err)
+	}
+	metrics.ServeHTTP(w, r)
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain") {
+		t.Errorf("Expected a text/plain content type, got %s.", w.Header().Get("Content-Type"))
+	}
+	body := w.Body.String()
+	expectations := []string{
+		"# TYPE paymentlog_store_calls_total counter\n",
+		`paymentlog_store_calls_total{method="GetPaymentLog"} 1` + "\n",
+		`paymentlog_store_calls_total{method="StorePaymentLog"} 0` + "\n",
+		`paymentlog_store_errors_total{method="GetPaymentLog",error="not_found"} 1` + "\n",
+		"# TYPE paymentlog_store_latency_seconds histogram\n",
+		`paymentlog_store_latency_seconds_bucket{method="GetPaymentLog",le="+Inf"} 1` + "\n",
+		`paymentlog_store_latency_seconds_count{method="GetPaymentLog"} 1` + "\n",
+	}
+	for _, expectation := range expectations {
+		if !strings.Contains(body, expectation) {
+			t.Errorf("Expected metrics output to contain %q, got:\n%s", expectation, body)
+		}
+	}
+}
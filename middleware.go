@@ -0,0 +1,213 @@
+package paymentlog
+
+import (
+	"time"
+)
+
+type Operation string
+
+const (
+	OpStorePaymentLog          Operation = "StorePaymentLog"
+	OpUpdatePaymentLog         Operation = "UpdatePaymentLog"
+	OpDeletePaymentLog         Operation = "DeletePaymentLog"
+	OpGetPaymentLog            Operation = "GetPaymentLog"
+	OpListPaymentLogsByProject Operation = "ListPaymentLogsByProject"
+	OpListPaymentLogsByUser    Operation = "ListPaymentLogsByUser"
+	OpListPaymentLogs          Operation = "ListPaymentLogs"
+	OpIteratePaymentLogs       Operation = "IteratePaymentLogs"
+	OpStoreFailureLog          Operation = "StoreFailureLog"
+	OpListFailureLogs          Operation = "ListFailureLogs"
+	OpListFailureLogsSince     Operation = "ListFailureLogsSince"
+	OpIterateFailureLogs       Operation = "IterateFailureLogs"
+	OpUpdateFailureLogState    Operation = "UpdateFailureLogState"
+	OpListOpenFailureLogs      Operation = "ListOpenFailureLogs"
+)
+
+var Operations = []Operation{
+	OpStorePaymentLog,
+	OpUpdatePaymentLog,
+	OpDeletePaymentLog,
+	OpGetPaymentLog,
+	OpListPaymentLogsByProject,
+	OpListPaymentLogsByUser,
+	OpListPaymentLogs,
+	OpIteratePaymentLogs,
+	OpStoreFailureLog,
+	OpListFailureLogs,
+	OpListFailureLogsSince,
+	OpIterateFailureLogs,
+	OpUpdateFailureLogState,
+	OpListOpenFailureLogs,
+}
+
+// Call describes a single LogStore method call. Args holds the arguments
+// in the order the LogStore method takes them.
+type Call struct {
+	Op   Operation
+	Args []interface{}
+}
+
+// Middleware hooks are run around every call made through a store returned
+// by Wrap. Before hooks run in the order the middlewares were passed to Wrap
+// and can reject a call by returning an error; After hooks run in reverse
+// order, and only for middlewares whose Before hook ran.
+type Middleware struct {
+	Before func(call Call) error
+	After  func(call Call, err error, elapsed time.Duration)
+}
+
+func Wrap(store LogStore, middlewares ...Middleware) LogStore {
+	return wrappedStore{store: store, middlewares: middlewares}
+}
+
+type wrappedStore struct {
+	store       LogStore
+	middlewares []Middleware
+}
+
+func (w wrappedStore) do(call Call, fn func() error) error {
+	for pos, m := range w.middlewares {
+		if m.Before == nil {
+			continue
+		}
+		if err := m.Before(call); err != nil {
+			w.after(w.middlewares[:pos], call, err, 0)
+			return err
+		}
+	}
+	start := time.Now()
+	err := fn()
+	w.after(w.middlewares, call, err, time.Since(start))
+	return err
+}
+
+func (w wrappedStore) after(middlewares []Middleware, call Call, err error, elapsed time.Duration) {
+	for pos := len(middlewares) - 1; pos >= 0; pos-- {
+		if middlewares[pos].After == nil {
+			continue
+		}
+		middlewares[pos].After(call, err, elapsed)
+	}
+}
+
+func (w wrappedStore) StorePaymentLog(log PaymentLog) error {
+	return w.do(Call{Op: OpStorePaymentLog, Args: []interface{}{log}}, func() error {
+		return w.store.StorePaymentLog(log)
+	})
+}
+
+func (w wrappedStore) UpdatePaymentLog(id string, change PaymentLogChange) error {
+	return w.do(Call{Op: OpUpdatePaymentLog, Args: []interface{}{id, change}}, func() error {
+		return w.store.UpdatePaymentLog(id, change)
+	})
+}
+
+func (w wrappedStore) DeletePaymentLog(id string) error {
+	return w.do(Call{Op: OpDeletePaymentLog, Args: []interface{}{id}}, func() error {
+		return w.store.DeletePaymentLog(id)
+	})
+}
+
+func (w wrappedStore) GetPaymentLog(id string) (PaymentLog, error) {
+	var log PaymentLog
+	err := w.do(Call{Op: OpGetPaymentLog, Args: []interface{}{id}}, func() error {
+		var err error
+		log, err = w.store.GetPaymentLog(id)
+		return err
+	})
+	return log, err
+}
+
+func (w wrappedStore) ListPaymentLogsByProject(campaignID string, num, offset int) ([]PaymentLog, error) {
+	var logs []PaymentLog
+	err := w.do(Call{Op: OpListPaymentLogsByProject, Args: []interface{}{campaignID, num, offset}}, func() error {
+		var err error
+		logs, err = w.store.ListPaymentLogsByProject(campaignID, num, offset)
+		return err
+	})
+	return logs, err
+}
+
+func (w wrappedStore) ListPaymentLogsByUser(userID string, num, offset int) ([]PaymentLog, error) {
+	var logs []PaymentLog
+	err := w.do(Call{Op: OpListPaymentLogsByUser, Args: []interface{}{userID, num, offset}}, func() error {
+		var err error
+		logs, err = w.store.ListPaymentLogsByUser(userID, num, offset)
+		return err
+	})
+	return logs, err
+}
+
+func (w wrappedStore) ListPaymentLogs(num, offset int) ([]PaymentLog, error) {
+	var logs []PaymentLog
+	err := w.do(Call{Op: OpListPaymentLogs, Args: []interface{}{num, offset}}, func() error {
+		var err error
+		logs, err = w.store.ListPaymentLogs(num, offset)
+		return err
+	})
+	return logs, err
+}
+
+func (w wrappedStore) IteratePaymentLogs(fn func(log PaymentLog) error) error {
+	return w.do(Call{Op: OpIteratePaymentLogs, Args: []interface{}{fn}}, func() error {
+		return w.store.IteratePaymentLogs(fn)
+	})
+}
+
+func (w wrappedStore) StoreFailureLog(failure FailureLog) error {
+	return w.do(Call{Op: OpStoreFailureLog, Args: []interface{}{failure}}, func() error {
+		return w.store.StoreFailureLog(failure)
+	})
+}
+
+func (w wrappedStore) ListFailureLogs(num, offset int) ([]FailureLog, error) {
+	var logs []FailureLog
+	err := w.do(Call{Op: OpListFailureLogs, Args: []interface{}{num, offset}}, func() error {
+		var err error
+		logs, err = w.store.ListFailureLogs(num, offset)
+		return err
+	})
+	return logs, err
+}
+
+func (w wrappedStore) ListFailureLogsSince(timestamp time.Time) ([]FailureLog, error) {
+	var logs []FailureLog
+	err := w.do(Call{Op: OpListFailureLogsSince, Args: []interface{}{timestamp}}, func() error {
+		var err error
+		logs, err = w.store.ListFailureLogsSince(timestamp)
+		return err
+	})
+	return logs, err
+}
+
+func (w wrappedStore) IterateFailureLogs(fn func(failure FailureLog) error) error {
+	return w.do(Call{Op: OpIterateFailureLogs, Args: []interface{}{fn}}, func() error {
+		return w.store.IterateFailureLogs(fn)
+	})
+}
+
+// UpdateFailureLogState returns FailureStatesUnsupported if the wrapped
+// store isn't a FailureStateStore.
+func (w wrappedStore) UpdateFailureLogState(id string, change FailureStateChange) error {
+	return w.do(Call{Op: OpUpdateFailureLogState, Args: []interface{}{id, change}}, func() error {
+		states, ok := w.store.(FailureStateStore)
+		if !ok {
+			return FailureStatesUnsupported
+		}
+		return states.UpdateFailureLogState(id, change)
+	})
+}
+
+func (w wrappedStore) ListOpenFailureLogs(num, offset int) ([]FailureLog, error) {
+	var logs []FailureLog
+	err := w.do(Call{Op: OpListOpenFailureLogs, Args: []interface{}{num, offset}}, func() error {
+		states, ok := w.store.(FailureStateStore)
+		if !ok {
+			return FailureStatesUnsupported
+		}
+		var err error
+		logs, err = states.ListOpenFailureLogs(num, offset)
+		return err
+	})
+	return logs, err
+}
@@ -0,0 +1,119 @@
+package paymentlog
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestMiddlewareOrdering(t *testing.T) {
+	calls := make([]string, 0)
+	recorder := func(name string) Middleware {
+		return Middleware{
+			Before: func(call Call) error {
+				calls = append(calls, fmt.Sprintf("%s before %s", name, call.Op))
+				return nil
+			},
+			After: func(call Call, err error, elapsed time.Duration) {
+				calls = append(calls, fmt.Sprintf("%s after %s: %v", name, call.Op, err))
+			},
+		}
+	}
+	store := Wrap(NewMemoryStore(), recorder("first"), recorder("second"))
+	_, err := store.GetPaymentLog("not a payment log")
+	if err != LogNotFound {
+		t.Errorf("Expected a log not found error, got %v.", err)
+	}
+	expectations := []string{
+		"first before GetPaymentLog",
+		"second before GetPaymentLog",
+		"second after GetPaymentLog: " + LogNotFound.Error(),
+		"first after GetPaymentLog: " + LogNotFound.Error(),
+	}
+	if len(calls) != len(expectations) {
+		t.Fatalf("Expected %d hook calls, got %d: %v", len(expectations), len(calls), calls)
+	}
+	for pos, expectation := range expectations {
+		if calls[pos] != expectation {
+			t.Errorf("Expected hook call %d to be %q, got %q.", pos, expectation, calls[pos])
+		}
+	}
+}
+
+func TestMiddlewareRejectingCall(t *testing.T) {
+	unauthorized := errors.New("unauthorized")
+	memory := NewMemoryStore()
+	afterCalled := false
+	store := Wrap(memory, Middleware{
+		After: func(call Call, err error, elapsed time.Duration) {
+			afterCalled = true
+			if err != unauthorized {
+				t.Errorf("Expected %s in after hook, got %v.", unauthorized, err)
+			}
+		},
+	}, Middleware{
+		Before: func(call Call) error {
+			if call.Op == OpDeletePaymentLog {
+				return unauthorized
+			}
+			return nil
+		},
+		After: func(call Call, err error, elapsed time.Duration) {
+			t.Errorf("After hook of rejecting middleware should not run.")
+		},
+	})
+	memory.paymentLogs["id"] = &PaymentLog{ID: "id"}
+	err := store.DeletePaymentLog("id")
+	if err != unauthorized {
+		t.Errorf("Expected %s, got %v.", unauthorized, err)
+	}
+	if !afterCalled {
+		t.Errorf("Expected the outer after hook to run.")
+	}
+	if _, ok := memory.paymentLogs["id"]; !ok {
+		t.Errorf("Rejected delete still deleted the payment log.")
+	}
+}
+
+func TestMiddlewareSeesEveryOperation(t *testing.T) {
+	seen := map[Operation]int{}
+	store := Wrap(NewMemoryStore(), Middleware{
+		After: func(call Call, err error, elapsed time.Duration) {
+			seen[call.Op]++
+		},
+	})
+	noop := func(log PaymentLog) error { return nil }
+	noopFailure := func(failure FailureLog) error { return nil }
+	store.StorePaymentLog(PaymentLog{ID: "id"})
+	store.UpdatePaymentLog("id", PaymentLogChange{})
+	store.GetPaymentLog("id")
+	store.ListPaymentLogsByProject("project-id", 10, 0)
+	store.ListPaymentLogsByUser("user-id", 10, 0)
+	store.ListPaymentLogs(10, 0)
+	store.IteratePaymentLogs(noop)
+	store.DeletePaymentLog("id")
+	store.StoreFailureLog(FailureLog{ID: "id"})
+	store.ListFailureLogs(10, 0)
+	store.ListFailureLogsSince(time.Now())
+	store.IterateFailureLogs(noopFailure)
+	states := store.(FailureStateStore)
+	states.UpdateFailureLogState("id", FailureStateChange{State: FailureAcknowledged, Resolver: "support"})
+	states.ListOpenFailureLogs(10, 0)
+	for _, op := range Operations {
+		if seen[op] != 1 {
+			t.Errorf("Expected %s to be seen once, saw it %d times.", op, seen[op])
+		}
+	}
+}
+
+func TestWrappingStoreWithoutFailureStates(t *testing.T) {
+	// only the LogStore methods of the memory store are promoted
+	store := Wrap(struct{ LogStore }{NewMemoryStore()}).(FailureStateStore)
+	if err := store.UpdateFailureLogState("id", FailureStateChange{State: FailureAcknowledged, Resolver: "support"}); err != FailureStatesUnsupported {
+		t.Errorf("Expected %s, got %v.", FailureStatesUnsupported, err)
+	}
+	if _, err := store.ListOpenFailureLogs(10, 0); err != FailureStatesUnsupported {
+		t.Errorf("Expected %s, got %v.", FailureStatesUnsupported, err)
+	}
+}
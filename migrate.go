@@ -0,0 +1,243 @@
+package paymentlog
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+)
+
+const DefaultMigrationBatchSize = 100
+
+var MigrationVerificationFailed = errors.New("Migrated logs don't match their source.")
+
+// MigrationCheckpoint records the newest payment log and failure log a
+// migration copied. Logs are copied oldest first, with ties broken by ID,
+// so logs added to the source after a checkpoint come after it and are
+// copied when the migration is resumed.
+type MigrationCheckpoint struct {
+	LastPaymentLogID      string    `json:"last_payment_log_id,omitempty"`
+	LastPaymentLogCreated time.Time `json:"last_payment_log_created"`
+	LastFailureLogID      string    `json:"last_failure_log_id,omitempty"`
+	LastFailureLogTime    time.Time `json:"last_failure_log_timestamp"`
+}
+
+// pastCheckpoint reports whether a log at created with id comes after the
+// last log copied at last with lastID.
+func pastCheckpoint(created time.Time, id string, last time.Time, lastID string) bool {
+	if lastID == "" || created.After(last) {
+		return true
+	}
+	return created.Equal(last) && id > lastID
+}
+
+type oldestPaymentLogs []PaymentLog
+
+func (o oldestPaymentLogs) Len() int {
+	return len(o)
+}
+
+func (o oldestPaymentLogs) Swap(i, j int) {
+	o[i], o[j] = o[j], o[i]
+}
+
+func (o oldestPaymentLogs) Less(i, j int) bool {
+	if o[i].Created.Equal(o[j].Created) {
+		return o[i].ID < o[j].ID
+	}
+	return o[i].Created.Before(o[j].Created)
+}
+
+type MigrationOptions struct {
+	BatchSize int
+	// Checkpoint is where the migration starts; the zero value starts
+	// from the beginning.
+	Checkpoint MigrationCheckpoint
+	// OnCheckpoint, if set, is called after every batch so the checkpoint
+	// can be persisted. Returning an error stops the migration.
+	OnCheckpoint func(checkpoint MigrationCheckpoint) error
+}
+
+type MigrationResult struct {
+	PaymentLogsCopied  int
+	PaymentLogsSkipped int
+	FailureLogsCopied  int
+	FailureLogsSkipped int
+
+	SourcePaymentLogs      int
+	DestinationPaymentLogs int
+	SourceFailureLogs      int
+	DestinationFailureLogs int
+	MismatchedPaymentLogs  []string
+	MismatchedFailureLogs  []string
+}
+
+func checksum(v interface{}) string {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		// the log types always encode; this is unreachable
+		panic(err)
+	}
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:])
+}
+
+// PaymentLogChecksum leaves out ChargedBack, which a store derives from its
+// disputes rather than copying.
+func PaymentLogChecksum(log PaymentLog) string {
+	log.ChargedBack = 0
+	log.Created = log.Created.UTC()
+	log.Updated = log.Updated.UTC()
+	return checksum(log)
+}
+
+func FailureLogChecksum(failure FailureLog) string {
+	failure.Timestamp = failure.Timestamp.UTC()
+	return checksum(failure)
+}
+
+// Migrate copies every payment log and failure log from src to dst, oldest
+// first, checkpointing after every batch, then verifies the two stores
+// match. Logs that already exist in dst are skipped, so an interrupted
+// migration can be resumed from its last checkpoint, or even from the
+// beginning, and a finished one can be run again to copy newer logs. The
+// logs past the checkpoint are read from src before any are copied.
+func Migrate(src, dst LogStore, opts MigrationOptions) (MigrationResult, error) {
+	var result MigrationResult
+	if opts.BatchSize < 1 {
+		opts.BatchSize = DefaultMigrationBatchSize
+	}
+	checkpoint := opts.Checkpoint
+	save := func() error {
+		if opts.OnCheckpoint == nil {
+			return nil
+		}
+		return opts.OnCheckpoint(checkpoint)
+	}
+	// sources list newest first, so stop at the first log older than the
+	// checkpoint
+	logs := []PaymentLog{}
+	err := src.IteratePaymentLogs(func(log PaymentLog) error {
+		if !pastCheckpoint(log.Created, log.ID, checkpoint.LastPaymentLogCreated, checkpoint.LastPaymentLogID) {
+			if log.Created.Before(checkpoint.LastPaymentLogCreated) {
+				return StopIteration
+			}
+			return nil
+		}
+		logs = append(logs, log)
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+	sort.Sort(oldestPaymentLogs(logs))
+	for pos, log := range logs {
+		err := dst.StorePaymentLog(log)
+		if err == AlreadyExists {
+			result.PaymentLogsSkipped++
+		} else if err != nil {
+			return result, err
+		} else {
+			result.PaymentLogsCopied++
+		}
+		checkpoint.LastPaymentLogID, checkpoint.LastPaymentLogCreated = log.ID, log.Created
+		if (pos+1)%opts.BatchSize == 0 || pos == len(logs)-1 {
+			if err := save(); err != nil {
+				return result, err
+			}
+		}
+	}
+	failures := []FailureLog{}
+	err = src.IterateFailureLogs(func(log FailureLog) error {
+		if !pastCheckpoint(log.Timestamp, log.ID, checkpoint.LastFailureLogTime, checkpoint.LastFailureLogID) {
+			if log.Timestamp.Before(checkpoint.LastFailureLogTime) {
+				return StopIteration
+			}
+			return nil
+		}
+		failures = append(failures, log)
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+	sort.Sort(oldestFailures(failures))
+	for pos, log := range failures {
+		err := dst.StoreFailureLog(log)
+		if err == AlreadyExists {
+			result.FailureLogsSkipped++
+		} else if err != nil {
+			return result, err
+		} else {
+			result.FailureLogsCopied++
+		}
+		checkpoint.LastFailureLogID, checkpoint.LastFailureLogTime = log.ID, log.Timestamp
+		if (pos+1)%opts.BatchSize == 0 || pos == len(failures)-1 {
+			if err := save(); err != nil {
+				return result, err
+			}
+		}
+	}
+	verification, err := VerifyMigration(src, dst)
+	verification.PaymentLogsCopied = result.PaymentLogsCopied
+	verification.PaymentLogsSkipped = result.PaymentLogsSkipped
+	verification.FailureLogsCopied = result.FailureLogsCopied
+	verification.FailureLogsSkipped = result.FailureLogsSkipped
+	return verification, err
+}
+
+// VerifyMigration compares the number of logs in src and dst and the
+// checksum of every log in src with its copy in dst. It returns
+// MigrationVerificationFailed if anything differs; the result says what.
+func VerifyMigration(src, dst LogStore) (MigrationResult, error) {
+	var result MigrationResult
+	result.MismatchedPaymentLogs = make([]string, 0)
+	result.MismatchedFailureLogs = make([]string, 0)
+	err := src.IteratePaymentLogs(func(log PaymentLog) error {
+		result.SourcePaymentLogs++
+		copied, err := dst.GetPaymentLog(log.ID)
+		if err == LogNotFound || (err == nil && PaymentLogChecksum(copied) != PaymentLogChecksum(log)) {
+			result.MismatchedPaymentLogs = append(result.MismatchedPaymentLogs, log.ID)
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return result, err
+	}
+	err = dst.IteratePaymentLogs(func(log PaymentLog) error {
+		result.DestinationPaymentLogs++
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+	copies := map[string]string{}
+	err = dst.IterateFailureLogs(func(failure FailureLog) error {
+		result.DestinationFailureLogs++
+		copies[failure.ID] = FailureLogChecksum(failure)
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+	err = src.IterateFailureLogs(func(failure FailureLog) error {
+		result.SourceFailureLogs++
+		if copies[failure.ID] != FailureLogChecksum(failure) {
+			result.MismatchedFailureLogs = append(result.MismatchedFailureLogs, failure.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+	if result.SourcePaymentLogs != result.DestinationPaymentLogs ||
+		result.SourceFailureLogs != result.DestinationFailureLogs ||
+		len(result.MismatchedPaymentLogs) > 0 ||
+		len(result.MismatchedFailureLogs) > 0 {
+		return result, MigrationVerificationFailed
+	}
+	return result, nil
+}
@@ -0,0 +1,173 @@
+package paymentlog
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestMigratingStores(t *testing.T) {
+	src := exportTestStore()
+	dst := NewMemoryStore()
+	result, err := Migrate(src, dst, MigrationOptions{BatchSize: 2})
+	if err != nil {
+		t.Fatalf("Error migrating: %s (%+v)", err, result)
+	}
+	if result.PaymentLogsCopied != len(src.paymentLogs) || result.FailureLogsCopied != len(src.failureLogs) {
+		t.Errorf("Expected %d payment logs and %d failure logs to be copied, got %+v.", len(src.paymentLogs), len(src.failureLogs), result)
+	}
+	for id, log := range src.paymentLogs {
+		copied, ok := dst.paymentLogs[id]
+		if !ok {
+			t.Errorf("Payment log %s was not migrated.", id)
+			continue
+		}
+		success, field, expectation, res := comparePaymentLogs(*log, *copied)
+		if !success {
+			t.Errorf("Expected payment log %s %s to be %+v, got %+v.", id, field, expectation, res)
+		}
+	}
+	for id := range src.failureLogs {
+		if _, ok := dst.failureLogs[id]; !ok {
+			t.Errorf("Failure log %s was not migrated.", id)
+		}
+	}
+}
+
+func TestResumingMigration(t *testing.T) {
+	src := exportTestStore()
+	dst := NewMemoryStore()
+	interrupted := errors.New("interrupted")
+	var saved MigrationCheckpoint
+	_, err := Migrate(src, dst, MigrationOptions{
+		BatchSize: 2,
+		OnCheckpoint: func(checkpoint MigrationCheckpoint) error {
+			saved = checkpoint
+			return interrupted
+		},
+	})
+	if err != interrupted {
+		t.Fatalf("Expected %s, got %v.", interrupted, err)
+	}
+	if saved.LastPaymentLogID != "test-payment-log 2" {
+		t.Errorf("Expected a checkpoint after the first batch, got %+v.", saved)
+	}
+	// simulate the newest payment log being copied without a checkpoint
+	dst.StorePaymentLog(*src.paymentLogs["test-payment-log 3"])
+	result, err := Migrate(src, dst, MigrationOptions{BatchSize: 2, Checkpoint: saved})
+	if err != nil {
+		t.Fatalf("Error resuming migration: %s (%+v)", err, result)
+	}
+	if result.PaymentLogsCopied != 0 || result.PaymentLogsSkipped != 1 {
+		t.Errorf("Expected the resumed migration to skip 1 payment log, got %+v.", result)
+	}
+	if result.DestinationPaymentLogs != len(src.paymentLogs) || result.DestinationFailureLogs != len(src.failureLogs) {
+		t.Errorf("Expected the destination counts to match the source, got %+v.", result)
+	}
+}
+
+func TestResumingMigrationAfterNewLogs(t *testing.T) {
+	src := exportTestStore()
+	dst := NewMemoryStore()
+	interrupted := errors.New("interrupted")
+	var saved MigrationCheckpoint
+	_, err := Migrate(src, dst, MigrationOptions{
+		BatchSize: 2,
+		OnCheckpoint: func(checkpoint MigrationCheckpoint) error {
+			saved = checkpoint
+			return interrupted
+		},
+	})
+	if err != interrupted {
+		t.Fatalf("Expected %s, got %v.", interrupted, err)
+	}
+	newer := *src.paymentLogs["test-payment-log 3"]
+	newer.ID, newer.Created = "newer", newer.Created.Add(time.Hour)
+	if err := src.StorePaymentLog(newer); err != nil {
+		t.Fatalf("Error storing payment log: %s", err)
+	}
+	result, err := Migrate(src, dst, MigrationOptions{
+		BatchSize:  2,
+		Checkpoint: saved,
+		OnCheckpoint: func(checkpoint MigrationCheckpoint) error {
+			saved = checkpoint
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Error resuming migration: %s (%+v)", err, result)
+	}
+	if result.PaymentLogsCopied != 2 {
+		t.Errorf("Expected 2 payment logs to be copied, got %+v.", result)
+	}
+
+	// running a finished migration again copies what's been added since
+	newest := newer
+	newest.ID, newest.Created = "newest", newest.Created.Add(time.Hour)
+	if err := src.StorePaymentLog(newest); err != nil {
+		t.Fatalf("Error storing payment log: %s", err)
+	}
+	result, err = Migrate(src, dst, MigrationOptions{BatchSize: 2, Checkpoint: saved})
+	if err != nil {
+		t.Fatalf("Error running migration again: %s (%+v)", err, result)
+	}
+	if result.PaymentLogsCopied != 1 || result.PaymentLogsSkipped != 0 || result.FailureLogsCopied != 0 {
+		t.Errorf("Expected only the newest payment log to be copied, got %+v.", result)
+	}
+}
+
+func TestMigratingTiedLogs(t *testing.T) {
+	src := NewMemoryStore()
+	now := time.Now()
+	for i := 0; i < 250; i++ {
+		id := fmt.Sprintf("id%03d", i)
+		src.StorePaymentLog(PaymentLog{ID: id, Created: now})
+		src.StoreFailureLog(FailureLog{ID: id, PaymentLogID: id, Timestamp: now})
+	}
+	dst := NewMemoryStore()
+	interrupted := errors.New("interrupted")
+	var saved MigrationCheckpoint
+	_, err := Migrate(src, dst, MigrationOptions{
+		BatchSize: 100,
+		OnCheckpoint: func(checkpoint MigrationCheckpoint) error {
+			saved = checkpoint
+			return interrupted
+		},
+	})
+	if err != interrupted {
+		t.Fatalf("Expected %s, got %v.", interrupted, err)
+	}
+	// logs added while the migration is stopped don't shift the checkpoint
+	src.StorePaymentLog(PaymentLog{ID: "id200a", Created: now})
+	result, err := Migrate(src, dst, MigrationOptions{BatchSize: 100, Checkpoint: saved})
+	if err != nil {
+		t.Fatalf("Error resuming migration: %s (%+v)", err, result)
+	}
+	if result.PaymentLogsCopied != 151 || result.PaymentLogsSkipped != 0 || result.FailureLogsCopied != 250 {
+		t.Errorf("Expected 151 payment logs and 250 failure logs to be copied, got %+v.", result)
+	}
+}
+
+func TestVerifyingMigration(t *testing.T) {
+	src := exportTestStore()
+	dst := NewMemoryStore()
+	if _, err := Migrate(src, dst, MigrationOptions{}); err != nil {
+		t.Fatalf("Error migrating: %s", err)
+	}
+	dst.paymentLogs["test-payment-log 2"].Amount++
+	delete(dst.failureLogs, "id1")
+	result, err := VerifyMigration(src, dst)
+	if err != MigrationVerificationFailed {
+		t.Errorf("Expected %s, got %v.", MigrationVerificationFailed, err)
+	}
+	if len(result.MismatchedPaymentLogs) != 1 || result.MismatchedPaymentLogs[0] != "test-payment-log 2" {
+		t.Errorf("Expected test-payment-log 2 to mismatch, got %v.", result.MismatchedPaymentLogs)
+	}
+	if len(result.MismatchedFailureLogs) != 1 || result.MismatchedFailureLogs[0] != "id1" {
+		t.Errorf("Expected id1 to mismatch, got %v.", result.MismatchedFailureLogs)
+	}
+	if result.SourceFailureLogs != 2 || result.DestinationFailureLogs != 1 {
+		t.Errorf("Expected 2 source and 1 destination failure logs, got %+v.", result)
+	}
+}
@@ -7,9 +7,12 @@ import (
 )
 
 const (
-	SourceBalanced = "balanced"
-	StatusPending  = "pending"
-	CurrencyUSD    = "usd"
+	SourceBalanced  = "balanced"
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+	StatusRefunded  = "refunded"
+	CurrencyUSD     = "usd"
 )
 
 var (
@@ -24,25 +27,40 @@ var (
 	MissingUserID      = errors.New("Missing payment log user ID.")
 	MissingAccountType = errors.New("Missing payment log account type.")
 	MissingAccountID   = errors.New("Missing payment log account ID.")
+	FeesExceedAmount   = errors.New("Payment log fees exceed its amount.")
 
 	AlreadyExists = errors.New("Payment log already exists.")
 	LogNotFound   = errors.New("Payment log not found.")
+
+	FailureLogNotFound = errors.New("Failure log not found.")
+
+	// StopIteration can be returned from an iteration callback to stop the
+	// iteration early without the iterating method returning an error.
+	StopIteration = errors.New("Stop iteration.")
 )
 
 type PaymentLog struct {
-	ID          string
-	Amount      uint
-	Description string
-	Source      string
-	SourceID    string
-	Created     time.Time
-	Updated     time.Time
-	Status      string
-	Currency    string
-	ProjectID   string
-	UserID      string
-	AccountID   string
-	AccountType string
+	ID          string    `json:"id"`
+	Amount      uint      `json:"amount,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Source      string    `json:"source,omitempty"`
+	SourceID    string    `json:"source_id,omitempty"`
+	Created     time.Time `json:"created,omitempty"`
+	Updated     time.Time `json:"updated,omitempty"`
+	Status      string    `json:"status,omitempty"`
+	Currency    string    `json:"currency,omitempty"`
+	ProjectID   string    `json:"project_id,omitempty"`
+	UserID      string    `json:"user_id,omitempty"`
+	AccountID   string    `json:"account_id,omitempty"`
+	AccountType string    `json:"account_type,omitempty"`
+	// ProcessorFee and PlatformFee are taken out of Amount before it's
+	// paid out, in the same minor currency units.
+	ProcessorFee uint `json:"processor_fee,omitempty"`
+	PlatformFee  uint `json:"platform_fee,omitempty"`
+	// ChargedBack is the amount taken back by disputes that were lost or
+	// accepted. Stores derive it from their disputes, so whatever it's set
+	// to when a payment log is stored or imported is ignored.
+	ChargedBack uint `json:"charged_back,omitempty"`
 }
 
 func (p PaymentLog) Validate() error {
@@ -69,28 +87,90 @@ func (p PaymentLog) Validate() error {
 		return MissingAccountType
 	case p.AccountID == "":
 		return MissingAccountID
+	case p.ProcessorFee+p.PlatformFee > p.Amount:
+		return FeesExceedAmount
 	default:
 		return nil
 	}
 }
 
+func (p PaymentLog) Fees() uint {
+	return p.ProcessorFee + p.PlatformFee
+}
+
+// NetAmount is what the payment is worth once fees and chargebacks are
+// taken off.
+func (p PaymentLog) NetAmount() uint {
+	deductions := p.Fees() + p.ChargedBack
+	if deductions >= p.Amount {
+		return 0
+	}
+	return p.Amount - deductions
+}
+
 type PaymentLogChange struct {
-	Amount      *uint
-	Description *string
-	Source      *string
-	SourceID    *string
-	Created     *time.Time
-	Updated     *time.Time
-	Status      *string
-	Currency    *string
+	Amount      *uint      `json:"amount,omitempty"`
+	Description *string    `json:"description,omitempty"`
+	Source      *string    `json:"source,omitempty"`
+	SourceID    *string    `json:"source_id,omitempty"`
+	Created     *time.Time `json:"created,omitempty"`
+	Updated     *time.Time `json:"updated,omitempty"`
+	Status      *string    `json:"status,omitempty"`
+	Currency    *string    `json:"currency,omitempty"`
+
+	ProcessorFee *uint `json:"processor_fee,omitempty"`
+	PlatformFee  *uint `json:"platform_fee,omitempty"`
+}
+
+func (c PaymentLogChange) Fields() []string {
+	fields := make([]string, 0)
+	if c.Amount != nil {
+		fields = append(fields, "Amount")
+	}
+	if c.Description != nil {
+		fields = append(fields, "Description")
+	}
+	if c.Source != nil {
+		fields = append(fields, "Source")
+	}
+	if c.SourceID != nil {
+		fields = append(fields, "SourceID")
+	}
+	if c.Created != nil {
+		fields = append(fields, "Created")
+	}
+	if c.Updated != nil {
+		fields = append(fields, "Updated")
+	}
+	if c.Status != nil {
+		fields = append(fields, "Status")
+	}
+	if c.Currency != nil {
+		fields = append(fields, "Currency")
+	}
+	if c.ProcessorFee != nil {
+		fields = append(fields, "ProcessorFee")
+	}
+	if c.PlatformFee != nil {
+		fields = append(fields, "PlatformFee")
+	}
+	return fields
 }
 
 type FailureLog struct {
-	ID                string
-	PaymentLogID      string
-	FailureReason     string
-	FailureReasonCode string
-	Timestamp         time.Time
+	ID                string    `json:"id"`
+	PaymentLogID      string    `json:"payment_log_id,omitempty"`
+	Source            string    `json:"source,omitempty"`
+	FailureReason     string    `json:"failure_reason,omitempty"`
+	FailureReasonCode string    `json:"failure_reason_code,omitempty"`
+	Timestamp         time.Time `json:"timestamp,omitempty"`
+	// State is one of the FailureOpen, FailureAcknowledged, FailureResolved
+	// and FailureIgnored constants. Failure logs stored without a state are
+	// open.
+	State          string    `json:"state,omitempty"`
+	Resolver       string    `json:"resolver,omitempty"`
+	ResolutionNote string    `json:"resolution_note,omitempty"`
+	StateUpdated   time.Time `json:"state_updated,omitempty"`
 }
 
 type LogStore interface {
@@ -101,10 +181,20 @@ type LogStore interface {
 	ListPaymentLogsByProject(campaignID string, num, offset int) ([]PaymentLog, error)
 	ListPaymentLogsByUser(userID string, num, offset int) ([]PaymentLog, error)
 	ListPaymentLogs(num, offset int) ([]PaymentLog, error)
+	IteratePaymentLogs(fn func(log PaymentLog) error) error
 
 	StoreFailureLog(failure FailureLog) error
 	ListFailureLogs(num, offset int) ([]FailureLog, error)
 	ListFailureLogsSince(timestamp time.Time) ([]FailureLog, error)
+	IterateFailureLogs(fn func(failure FailureLog) error) error
+}
+
+// PaymentLogsSinceStore is implemented by stores that can list recent
+// payment logs without reading the rest.
+type PaymentLogsSinceStore interface {
+	// ListPaymentLogsSince lists the payment logs created after timestamp,
+	// newest first.
+	ListPaymentLogsSince(timestamp time.Time) ([]PaymentLog, error)
 }
 
 type createdSortedLogs []PaymentLog
@@ -118,6 +208,9 @@ func (c createdSortedLogs) Swap(i, j int) {
 }
 
 func (c createdSortedLogs) Less(i, j int) bool {
+	if c[i].Created.Equal(c[j].Created) {
+		return c[i].ID < c[j].ID
+	}
 	return c[i].Created.After(c[j].Created)
 }
 
@@ -138,6 +231,9 @@ func (s sortedFailures) Swap(i, j int) {
 }
 
 func (s sortedFailures) Less(i, j int) bool {
+	if s[i].Timestamp.Equal(s[j].Timestamp) {
+		return s[i].ID < s[j].ID
+	}
 	return s[i].Timestamp.After(s[j].Timestamp)
 }
 
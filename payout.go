@@ -0,0 +1,89 @@
+package paymentlog
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	MissingPayoutID          = errors.New("Missing payout ID.")
+	MissingPayoutProjectID   = errors.New("Missing payout project ID.")
+	MissingPayoutCurrency    = errors.New("Missing payout currency.")
+	MissingPayoutPaymentLogs = errors.New("Missing payout payment logs.")
+	MissingPayoutCreated     = errors.New("Missing payout created timestamp.")
+	PayoutAlreadyExists      = errors.New("Payout already exists.")
+	PayoutNotFound           = errors.New("Payout not found.")
+	NotPayable               = errors.New("Payment log can't be paid out.")
+	AlreadyPaidOut           = errors.New("Payment log has already been paid out.")
+)
+
+// Payout is money paid out to a project, settling a set of its payment
+// logs. Amount is the sum of the payment logs' net amounts when they were
+// paid out, and Sources splits it by the payment logs' sources. Both are
+// set by StorePayout.
+type Payout struct {
+	ID            string          `json:"id"`
+	ProjectID     string          `json:"project_id"`
+	Currency      string          `json:"currency"`
+	Amount        uint            `json:"amount"`
+	Sources       map[string]uint `json:"sources,omitempty"`
+	PaymentLogIDs []string        `json:"payment_log_ids"`
+	Created       time.Time       `json:"created"`
+}
+
+func (p Payout) Validate() error {
+	switch {
+	case p.ID == "":
+		return MissingPayoutID
+	case p.ProjectID == "":
+		return MissingPayoutProjectID
+	case p.Currency == "":
+		return MissingPayoutCurrency
+	case len(p.PaymentLogIDs) == 0:
+		return MissingPayoutPaymentLogs
+	case p.Created.IsZero():
+		return MissingPayoutCreated
+	default:
+		return nil
+	}
+}
+
+// Payable reports whether a payment log can be paid out to a project in a
+// currency, ignoring whether it already has been.
+func (p Payout) Payable(log PaymentLog) bool {
+	return log.Status == StatusSucceeded && log.ProjectID == p.ProjectID && log.Currency == p.Currency
+}
+
+type PayoutStore interface {
+	// StorePayout returns LogNotFound if a payment log doesn't exist,
+	// NotPayable if one isn't a succeeded payment to the payout's project
+	// in its currency, and AlreadyPaidOut if one is settled by another
+	// payout.
+	StorePayout(payout Payout) error
+	GetPayout(id string) (Payout, error)
+	// GetPayoutByPaymentLog returns the payout settling a payment log, or
+	// PayoutNotFound if it hasn't been paid out.
+	GetPayoutByPaymentLog(paymentLogID string) (Payout, error)
+	// ListPayouts lists a project's payouts, newest first.
+	ListPayouts(projectID string, num, offset int) ([]Payout, error)
+	// UnpaidBalances returns the net amount of a project's succeeded
+	// payments that haven't been paid out, by currency.
+	UnpaidBalances(projectID string) (map[string]uint, error)
+}
+
+type sortedPayouts []Payout
+
+func (s sortedPayouts) Len() int {
+	return len(s)
+}
+
+func (s sortedPayouts) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+func (s sortedPayouts) Less(i, j int) bool {
+	if s[i].Created.Equal(s[j].Created) {
+		return s[i].ID < s[j].ID
+	}
+	return s[i].Created.After(s[j].Created)
+}
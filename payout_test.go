@@ -0,0 +1,86 @@
+package paymentlog
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestPayouts(t *testing.T) {
+	store := storeTestPayments(t,
+		testPayment{id: "p1", status: StatusSucceeded, amount: 1000, processorFee: 100},
+		testPayment{id: "p2", status: StatusSucceeded, amount: 2000, processorFee: 150},
+		testPayment{id: "p3", currency: "eur", status: StatusSucceeded, amount: 500},
+		testPayment{id: "p4", status: StatusPending, amount: 700},
+		testPayment{id: "p5", project: "other-project-id", status: StatusSucceeded, amount: 300},
+	)
+	created := time.Date(2014, time.March, 8, 12, 0, 0, 0, time.UTC)
+	balances, err := store.UnpaidBalances("project-id")
+	if err != nil || len(balances) != 2 || balances[CurrencyUSD] != 2750 || balances["eur"] != 500 {
+		t.Errorf("Unexpected unpaid balances: %v, %v", balances, err)
+	}
+
+	payout := Payout{ID: "po1", ProjectID: "project-id", Currency: CurrencyUSD, PaymentLogIDs: []string{"p1"}, Created: created}
+	invalid := map[error]Payout{
+		MissingPayoutPaymentLogs: {ID: "po0", ProjectID: "project-id", Currency: CurrencyUSD, Created: created},
+		LogNotFound:              {ID: "po0", ProjectID: "project-id", Currency: CurrencyUSD, PaymentLogIDs: []string{"missing"}, Created: created},
+		NotPayable:               {ID: "po0", ProjectID: "project-id", Currency: CurrencyUSD, PaymentLogIDs: []string{"p4"}, Created: created},
+		AlreadyPaidOut:           {ID: "po0", ProjectID: "project-id", Currency: CurrencyUSD, PaymentLogIDs: []string{"p2", "p2"}, Created: created},
+	}
+	for expectation, p := range invalid {
+		if err := store.StorePayout(p); err != expectation {
+			t.Errorf("Expected %s storing %+v, got %v.", expectation, p, err)
+		}
+	}
+	for _, id := range []string{"p3", "p5"} {
+		p := payout
+		p.ID, p.PaymentLogIDs = "po0", []string{id}
+		if err := store.StorePayout(p); err != NotPayable {
+			t.Errorf("Expected %s paying out %s, got %v.", NotPayable, id, err)
+		}
+	}
+	if err := store.StorePayout(payout); err != nil {
+		t.Fatalf("Error storing payout: %s", err)
+	}
+	if err := store.StorePayout(payout); err != PayoutAlreadyExists {
+		t.Errorf("Expected %s, got %v.", PayoutAlreadyExists, err)
+	}
+	second := Payout{ID: "po2", ProjectID: "project-id", Currency: CurrencyUSD, PaymentLogIDs: []string{"p1", "p2"}, Created: created.Add(time.Hour)}
+	if err := store.StorePayout(second); err != AlreadyPaidOut {
+		t.Errorf("Expected %s, got %v.", AlreadyPaidOut, err)
+	}
+	second.PaymentLogIDs = []string{"p2"}
+	if err := store.StorePayout(second); err != nil {
+		t.Fatalf("Error storing payout: %s", err)
+	}
+
+	if stored, err := store.GetPayout("po1"); err != nil || stored.Amount != 900 || stored.Sources[SourceBalanced] != 900 {
+		t.Errorf("Expected a payout of 900, got %+v, %v.", stored, err)
+	}
+	if stored, err := store.GetPayoutByPaymentLog("p2"); err != nil || stored.ID != "po2" || stored.Amount != 1850 {
+		t.Errorf("Expected p2 to be paid out by po2, got %+v, %v.", stored, err)
+	}
+	if _, err := store.GetPayoutByPaymentLog("p4"); err != PayoutNotFound {
+		t.Errorf("Expected %s, got %v.", PayoutNotFound, err)
+	}
+	payouts, err := store.ListPayouts("project-id", 0, 0)
+	if err != nil || len(payouts) != 2 || payouts[0].ID != "po2" || payouts[1].ID != "po1" {
+		t.Errorf("Expected [po2 po1], got %+v, %v.", payouts, err)
+	}
+	balances, _ = store.UnpaidBalances("project-id")
+	if len(balances) != 1 || balances["eur"] != 500 {
+		t.Errorf("Unexpected unpaid balances: %v", balances)
+	}
+
+	var buf bytes.Buffer
+	if err := store.Snapshot(&buf); err != nil {
+		t.Fatalf("Error snapshotting memory store: %s", err)
+	}
+	loaded, err := LoadMemoryStore(&buf)
+	if err != nil {
+		t.Fatalf("Error loading memory store: %s", err)
+	}
+	if stored, err := loaded.GetPayoutByPaymentLog("p1"); err != nil || stored.ID != "po1" {
+		t.Errorf("Expected p1 to be paid out by po1 after loading, got %+v, %v.", stored, err)
+	}
+}
@@ -0,0 +1,312 @@
+package paypal
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"code.whipround.net/paymentlog"
+)
+
+const (
+	TransmissionIDHeader   = "Paypal-Transmission-Id"
+	TransmissionTimeHeader = "Paypal-Transmission-Time"
+	TransmissionSigHeader  = "Paypal-Transmission-Sig"
+	DefaultTolerance       = 5 * time.Minute
+)
+
+var MissingResource = errors.New("PayPal event is missing its resource.")
+
+type Amount struct {
+	CurrencyCode string `json:"currency_code"`
+	Value        string `json:"value"`
+}
+
+type Link struct {
+	Href   string `json:"href"`
+	Rel    string `json:"rel"`
+	Method string `json:"method"`
+}
+
+type Capture struct {
+	ID            string    `json:"id"`
+	Status        string    `json:"status"`
+	Amount        Amount    `json:"amount"`
+	CustomID      string    `json:"custom_id"`
+	InvoiceID     string    `json:"invoice_id"`
+	CreateTime    time.Time `json:"create_time"`
+	UpdateTime    time.Time `json:"update_time"`
+	StatusDetails struct {
+		Reason string `json:"reason"`
+	} `json:"status_details"`
+	ProcessorResponse struct {
+		ResponseCode string `json:"response_code"`
+	} `json:"processor_response"`
+}
+
+type Refund struct {
+	ID                     string `json:"id"`
+	Status                 string `json:"status"`
+	Amount                 Amount `json:"amount"`
+	SellerPayableBreakdown struct {
+		TotalRefundedAmount *Amount `json:"total_refunded_amount"`
+	} `json:"seller_payable_breakdown"`
+	CreateTime time.Time `json:"create_time"`
+	UpdateTime time.Time `json:"update_time"`
+	Links      []Link    `json:"links"`
+}
+
+// CaptureID returns the ID of the capture a refund refunds, from its "up"
+// link.
+func (r Refund) CaptureID() string {
+	for _, link := range r.Links {
+		if link.Rel == "up" {
+			return link.Href[strings.LastIndex(link.Href, "/")+1:]
+		}
+	}
+	return ""
+}
+
+type Event struct {
+	ID           string          `json:"id"`
+	EventType    string          `json:"event_type"`
+	ResourceType string          `json:"resource_type"`
+	CreateTime   time.Time       `json:"create_time"`
+	Summary      string          `json:"summary"`
+	Resource     json.RawMessage `json:"resource"`
+}
+
+// PaymentLogID returns the ID of the payment log that records a PayPal
+// capture.
+func PaymentLogID(captureID string) string {
+	return paymentlog.SourcePaymentLogID(paymentlog.SourcePayPal, captureID)
+}
+
+func sign(payload []byte, transmissionID, transmissionTime, secret string) []byte {
+	digest := sha256.Sum256(payload)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(transmissionID + "|" + transmissionTime + "|" + hex.EncodeToString(digest[:])))
+	return mac.Sum(nil)
+}
+
+// Sign returns the TransmissionSigHeader value for a delivery. Deliveries
+// sign the transmission ID and time and a SHA-256 digest of the payload,
+// with an HMAC-SHA256 keyed with a shared secret in place of PayPal's
+// certificate.
+func Sign(payload []byte, transmissionID, transmissionTime, secret string) string {
+	return base64.StdEncoding.EncodeToString(sign(payload, transmissionID, transmissionTime, secret))
+}
+
+// Verify checks a TransmissionSigHeader value. transmissionTime is RFC 3339,
+// and deliveries sent more than tolerance before or after now are rejected,
+// to limit replay attacks.
+func Verify(payload []byte, transmissionID, transmissionTime, signature, secret string, tolerance time.Duration) bool {
+	if transmissionID == "" {
+		return false
+	}
+	sent, err := time.Parse(time.RFC3339, transmissionTime)
+	if err != nil {
+		return false
+	}
+	age := time.Since(sent)
+	if age > tolerance || age < -tolerance {
+		return false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(decoded, sign(payload, transmissionID, transmissionTime, secret))
+}
+
+func ParseEvent(payload []byte) (Event, error) {
+	var event Event
+	err := json.Unmarshal(payload, &event)
+	return event, err
+}
+
+// PaymentLog maps a capture to a payment log. The capture's custom_id holds
+// the project, user and account it pays for, query string encoded.
+func (c Capture) PaymentLog() (paymentlog.PaymentLog, error) {
+	amount, err := paymentlog.ParseAmount(c.Amount.Value)
+	if err != nil {
+		return paymentlog.PaymentLog{}, err
+	}
+	meta, err := url.ParseQuery(c.CustomID)
+	if err != nil {
+		return paymentlog.PaymentLog{}, err
+	}
+	return paymentlog.PaymentLog{
+		ID:          PaymentLogID(c.ID),
+		Amount:      amount,
+		Description: meta.Get("description"),
+		Source:      paymentlog.SourcePayPal,
+		SourceID:    c.ID,
+		Created:     c.CreateTime,
+		Updated:     c.UpdateTime,
+		Status:      Adapter{}.Status(c.Status),
+		Currency:    strings.ToLower(c.Amount.CurrencyCode),
+		ProjectID:   meta.Get("project_id"),
+		UserID:      meta.Get("user_id"),
+		AccountID:   meta.Get("account_id"),
+		AccountType: meta.Get("account_type"),
+	}, nil
+}
+
+// SourceEvent normalizes a PayPal event. Events that aren't about captures
+// normalize to an empty SourceEvent.
+func (e Event) SourceEvent() (paymentlog.SourceEvent, error) {
+	result := paymentlog.SourceEvent{ID: e.ID}
+	if !strings.HasPrefix(e.EventType, "PAYMENT.CAPTURE.") {
+		return result, nil
+	}
+	if len(e.Resource) == 0 {
+		return result, MissingResource
+	}
+	switch e.EventType {
+	case "PAYMENT.CAPTURE.REFUNDED", "PAYMENT.CAPTURE.REVERSED":
+		var refund Refund
+		if err := json.Unmarshal(e.Resource, &refund); err != nil {
+			return result, err
+		}
+		if refund.CaptureID() == "" {
+			return result, MissingResource
+		}
+		result.PaymentLogID = PaymentLogID(refund.CaptureID())
+		if refund.Status != "COMPLETED" {
+			return result, nil
+		}
+		// the total refunded so far tells a partial refund from the last
+		// of several; older deliveries only carry this refund's amount
+		refunded := refund.Amount
+		if total := refund.SellerPayableBreakdown.TotalRefundedAmount; total != nil {
+			refunded = *total
+		}
+		amount, err := paymentlog.ParseAmount(refunded.Value)
+		if err != nil {
+			return result, err
+		}
+		result.RefundedAmount = amount
+		status := paymentlog.StatusRefunded
+		updated := refund.UpdateTime
+		result.Change = &paymentlog.PaymentLogChange{
+			Status:  &status,
+			Updated: &updated,
+		}
+		return result, nil
+	}
+	var capture Capture
+	if err := json.Unmarshal(e.Resource, &capture); err != nil {
+		return result, err
+	}
+	if capture.ID == "" {
+		return result, MissingResource
+	}
+	log, err := capture.PaymentLog()
+	if err != nil {
+		return result, err
+	}
+	result.PaymentLogID = log.ID
+	result.PaymentLog = &log
+	result.Change = &paymentlog.PaymentLogChange{
+		Status:  &log.Status,
+		Updated: &log.Updated,
+	}
+	if log.Status == paymentlog.StatusFailed {
+		code := capture.ProcessorResponse.ResponseCode
+		if code == "" {
+			code = capture.StatusDetails.Reason
+		}
+		result.Failure = &paymentlog.FailureLog{
+			ID:                e.ID,
+			PaymentLogID:      log.ID,
+			Source:            paymentlog.SourcePayPal,
+			FailureReason:     e.Summary,
+			FailureReasonCode: code,
+			Timestamp:         e.CreateTime,
+		}
+	}
+	return result, nil
+}
+
+// Adapter is the paymentlog.SourceAdapter for PayPal. The zero value
+// accepts transmissions within DefaultTolerance.
+type Adapter struct {
+	Tolerance time.Duration
+}
+
+func (Adapter) Source() string {
+	return paymentlog.SourcePayPal
+}
+
+func (a Adapter) Verify(payload []byte, header http.Header, secret string) bool {
+	tolerance := a.Tolerance
+	if tolerance <= 0 {
+		tolerance = DefaultTolerance
+	}
+	return Verify(payload, header.Get(TransmissionIDHeader), header.Get(TransmissionTimeHeader), header.Get(TransmissionSigHeader), secret, tolerance)
+}
+
+func (Adapter) ParseEvents(payload []byte) ([]paymentlog.SourceEvent, error) {
+	event, err := ParseEvent(payload)
+	if err != nil {
+		return nil, err
+	}
+	normalized, err := event.SourceEvent()
+	if err != nil {
+		return nil, err
+	}
+	return []paymentlog.SourceEvent{normalized}, nil
+}
+
+func (Adapter) Status(processorStatus string) string {
+	switch processorStatus {
+	case "PENDING":
+		return paymentlog.StatusPending
+	case "COMPLETED":
+		return paymentlog.StatusSucceeded
+	case "DECLINED", "DENIED", "FAILED":
+		return paymentlog.StatusFailed
+	case "REFUNDED":
+		return paymentlog.StatusRefunded
+	}
+	return ""
+}
+
+// FailureCategories maps PayPal processor response codes and status detail
+// reasons onto failure categories.
+var FailureCategories = map[string]paymentlog.FailureCategory{
+	"5120":                           paymentlog.CategoryInsufficientFunds,
+	"0500":                           paymentlog.CategoryCardDeclined,
+	"5100":                           paymentlog.CategoryCardDeclined,
+	"5110":                           paymentlog.CategoryCardDeclined,
+	"5400":                           paymentlog.CategoryExpiredCard,
+	"1330":                           paymentlog.CategoryInvalidAccount,
+	"5180":                           paymentlog.CategoryInvalidAccount,
+	"9500":                           paymentlog.CategoryFraudSuspected,
+	"9520":                           paymentlog.CategoryFraudSuspected,
+	"DECLINED_BY_RISK_FRAUD_FILTERS": paymentlog.CategoryFraudSuspected,
+	"1000":                           paymentlog.CategoryProcessorError,
+	"INTERNAL_SERVER_ERROR":          paymentlog.CategoryProcessorError,
+	"9100":                           paymentlog.CategoryNetworkError,
+}
+
+func (Adapter) FailureCategory(code string) paymentlog.FailureCategory {
+	return FailureCategories[code]
+}
+
+func init() {
+	paymentlog.RegisterSource(Adapter{})
+}
+
+// NewHandler returns a handler for PayPal webhook events.
+func NewHandler(store paymentlog.LogStore, secret string) *paymentlog.WebhookHandler {
+	return paymentlog.NewWebhookHandler(store, Adapter{}, secret)
+}
@@ -0,0 +1,189 @@
+package paypal
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"code.whipround.net/paymentlog"
+)
+
+const (
+	testSecret    = "shh"
+	testCaptureID = "8MC585209K746392H"
+)
+
+func fixture(t *testing.T, name string) []byte {
+	body, err := ioutil.ReadFile(filepath.Join("testdata", name+".json"))
+	if err != nil {
+		t.Fatalf("Error reading fixture %s: %s", name, err)
+	}
+	return body
+}
+
+func deliver(t *testing.T, handler http.Handler, body []byte, transmissionID, transmissionTime, signature string) *httptest.ResponseRecorder {
+	r, err := http.NewRequest("POST", "/webhooks/paypal", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("Error creating request: %s", err)
+	}
+	r.Header.Set(TransmissionIDHeader, transmissionID)
+	r.Header.Set(TransmissionTimeHeader, transmissionTime)
+	r.Header.Set(TransmissionSigHeader, signature)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func deliverFixtures(t *testing.T, store paymentlog.LogStore, names ...string) {
+	handler := NewHandler(store, testSecret)
+	for _, name := range names {
+		body, sent := fixture(t, name), time.Now().Format(time.RFC3339)
+		w := deliver(t, handler, body, "transmission-"+name, sent, Sign(body, "transmission-"+name, sent, testSecret))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected status %d, got %d: %s", name, http.StatusOK, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestAdapterIsRegistered(t *testing.T) {
+	adapter, err := paymentlog.SourceAdapterFor(paymentlog.SourcePayPal)
+	if err != nil {
+		t.Fatalf("Error finding adapter: %s", err)
+	}
+	if _, ok := adapter.(Adapter); !ok {
+		t.Errorf("Expected the paypal adapter, got %T.", adapter)
+	}
+}
+
+func TestCaptureLifecycle(t *testing.T) {
+	store := paymentlog.NewMemoryStore()
+	deliverFixtures(t, store, "capture_pending", "capture_completed", "capture_refunded")
+	log, err := store.GetPaymentLog(PaymentLogID(testCaptureID))
+	if err != nil {
+		t.Fatalf("Error retrieving payment log: %s", err)
+	}
+	expectation := paymentlog.PaymentLog{
+		ID:          "paypal-" + testCaptureID,
+		Amount:      1250,
+		Description: "Contribution to project-id",
+		Source:      paymentlog.SourcePayPal,
+		SourceID:    testCaptureID,
+		Created:     time.Date(2014, time.March, 1, 12, 0, 0, 0, time.UTC),
+		Updated:     time.Date(2014, time.March, 2, 9, 40, 0, 0, time.UTC),
+		Status:      paymentlog.StatusRefunded,
+		Currency:    paymentlog.CurrencyUSD,
+		ProjectID:   "project-id",
+		UserID:      "user-id",
+		AccountID:   "account-id",
+		AccountType: "google",
+	}
+	if log.ID != expectation.ID || log.Amount != expectation.Amount || log.Status != expectation.Status ||
+		log.Description != expectation.Description || log.Currency != expectation.Currency ||
+		log.ProjectID != expectation.ProjectID || log.UserID != expectation.UserID ||
+		!log.Created.Equal(expectation.Created) || !log.Updated.Equal(expectation.Updated) {
+		t.Errorf("Expected %+v, got %+v.", expectation, log)
+	}
+}
+
+func TestReplayedEventsAreIdempotent(t *testing.T) {
+	store := paymentlog.NewMemoryStore()
+	deliverFixtures(t, store, "capture_pending", "capture_refunded", "capture_completed", "capture_pending", "capture_refunded")
+	log, err := store.GetPaymentLog(PaymentLogID(testCaptureID))
+	if err != nil {
+		t.Fatalf("Error retrieving payment log: %s", err)
+	}
+	if log.Status != paymentlog.StatusRefunded {
+		t.Errorf("Expected older events not to undo the refund, got status %s.", log.Status)
+	}
+}
+
+func TestPartialRefund(t *testing.T) {
+	store := paymentlog.NewMemoryStore()
+	deliverFixtures(t, store, "capture_pending", "capture_completed", "capture_partially_refunded")
+	log, err := store.GetPaymentLog(PaymentLogID(testCaptureID))
+	if err != nil {
+		t.Fatalf("Error retrieving payment log: %s", err)
+	}
+	if log.Status != paymentlog.StatusSucceeded {
+		t.Errorf("Expected a partial refund to leave the capture completed, got status %s.", log.Status)
+	}
+	deliverFixtures(t, store, "capture_refunded")
+	if log, _ := store.GetPaymentLog(PaymentLogID(testCaptureID)); log.Status != paymentlog.StatusRefunded {
+		t.Errorf("Expected a full refund to refund the capture, got status %s.", log.Status)
+	}
+}
+
+func TestDeniedCapture(t *testing.T) {
+	store := paymentlog.NewMemoryStore()
+	deliverFixtures(t, store, "capture_pending", "capture_denied", "capture_denied")
+	log, err := store.GetPaymentLog(PaymentLogID(testCaptureID))
+	if err != nil {
+		t.Fatalf("Error retrieving payment log: %s", err)
+	}
+	if log.Status != paymentlog.StatusFailed {
+		t.Errorf("Expected status %s, got %s.", paymentlog.StatusFailed, log.Status)
+	}
+	failures, err := store.ListFailureLogs(10, 0)
+	if err != nil {
+		t.Fatalf("Error listing failure logs: %s", err)
+	}
+	if len(failures) != 1 {
+		t.Fatalf("Expected 1 failure log, got %+v.", failures)
+	}
+	if failures[0].PaymentLogID != log.ID || failures[0].FailureReason != "A payment capture for $ 12.50 USD was denied." || failures[0].FailureReasonCode != "5120" {
+		t.Errorf("Unexpected failure log: %+v", failures[0])
+	}
+	if category := failures[0].Category(); category != paymentlog.CategoryInsufficientFunds {
+		t.Errorf("Expected category %s, got %s.", paymentlog.CategoryInsufficientFunds, category)
+	}
+}
+
+func TestRejectingUnsignedEvents(t *testing.T) {
+	store := paymentlog.NewMemoryStore()
+	handler := NewHandler(store, testSecret)
+	body := fixture(t, "capture_completed")
+	id, sent := "transmission-id", time.Now().Format(time.RFC3339)
+	stale := time.Now().Add(-time.Hour).Format(time.RFC3339)
+	forged := bytes.Replace(body, []byte("12.50"), []byte("92.50"), 1)
+	requests := [][3]string{
+		{id, sent, ""},
+		{id, sent, "not base64"},
+		{id, sent, Sign(body, id, sent, "wrong secret")},
+		{"other-id", sent, Sign(body, id, sent, testSecret)},
+		{"", sent, Sign(body, "", sent, testSecret)},
+		{id, "", Sign(body, id, "", testSecret)},
+		{id, stale, Sign(body, id, stale, testSecret)},
+	}
+	for _, request := range requests {
+		w := deliver(t, handler, body, request[0], request[1], request[2])
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("Expected status %d for %q, got %d.", http.StatusUnauthorized, request, w.Code)
+		}
+	}
+	if w := deliver(t, handler, forged, id, sent, Sign(body, id, sent, testSecret)); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status %d for an edited body, got %d.", http.StatusUnauthorized, w.Code)
+	}
+	logs, _ := store.ListPaymentLogs(10, 0)
+	if len(logs) != 0 {
+		t.Errorf("Expected unsigned events to be ignored, got %+v.", logs)
+	}
+}
+
+func TestStatusMapping(t *testing.T) {
+	expectations := map[string]string{
+		"PENDING":   paymentlog.StatusPending,
+		"COMPLETED": paymentlog.StatusSucceeded,
+		"DECLINED":  paymentlog.StatusFailed,
+		"REFUNDED":  paymentlog.StatusRefunded,
+		"VOIDED":    "",
+	}
+	for status, expectation := range expectations {
+		if result := (Adapter{}).Status(status); result != expectation {
+			t.Errorf("Expected %q to map to %q, got %q.", status, expectation, result)
+		}
+	}
+}
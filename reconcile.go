@@ -0,0 +1,269 @@
+package paymentlog
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+var MissingSettlementColumn = errors.New("Settlement report is missing a required column.")
+
+// SettlementFormat names the columns of a settlement report. Source may be
+// empty, or missing from the report, when every row is from the same source.
+type SettlementFormat struct {
+	Source   string
+	SourceID string
+	Amount   string
+	Currency string
+	Status   string
+}
+
+var DefaultSettlementFormat = SettlementFormat{
+	Source:   "source",
+	SourceID: "source_id",
+	Amount:   "amount",
+	Currency: "currency",
+	Status:   "status",
+}
+
+type SettlementRow struct {
+	Line     int
+	Source   string
+	SourceID string
+	Amount   uint
+	Currency string
+	// Status is mapped onto our statuses by the source's adapter, if one
+	// is registered and knows the status.
+	Status string
+}
+
+type SettlementRowError struct {
+	Line int
+	Err  error
+}
+
+func (s SettlementRowError) Error() string {
+	return fmt.Sprintf("Invalid settlement report line %d: %s", s.Line, s.Err)
+}
+
+func settlementStatus(source, status string) string {
+	if adapter, err := SourceAdapterFor(source); err == nil {
+		if mapped := adapter.Status(status); mapped != "" {
+			return mapped
+		}
+	}
+	return strings.ToLower(status)
+}
+
+// ParseSettlementReport reads a CSV settlement report with a header row.
+// Rows without a source column are from source.
+func ParseSettlementReport(r io.Reader, format SettlementFormat, source string) ([]SettlementRow, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err == io.EOF {
+		return []SettlementRow{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	columns := map[string]int{}
+	for pos, name := range header {
+		columns[strings.TrimSpace(name)] = pos
+	}
+	for _, name := range []string{format.SourceID, format.Amount, format.Currency, format.Status} {
+		if _, ok := columns[name]; !ok {
+			return nil, MissingSettlementColumn
+		}
+	}
+	sourceColumn, hasSource := columns[format.Source]
+	if format.Source == "" {
+		hasSource = false
+	}
+	if !hasSource && source == "" {
+		return nil, MissingSettlementColumn
+	}
+	rows := []SettlementRow{}
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		row := SettlementRow{
+			Line:     line,
+			Source:   source,
+			SourceID: record[columns[format.SourceID]],
+			Currency: strings.ToLower(record[columns[format.Currency]]),
+		}
+		if hasSource && record[sourceColumn] != "" {
+			row.Source = strings.ToLower(record[sourceColumn])
+		}
+		row.Amount, err = ParseAmount(record[columns[format.Amount]])
+		if err != nil {
+			return nil, SettlementRowError{Line: line, Err: err}
+		}
+		row.Status = settlementStatus(row.Source, record[columns[format.Status]])
+		rows = append(rows, row)
+	}
+}
+
+type DiscrepancyType string
+
+const (
+	MissingFromLog        DiscrepancyType = "missing_from_log"
+	MissingFromSettlement DiscrepancyType = "missing_from_settlement"
+	AmountMismatch        DiscrepancyType = "amount_mismatch"
+	CurrencyMismatch      DiscrepancyType = "currency_mismatch"
+	StatusMismatch        DiscrepancyType = "status_mismatch"
+)
+
+// Discrepancy is a difference between a settlement report and the payment
+// logs. Ours and Theirs hold the differing values; PaymentLogID is empty
+// for MissingFromLog and Line is 0 for MissingFromSettlement.
+type Discrepancy struct {
+	Type         DiscrepancyType `json:"type"`
+	Source       string          `json:"source"`
+	SourceID     string          `json:"source_id"`
+	PaymentLogID string          `json:"payment_log_id,omitempty"`
+	Line         int             `json:"line,omitempty"`
+	Ours         string          `json:"ours,omitempty"`
+	Theirs       string          `json:"theirs,omitempty"`
+}
+
+// Correction is a change that brings a payment log in line with the
+// settlement report.
+type Correction struct {
+	PaymentLogID string           `json:"payment_log_id"`
+	Change       PaymentLogChange `json:"change"`
+}
+
+type ReconciliationOptions struct {
+	// Since and Until limit the payment logs the report is expected to
+	// cover, by Created. Zero values leave that end of the range open.
+	Since time.Time
+	Until time.Time
+	// Corrections asks for a Correction for every payment log with an
+	// amount, currency or status discrepancy.
+	Corrections bool
+	// Updated is used as the Updated time of corrections. The zero value
+	// uses the current time.
+	Updated time.Time
+}
+
+type ReconciliationReport struct {
+	Matched       int           `json:"matched"`
+	Discrepancies []Discrepancy `json:"discrepancies"`
+	Corrections   []Correction  `json:"corrections,omitempty"`
+}
+
+func settlementKey(source, sourceID string) string {
+	return source + "\x00" + sourceID
+}
+
+// Reconcile matches settlement report rows to payment logs by Source and
+// SourceID and reports every discrepancy. Only payment logs from sources
+// that appear in rows are expected to be in the report. If a payment
+// appears in more than one row, the last row wins.
+func Reconcile(store LogStore, rows []SettlementRow, opts ReconciliationOptions) (ReconciliationReport, error) {
+	report := ReconciliationReport{Discrepancies: []Discrepancy{}}
+	updated := opts.Updated
+	if updated.IsZero() {
+		updated = time.Now()
+	}
+	settled := map[string]SettlementRow{}
+	sources := map[string]bool{}
+	for _, row := range rows {
+		settled[settlementKey(row.Source, row.SourceID)] = row
+		sources[row.Source] = true
+	}
+	seen := map[string]bool{}
+	expected := CreatedFilter(opts.Since, opts.Until)
+	err := store.IteratePaymentLogs(func(log PaymentLog) error {
+		if !sources[log.Source] {
+			return nil
+		}
+		key := settlementKey(log.Source, log.SourceID)
+		row, ok := settled[key]
+		if !ok {
+			if expected(log) {
+				report.Discrepancies = append(report.Discrepancies, Discrepancy{
+					Type:         MissingFromSettlement,
+					Source:       log.Source,
+					SourceID:     log.SourceID,
+					PaymentLogID: log.ID,
+				})
+			}
+			return nil
+		}
+		seen[key] = true
+		discrepancy := Discrepancy{
+			Source:       log.Source,
+			SourceID:     log.SourceID,
+			PaymentLogID: log.ID,
+			Line:         row.Line,
+		}
+		var change PaymentLogChange
+		if log.Amount != row.Amount {
+			discrepancy.Type, discrepancy.Ours, discrepancy.Theirs = AmountMismatch, FormatAmount(log.Amount), FormatAmount(row.Amount)
+			report.Discrepancies = append(report.Discrepancies, discrepancy)
+			amount := row.Amount
+			change.Amount = &amount
+		}
+		if log.Currency != row.Currency {
+			discrepancy.Type, discrepancy.Ours, discrepancy.Theirs = CurrencyMismatch, log.Currency, row.Currency
+			report.Discrepancies = append(report.Discrepancies, discrepancy)
+			currency := row.Currency
+			change.Currency = &currency
+		}
+		if log.Status != row.Status {
+			discrepancy.Type, discrepancy.Ours, discrepancy.Theirs = StatusMismatch, log.Status, row.Status
+			report.Discrepancies = append(report.Discrepancies, discrepancy)
+			status := row.Status
+			change.Status = &status
+		}
+		if len(change.Fields()) == 0 {
+			report.Matched++
+			return nil
+		}
+		if opts.Corrections {
+			change.Updated = &updated
+			report.Corrections = append(report.Corrections, Correction{PaymentLogID: log.ID, Change: change})
+		}
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+	for _, row := range rows {
+		key := settlementKey(row.Source, row.SourceID)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		row = settled[key]
+		report.Discrepancies = append(report.Discrepancies, Discrepancy{
+			Type:     MissingFromLog,
+			Source:   row.Source,
+			SourceID: row.SourceID,
+			Line:     row.Line,
+			Theirs:   FormatAmount(row.Amount) + " " + row.Currency + " " + row.Status,
+		})
+	}
+	return report, nil
+}
+
+// ApplyCorrections applies the corrections from a ReconciliationReport, in
+// order, stopping at the first error.
+func ApplyCorrections(store LogStore, corrections []Correction) error {
+	for _, correction := range corrections {
+		if err := store.UpdatePaymentLog(correction.PaymentLogID, correction.Change); err != nil {
+			return err
+		}
+	}
+	return nil
+}
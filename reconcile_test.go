@@ -0,0 +1,125 @@
+package paymentlog
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func reconcileTestStore() *MemoryStore {
+	store := NewMemoryStore()
+	created := time.Date(2014, time.March, 1, 12, 0, 0, 0, time.UTC)
+	for pos, id := range []string{"matched", "pending", "short", "euros", "unsettled", "old"} {
+		log := PaymentLog{
+			ID:          "balanced-" + id,
+			Amount:      1250,
+			Source:      SourceBalanced,
+			SourceID:    id,
+			Created:     created.Add(time.Duration(pos) * time.Hour),
+			Status:      StatusSucceeded,
+			Currency:    CurrencyUSD,
+			ProjectID:   "project-id",
+			UserID:      "user-id",
+			AccountID:   "account-id",
+			AccountType: "google",
+		}
+		if id == "pending" {
+			log.Status = StatusPending
+		}
+		if id == "old" {
+			log.Created = created.Add(-48 * time.Hour)
+		}
+		store.paymentLogs[log.ID] = &log
+	}
+	other := *store.paymentLogs["balanced-matched"]
+	other.ID, other.Source = "stripe-matched", SourceStripe
+	store.paymentLogs[other.ID] = &other
+	return store
+}
+
+const testSettlementReport = `source_id,amount,currency,status,fee
+matched,12.50,USD,succeeded,0.66
+pending,12.50,USD,succeeded,0.66
+short,12.00,USD,succeeded,0.66
+euros,12.50,EUR,succeeded,0.66
+theirs,3.00,USD,succeeded,0.39
+`
+
+func TestParsingSettlementReports(t *testing.T) {
+	rows, err := ParseSettlementReport(strings.NewReader(testSettlementReport), DefaultSettlementFormat, SourceBalanced)
+	if err != nil {
+		t.Fatalf("Error parsing settlement report: %s", err)
+	}
+	if len(rows) != 5 {
+		t.Fatalf("Expected 5 rows, got %+v.", rows)
+	}
+	expectation := SettlementRow{Line: 4, Source: SourceBalanced, SourceID: "short", Amount: 1200, Currency: CurrencyUSD, Status: StatusSucceeded}
+	if rows[2] != expectation {
+		t.Errorf("Expected %+v, got %+v.", expectation, rows[2])
+	}
+	_, err = ParseSettlementReport(strings.NewReader(testSettlementReport), DefaultSettlementFormat, "")
+	if err != MissingSettlementColumn {
+		t.Errorf("Expected %s without a source, got %v.", MissingSettlementColumn, err)
+	}
+	_, err = ParseSettlementReport(strings.NewReader("source_id,amount,status\n"), DefaultSettlementFormat, SourceBalanced)
+	if err != MissingSettlementColumn {
+		t.Errorf("Expected %s without a currency column, got %v.", MissingSettlementColumn, err)
+	}
+	_, err = ParseSettlementReport(strings.NewReader("source_id,amount,currency,status\nid,twelve,usd,succeeded\n"), DefaultSettlementFormat, SourceBalanced)
+	if rowErr, ok := err.(SettlementRowError); !ok || rowErr.Line != 2 || rowErr.Err != InvalidAmount {
+		t.Errorf("Expected an invalid amount on line 2, got %v.", err)
+	}
+}
+
+func TestReconciling(t *testing.T) {
+	store := reconcileTestStore()
+	rows, err := ParseSettlementReport(strings.NewReader(testSettlementReport), DefaultSettlementFormat, SourceBalanced)
+	if err != nil {
+		t.Fatalf("Error parsing settlement report: %s", err)
+	}
+	updated := time.Date(2014, time.March, 5, 0, 0, 0, 0, time.UTC)
+	report, err := Reconcile(store, rows, ReconciliationOptions{
+		Since:       time.Date(2014, time.March, 1, 0, 0, 0, 0, time.UTC),
+		Corrections: true,
+		Updated:     updated,
+	})
+	if err != nil {
+		t.Fatalf("Error reconciling: %s", err)
+	}
+	if report.Matched != 1 {
+		t.Errorf("Expected 1 match, got %d.", report.Matched)
+	}
+	expectations := []Discrepancy{
+		{Type: MissingFromSettlement, Source: SourceBalanced, SourceID: "unsettled", PaymentLogID: "balanced-unsettled"},
+		{Type: CurrencyMismatch, Source: SourceBalanced, SourceID: "euros", PaymentLogID: "balanced-euros", Line: 5, Ours: "usd", Theirs: "eur"},
+		{Type: AmountMismatch, Source: SourceBalanced, SourceID: "short", PaymentLogID: "balanced-short", Line: 4, Ours: "12.50", Theirs: "12.00"},
+		{Type: StatusMismatch, Source: SourceBalanced, SourceID: "pending", PaymentLogID: "balanced-pending", Line: 3, Ours: StatusPending, Theirs: StatusSucceeded},
+		{Type: MissingFromLog, Source: SourceBalanced, SourceID: "theirs", Line: 6, Theirs: "3.00 usd succeeded"},
+	}
+	if len(report.Discrepancies) != len(expectations) {
+		t.Fatalf("Expected %d discrepancies, got %+v.", len(expectations), report.Discrepancies)
+	}
+	for pos, expectation := range expectations {
+		if report.Discrepancies[pos] != expectation {
+			t.Errorf("Expected discrepancy %d to be %+v, got %+v.", pos, expectation, report.Discrepancies[pos])
+		}
+	}
+	if len(report.Corrections) != 3 {
+		t.Fatalf("Expected 3 corrections, got %+v.", report.Corrections)
+	}
+	if err := ApplyCorrections(store, report.Corrections); err != nil {
+		t.Fatalf("Error applying corrections: %s", err)
+	}
+	for _, id := range []string{"balanced-pending", "balanced-short", "balanced-euros"} {
+		if !store.paymentLogs[id].Updated.Equal(updated) {
+			t.Errorf("Expected %s to be corrected at %s, got %+v.", id, updated, store.paymentLogs[id])
+		}
+	}
+	report, err = Reconcile(store, rows, ReconciliationOptions{Since: time.Date(2014, time.March, 1, 0, 0, 0, 0, time.UTC)})
+	if err != nil {
+		t.Fatalf("Error reconciling: %s", err)
+	}
+	if report.Matched != 4 || len(report.Discrepancies) != 2 || report.Corrections != nil {
+		t.Errorf("Expected corrections to resolve the mismatches, got %+v.", report)
+	}
+}
@@ -0,0 +1,108 @@
+package paymentlog
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	FailureOpen         = "open"
+	FailureAcknowledged = "acknowledged"
+	FailureResolved     = "resolved"
+	FailureIgnored      = "ignored"
+)
+
+var (
+	InvalidFailureState      = errors.New("Invalid failure log state.")
+	InvalidFailureTransition = errors.New("Invalid failure log state transition.")
+	MissingResolver          = errors.New("Missing failure log resolver.")
+	FailureStatesUnsupported = errors.New("Store doesn't track failure log states.")
+)
+
+// FailureStateChange moves a failure log to State. Resolver is required for
+// every state but FailureOpen. A zero Updated means now.
+type FailureStateChange struct {
+	State    string    `json:"state"`
+	Resolver string    `json:"resolver,omitempty"`
+	Note     string    `json:"note,omitempty"`
+	Updated  time.Time `json:"updated,omitempty"`
+}
+
+// FailureStateStore tracks the resolution state of failure logs.
+type FailureStateStore interface {
+	UpdateFailureLogState(id string, change FailureStateChange) error
+	// ListOpenFailureLogs lists failure logs that haven't been
+	// acknowledged, resolved or ignored, oldest first.
+	ListOpenFailureLogs(num, offset int) ([]FailureLog, error)
+}
+
+var failureTransitions = map[string][]string{
+	FailureOpen:         {FailureAcknowledged, FailureResolved, FailureIgnored},
+	FailureAcknowledged: {FailureOpen, FailureResolved, FailureIgnored},
+	FailureResolved:     {FailureOpen},
+	FailureIgnored:      {FailureOpen},
+}
+
+func validFailureState(state string) bool {
+	_, ok := failureTransitions[state]
+	return ok
+}
+
+// CurrentState returns the failure's state, treating an empty state as
+// FailureOpen.
+func (f FailureLog) CurrentState() string {
+	if f.State == "" {
+		return FailureOpen
+	}
+	return f.State
+}
+
+func (f FailureLog) Open() bool {
+	return f.CurrentState() == FailureOpen
+}
+
+// ApplyStateChange returns the failure with change applied, or an error if
+// the failure can't move to the change's state. Resolved and ignored
+// failures can only be reopened.
+func (f FailureLog) ApplyStateChange(change FailureStateChange) (FailureLog, error) {
+	if !validFailureState(change.State) {
+		return f, InvalidFailureState
+	}
+	if change.State != FailureOpen && change.Resolver == "" {
+		return f, MissingResolver
+	}
+	allowed := false
+	for _, state := range failureTransitions[f.CurrentState()] {
+		if state == change.State {
+			allowed = true
+		}
+	}
+	if !allowed {
+		return f, InvalidFailureTransition
+	}
+	if change.Updated.IsZero() {
+		change.Updated = time.Now()
+	}
+	f.State = change.State
+	f.Resolver = change.Resolver
+	f.ResolutionNote = change.Note
+	f.StateUpdated = change.Updated
+	return f, nil
+}
+
+type oldestFailures []FailureLog
+
+func (o oldestFailures) Len() int {
+	return len(o)
+}
+
+func (o oldestFailures) Swap(i, j int) {
+	o[i], o[j] = o[j], o[i]
+}
+
+func (o oldestFailures) Less(i, j int) bool {
+	if o[i].Timestamp.Equal(o[j].Timestamp) {
+		return o[i].ID < o[j].ID
+	}
+	return o[i].Timestamp.Before(o[j].Timestamp)
+}
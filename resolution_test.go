@@ -0,0 +1,143 @@
+package paymentlog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func storeResolutionTestFailures(t *testing.T, store LogStore, start time.Time) {
+	for pos, id := range []string{"f1", "f2", "f3", "f4"} {
+		err := store.StoreFailureLog(FailureLog{
+			ID:                id,
+			PaymentLogID:      "payment-" + id,
+			FailureReasonCode: "card-declined",
+			Timestamp:         start.Add(time.Duration(pos) * time.Hour),
+		})
+		if err != nil {
+			t.Fatalf("Error storing failure log: %s", err)
+		}
+	}
+}
+
+func TestFailureStateTransitions(t *testing.T) {
+	store := NewMemoryStore()
+	start := time.Date(2014, time.March, 1, 12, 0, 0, 0, time.UTC)
+	storeResolutionTestFailures(t, store, start)
+
+	changes := []struct {
+		id     string
+		change FailureStateChange
+		err    error
+	}{
+		{"f1", FailureStateChange{State: FailureAcknowledged, Resolver: "alice", Note: "looking into it"}, nil},
+		{"f1", FailureStateChange{State: FailureAcknowledged, Resolver: "alice"}, InvalidFailureTransition},
+		{"f1", FailureStateChange{State: FailureResolved, Resolver: "alice", Note: "refunded"}, nil},
+		{"f1", FailureStateChange{State: FailureIgnored, Resolver: "alice"}, InvalidFailureTransition},
+		{"f2", FailureStateChange{State: FailureIgnored}, MissingResolver},
+		{"f2", FailureStateChange{State: "closed", Resolver: "bob"}, InvalidFailureState},
+		{"f2", FailureStateChange{State: FailureOpen}, InvalidFailureTransition},
+		{"f3", FailureStateChange{State: FailureIgnored, Resolver: "bob", Note: "test card"}, nil},
+		{"f3", FailureStateChange{State: FailureOpen, Note: "not a test card"}, nil},
+		{"missing", FailureStateChange{State: FailureResolved, Resolver: "bob"}, FailureLogNotFound},
+	}
+	for _, c := range changes {
+		if err := store.UpdateFailureLogState(c.id, c.change); err != c.err {
+			t.Errorf("Expected %+v on %s to return %v, got %v.", c.change, c.id, c.err, err)
+		}
+	}
+
+	failures, err := store.ListFailureLogs(0, 0)
+	if err != nil {
+		t.Fatalf("Error listing failure logs: %s", err)
+	}
+	states := map[string]FailureLog{}
+	for _, failure := range failures {
+		states[failure.ID] = failure
+	}
+	if f := states["f1"]; f.State != FailureResolved || f.Resolver != "alice" || f.ResolutionNote != "refunded" || f.StateUpdated.IsZero() {
+		t.Errorf("Unexpected resolved failure: %+v", f)
+	}
+	if f := states["f2"]; f.State != "" || !f.Open() {
+		t.Errorf("Expected f2 to be untouched, got %+v.", f)
+	}
+	if f := states["f3"]; f.State != FailureOpen || f.ResolutionNote != "not a test card" {
+		t.Errorf("Unexpected reopened failure: %+v", f)
+	}
+}
+
+func TestListingOpenFailureLogs(t *testing.T) {
+	store := NewMemoryStore()
+	start := time.Date(2014, time.March, 1, 12, 0, 0, 0, time.UTC)
+	storeResolutionTestFailures(t, store, start)
+	if err := store.UpdateFailureLogState("f2", FailureStateChange{State: FailureAcknowledged, Resolver: "alice"}); err != nil {
+		t.Fatalf("Error acknowledging failure log: %s", err)
+	}
+
+	open, err := store.ListOpenFailureLogs(0, 0)
+	if err != nil {
+		t.Fatalf("Error listing open failure logs: %s", err)
+	}
+	if len(open) != 3 || open[0].ID != "f1" || open[1].ID != "f3" || open[2].ID != "f4" {
+		t.Errorf("Expected [f1 f3 f4], got %+v.", open)
+	}
+	open, err = store.ListOpenFailureLogs(1, 1)
+	if err != nil || len(open) != 1 || open[0].ID != "f3" {
+		t.Errorf("Expected [f3], got %+v, %v.", open, err)
+	}
+}
+
+func TestFailureStateChangesAreFed(t *testing.T) {
+	store := NewMemoryStore()
+	start := time.Date(2014, time.March, 1, 12, 0, 0, 0, time.UTC)
+	storeResolutionTestFailures(t, store, start)
+	sub, err := store.Subscribe(4, 10)
+	if err != nil {
+		t.Fatalf("Error subscribing: %s", err)
+	}
+	defer sub.Close()
+	updated := start.Add(24 * time.Hour)
+	if err := store.UpdateFailureLogState("f1", FailureStateChange{State: FailureResolved, Resolver: "alice", Updated: updated}); err != nil {
+		t.Fatalf("Error resolving failure log: %s", err)
+	}
+	event := nextEvent(t, sub)
+	if event.Type != EventFailureLogUpdated || event.PaymentLogID != "payment-f1" || event.FailureLog == nil ||
+		event.FailureLog.State != FailureResolved || !event.FailureLog.StateUpdated.Equal(updated) {
+		t.Errorf("Unexpected event: %+v", event)
+	}
+}
+
+func TestFailureStateSurvivesCSV(t *testing.T) {
+	store := NewMemoryStore()
+	start := time.Date(2014, time.March, 1, 12, 0, 0, 0, time.UTC)
+	storeResolutionTestFailures(t, store, start)
+	updated := start.Add(24 * time.Hour)
+	if err := store.UpdateFailureLogState("f1", FailureStateChange{State: FailureIgnored, Resolver: "alice", Note: "duplicate, see f2", Updated: updated}); err != nil {
+		t.Fatalf("Error ignoring failure log: %s", err)
+	}
+	var buf bytes.Buffer
+	enc := NewFailureLogCSVEncoder(&buf)
+	if err := ExportFailureLogs(store, enc); err != nil {
+		t.Fatalf("Error exporting failure logs: %s", err)
+	}
+	imported := NewMemoryStore()
+	if _, err := ImportFailureLogs(imported, NewFailureLogCSVDecoder(&buf)); err != nil {
+		t.Fatalf("Error importing failure logs: %s", err)
+	}
+	open, _ := imported.ListOpenFailureLogs(0, 0)
+	if len(open) != 3 {
+		t.Errorf("Expected 3 open failure logs, got %+v.", open)
+	}
+
+	// exports from before failure logs had a state import as open
+	legacy := "id,payment_log_id,failure_reason,failure_reason_code,timestamp,source\nf9,payment,declined,card-declined,2014-03-01T12:00:00Z,balanced\n"
+	imported = NewMemoryStore()
+	if _, err := ImportFailureLogs(imported, NewFailureLogCSVDecoder(strings.NewReader(legacy))); err != nil {
+		t.Fatalf("Error importing legacy failure logs: %s", err)
+	}
+	open, _ = imported.ListOpenFailureLogs(0, 0)
+	if len(open) != 1 || open[0].ID != "f9" || open[0].Source != SourceBalanced {
+		t.Errorf("Expected [f9], got %+v.", open)
+	}
+}
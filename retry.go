@@ -0,0 +1,230 @@
+package paymentlog
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	RetryScheduled    = "scheduled"
+	RetryInProgress   = "in_progress"
+	RetryExhausted    = "exhausted"
+	RetryNotRetryable = "not_retryable"
+)
+
+var (
+	RetryScheduleNotFound = errors.New("Retry schedule not found.")
+	RetryNotDue           = errors.New("Retry is not due.")
+)
+
+// RetrySchedule tracks the retries of a failed payment. Attempts counts
+// the failed attempts so far, including the original one.
+type RetrySchedule struct {
+	PaymentLogID  string    `json:"payment_log_id"`
+	State         string    `json:"state"`
+	Attempts      int       `json:"attempts"`
+	NextAttempt   time.Time `json:"next_attempt"`
+	LastFailureID string    `json:"last_failure_id"`
+	LastFailure   time.Time `json:"last_failure"`
+}
+
+type RetryStore interface {
+	// SaveRetrySchedule creates or replaces the schedule for its payment
+	// log.
+	SaveRetrySchedule(schedule RetrySchedule) error
+	GetRetrySchedule(paymentLogID string) (RetrySchedule, error)
+	DeleteRetrySchedule(paymentLogID string) error
+	// ListDueRetries lists scheduled retries whose next attempt is at or
+	// before now, most overdue first. A num of 0 or less lists all of them.
+	ListDueRetries(now time.Time, num int) ([]RetrySchedule, error)
+	// ClaimRetry moves a due retry to RetryInProgress, so only one worker
+	// attempts it. It returns RetryNotDue if the retry isn't scheduled or
+	// isn't due yet.
+	ClaimRetry(paymentLogID string, now time.Time) (RetrySchedule, error)
+}
+
+type RetryPolicy struct {
+	// MaxAttempts is the most attempts a payment gets, including the
+	// original one.
+	MaxAttempts  int
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+	Multiplier   float64
+	// Retryable decides whether a failure is worth retrying. If nil,
+	// RetryableFailure is used.
+	Retryable func(failure FailureLog) bool
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:  4,
+	InitialDelay: time.Hour,
+	MaxDelay:     72 * time.Hour,
+	Multiplier:   4,
+}
+
+// RetryableFailure reports whether a failure might not happen again if
+// the payment is retried later: insufficient funds, processor errors and
+// network errors.
+func RetryableFailure(failure FailureLog) bool {
+	switch failure.Category() {
+	case CategoryInsufficientFunds, CategoryProcessorError, CategoryNetworkError:
+		return true
+	}
+	return false
+}
+
+// Delay returns how long to wait after the attempts'th failed attempt
+// before trying again.
+func (p RetryPolicy) Delay(attempts int) time.Duration {
+	delay := float64(p.InitialDelay)
+	for i := 1; i < attempts; i++ {
+		delay *= p.Multiplier
+		if delay >= float64(p.MaxDelay) {
+			return p.MaxDelay
+		}
+	}
+	if delay >= float64(p.MaxDelay) {
+		return p.MaxDelay
+	}
+	return time.Duration(delay)
+}
+
+// RetryScheduler turns failure logs into retry schedules, for workers to
+// poll with Due.
+type RetryScheduler struct {
+	Retries RetryStore
+	Policy  RetryPolicy
+	// Logs, if set, is used to resolve the Source of failures logged
+	// without one, so they can be categorized.
+	Logs LogStore
+}
+
+// NewRetryScheduler returns a scheduler using policy. Zero fields in
+// policy are taken from DefaultRetryPolicy. If retries is also a LogStore,
+// it's used to resolve failure sources.
+func NewRetryScheduler(retries RetryStore, policy RetryPolicy) *RetryScheduler {
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = DefaultRetryPolicy.MaxAttempts
+	}
+	if policy.InitialDelay <= 0 {
+		policy.InitialDelay = DefaultRetryPolicy.InitialDelay
+	}
+	if policy.MaxDelay <= 0 {
+		policy.MaxDelay = DefaultRetryPolicy.MaxDelay
+	}
+	if policy.Multiplier < 1 {
+		policy.Multiplier = DefaultRetryPolicy.Multiplier
+	}
+	if policy.Retryable == nil {
+		policy.Retryable = RetryableFailure
+	}
+	logs, _ := retries.(LogStore)
+	return &RetryScheduler{Retries: retries, Policy: policy, Logs: logs}
+}
+
+// Schedule records a failed attempt at a payment and schedules its next
+// attempt, if it gets one. Failures at or before the schedule's last
+// failure have already been counted and are ignored.
+func (s *RetryScheduler) Schedule(failure FailureLog) (RetrySchedule, error) {
+	if failure.PaymentLogID == "" {
+		return RetrySchedule{}, MissingID
+	}
+	if s.Logs != nil {
+		failure = ResolveFailureSource(s.Logs, failure)
+	}
+	schedule, err := s.Retries.GetRetrySchedule(failure.PaymentLogID)
+	if err == RetryScheduleNotFound {
+		schedule, err = RetrySchedule{PaymentLogID: failure.PaymentLogID}, nil
+	}
+	if err != nil {
+		return schedule, err
+	}
+	if schedule.LastFailureID != "" && !failure.Timestamp.After(schedule.LastFailure) {
+		return schedule, nil
+	}
+	schedule.Attempts++
+	schedule.LastFailureID = failure.ID
+	schedule.LastFailure = failure.Timestamp
+	schedule.NextAttempt = time.Time{}
+	switch {
+	case !s.Policy.Retryable(failure):
+		schedule.State = RetryNotRetryable
+	case schedule.Attempts >= s.Policy.MaxAttempts:
+		schedule.State = RetryExhausted
+	default:
+		schedule.State = RetryScheduled
+		schedule.NextAttempt = failure.Timestamp.Add(s.Policy.Delay(schedule.Attempts))
+	}
+	return schedule, s.Retries.SaveRetrySchedule(schedule)
+}
+
+// ScheduleFailuresSince schedules every failure logged after since, oldest
+// first, and returns how many retries are scheduled as a result.
+func (s *RetryScheduler) ScheduleFailuresSince(store LogStore, since time.Time) (int, error) {
+	failures, err := store.ListFailureLogsSince(since)
+	if err != nil {
+		return 0, err
+	}
+	scheduled := map[string]bool{}
+	for pos := len(failures) - 1; pos >= 0; pos-- {
+		schedule, err := s.Schedule(failures[pos])
+		if err != nil {
+			return len(scheduled), err
+		}
+		scheduled[schedule.PaymentLogID] = schedule.State == RetryScheduled
+	}
+	count := 0
+	for _, ok := range scheduled {
+		if ok {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// Run schedules failures as they're stored, until sub is closed.
+func (s *RetryScheduler) Run(sub *Subscription) error {
+	for event := range sub.Events() {
+		if event.Type != EventFailureLogStored || event.FailureLog == nil {
+			continue
+		}
+		if _, err := s.Schedule(*event.FailureLog); err != nil {
+			return err
+		}
+	}
+	return sub.Err()
+}
+
+func (s *RetryScheduler) Due(now time.Time, num int) ([]RetrySchedule, error) {
+	return s.Retries.ListDueRetries(now, num)
+}
+
+func (s *RetryScheduler) Claim(paymentLogID string, now time.Time) (RetrySchedule, error) {
+	return s.Retries.ClaimRetry(paymentLogID, now)
+}
+
+// Succeeded forgets the schedule of a payment whose retry succeeded.
+func (s *RetryScheduler) Succeeded(paymentLogID string) error {
+	err := s.Retries.DeleteRetrySchedule(paymentLogID)
+	if err == RetryScheduleNotFound {
+		return nil
+	}
+	return err
+}
+
+type retriesByNextAttempt []RetrySchedule
+
+func (r retriesByNextAttempt) Len() int {
+	return len(r)
+}
+
+func (r retriesByNextAttempt) Swap(i, j int) {
+	r[i], r[j] = r[j], r[i]
+}
+
+func (r retriesByNextAttempt) Less(i, j int) bool {
+	if r[i].NextAttempt.Equal(r[j].NextAttempt) {
+		return r[i].PaymentLogID < r[j].PaymentLogID
+	}
+	return r[i].NextAttempt.Before(r[j].NextAttempt)
+}
@@ -0,0 +1,184 @@
+package paymentlog
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func retryTestFailure(id, paymentLogID, code string, timestamp time.Time) FailureLog {
+	return FailureLog{
+		ID:                id,
+		PaymentLogID:      paymentLogID,
+		Source:            "test-source",
+		FailureReasonCode: code,
+		Timestamp:         timestamp,
+	}
+}
+
+func TestRetryDelays(t *testing.T) {
+	policy := RetryPolicy{InitialDelay: time.Minute, MaxDelay: time.Hour, Multiplier: 3}
+	expectations := map[int]time.Duration{
+		1: time.Minute,
+		2: 3 * time.Minute,
+		3: 9 * time.Minute,
+		4: 27 * time.Minute,
+		5: time.Hour,
+		9: time.Hour,
+	}
+	for attempts, expectation := range expectations {
+		if result := policy.Delay(attempts); result != expectation {
+			t.Errorf("Expected a delay of %s after %d attempts, got %s.", expectation, attempts, result)
+		}
+	}
+}
+
+func TestSchedulingRetries(t *testing.T) {
+	if _, err := SourceAdapterFor("test-source"); err == UnknownSource {
+		RegisterSource(testAdapter{})
+	}
+	store := NewMemoryStore()
+	scheduler := NewRetryScheduler(store, RetryPolicy{MaxAttempts: 3, InitialDelay: time.Hour, Multiplier: 2})
+	start := time.Date(2014, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+	schedule, err := scheduler.Schedule(retryTestFailure("f1", "payment", string(CategoryInsufficientFunds), start))
+	if err != nil {
+		t.Fatalf("Error scheduling retry: %s", err)
+	}
+	if schedule.State != RetryScheduled || schedule.Attempts != 1 || !schedule.NextAttempt.Equal(start.Add(time.Hour)) {
+		t.Errorf("Unexpected schedule after the first failure: %+v", schedule)
+	}
+	// replays of counted failures don't count again
+	schedule, err = scheduler.Schedule(retryTestFailure("f1", "payment", string(CategoryInsufficientFunds), start))
+	if err != nil || schedule.Attempts != 1 {
+		t.Errorf("Expected a replayed failure to be ignored, got %+v, %v.", schedule, err)
+	}
+
+	due, err := scheduler.Due(start.Add(59*time.Minute), 0)
+	if err != nil || len(due) != 0 {
+		t.Errorf("Expected no due retries yet, got %+v, %v.", due, err)
+	}
+	if _, err := scheduler.Claim("payment", start.Add(59*time.Minute)); err != RetryNotDue {
+		t.Errorf("Expected %s, got %v.", RetryNotDue, err)
+	}
+	due, err = scheduler.Due(start.Add(time.Hour), 0)
+	if err != nil || len(due) != 1 || due[0].PaymentLogID != "payment" {
+		t.Fatalf("Expected payment to be due, got %+v, %v.", due, err)
+	}
+	schedule, err = scheduler.Claim("payment", start.Add(time.Hour))
+	if err != nil || schedule.State != RetryInProgress {
+		t.Fatalf("Expected to claim the retry, got %+v, %v.", schedule, err)
+	}
+	if _, err := scheduler.Claim("payment", start.Add(time.Hour)); err != RetryNotDue {
+		t.Errorf("Expected a claimed retry not to be claimed twice, got %v.", err)
+	}
+
+	retried := start.Add(time.Hour + time.Minute)
+	schedule, err = scheduler.Schedule(retryTestFailure("f2", "payment", string(CategoryNetworkError), retried))
+	if err != nil {
+		t.Fatalf("Error scheduling retry: %s", err)
+	}
+	if schedule.State != RetryScheduled || schedule.Attempts != 2 || !schedule.NextAttempt.Equal(retried.Add(2*time.Hour)) {
+		t.Errorf("Unexpected schedule after the second failure: %+v", schedule)
+	}
+	schedule, err = scheduler.Schedule(retryTestFailure("f3", "payment", string(CategoryNetworkError), retried.Add(3*time.Hour)))
+	if err != nil || schedule.State != RetryExhausted || schedule.Attempts != 3 || !schedule.NextAttempt.IsZero() {
+		t.Errorf("Expected retries to be exhausted, got %+v, %v.", schedule, err)
+	}
+
+	schedule, err = scheduler.Schedule(retryTestFailure("f4", "other-payment", string(CategoryFraudSuspected), start))
+	if err != nil || schedule.State != RetryNotRetryable {
+		t.Errorf("Expected a fraud block not to be retried, got %+v, %v.", schedule, err)
+	}
+	due, err = scheduler.Due(start.Add(24*time.Hour), 0)
+	if err != nil || len(due) != 0 {
+		t.Errorf("Expected no due retries, got %+v, %v.", due, err)
+	}
+	if err := scheduler.Succeeded("payment"); err != nil {
+		t.Errorf("Error completing retry: %s", err)
+	}
+	if _, err := store.GetRetrySchedule("payment"); err != RetryScheduleNotFound {
+		t.Errorf("Expected %s, got %v.", RetryScheduleNotFound, err)
+	}
+}
+
+func TestSchedulingFailuresSince(t *testing.T) {
+	if _, err := SourceAdapterFor("test-source"); err == UnknownSource {
+		RegisterSource(testAdapter{})
+	}
+	store := NewMemoryStore()
+	start := time.Date(2014, time.March, 1, 12, 0, 0, 0, time.UTC)
+	failures := []FailureLog{
+		retryTestFailure("f1", "a", string(CategoryInsufficientFunds), start.Add(time.Minute)),
+		retryTestFailure("f2", "a", string(CategoryInsufficientFunds), start.Add(2*time.Minute)),
+		retryTestFailure("f3", "b", string(CategoryProcessorError), start.Add(3*time.Minute)),
+		retryTestFailure("f4", "c", string(CategoryExpiredCard), start.Add(4*time.Minute)),
+	}
+	for _, failure := range failures {
+		if err := store.StoreFailureLog(failure); err != nil {
+			t.Fatalf("Error storing failure log: %s", err)
+		}
+	}
+	scheduler := NewRetryScheduler(store, DefaultRetryPolicy)
+	scheduled, err := scheduler.ScheduleFailuresSince(store, start)
+	if err != nil {
+		t.Fatalf("Error scheduling retries: %s", err)
+	}
+	if scheduled != 2 {
+		t.Errorf("Expected 2 retries to be scheduled, got %d.", scheduled)
+	}
+	schedule, err := store.GetRetrySchedule("a")
+	if err != nil || schedule.Attempts != 2 || schedule.LastFailureID != "f2" {
+		t.Errorf("Expected both failures of a to count, got %+v, %v.", schedule, err)
+	}
+	due, err := scheduler.Due(start.Add(365*24*time.Hour), 1)
+	if err != nil || len(due) != 1 || due[0].PaymentLogID != "b" {
+		t.Errorf("Expected b to be the most overdue, got %+v, %v.", due, err)
+	}
+
+	var buf bytes.Buffer
+	if err := store.Snapshot(&buf); err != nil {
+		t.Fatalf("Error taking snapshot: %s", err)
+	}
+	restored, err := LoadMemoryStore(&buf)
+	if err != nil {
+		t.Fatalf("Error loading snapshot: %s", err)
+	}
+	if len(restored.retries) != 3 || restored.retries["a"].Attempts != 2 {
+		t.Errorf("Expected the retry schedules to be restored, got %+v.", restored.retries)
+	}
+}
+
+func TestRunningRetryScheduler(t *testing.T) {
+	if _, err := SourceAdapterFor("test-source"); err == UnknownSource {
+		RegisterSource(testAdapter{})
+	}
+	store := NewMemoryStore()
+	sub, err := store.Subscribe(0, 0)
+	if err != nil {
+		t.Fatalf("Error subscribing: %s", err)
+	}
+	scheduler := NewRetryScheduler(store, DefaultRetryPolicy)
+	done := make(chan error)
+	go func() {
+		done <- scheduler.Run(sub)
+	}()
+	now := time.Now()
+	if err := store.StoreFailureLog(retryTestFailure("f1", "a", string(CategoryNetworkError), now)); err != nil {
+		t.Fatalf("Error storing failure log: %s", err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		if _, err := store.GetRetrySchedule("a"); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the stored failure to be scheduled.")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	sub.Close()
+	if err := <-done; err != nil {
+		t.Errorf("Expected Run to stop cleanly, got %s.", err)
+	}
+}
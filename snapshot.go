@@ -0,0 +1,123 @@
+package paymentlog
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+)
+
+const SnapshotVersion = 1
+
+var UnsupportedSnapshotVersion = errors.New("Unsupported snapshot version.")
+
+type snapshot struct {
+	Version     int          `json:"version"`
+	PaymentLogs []PaymentLog `json:"payment_logs"`
+	FailureLogs []FailureLog `json:"failure_logs"`
+
+	RetrySchedules []RetrySchedule `json:"retry_schedules,omitempty"`
+	Disputes       []Dispute       `json:"disputes,omitempty"`
+	Payouts        []Payout        `json:"payouts,omitempty"`
+}
+
+// Snapshot writes every payment log, failure log, retry schedule, dispute
+// and payout in the store to w, in a form LoadMemoryStore can read back.
+func (store *MemoryStore) Snapshot(w io.Writer) error {
+	store.Lock()
+	s := snapshot{
+		Version:     SnapshotVersion,
+		PaymentLogs: make([]PaymentLog, 0, len(store.paymentLogs)),
+		FailureLogs: make([]FailureLog, 0, len(store.failureLogs)),
+	}
+	for _, log := range store.paymentLogs {
+		if log == nil {
+			continue
+		}
+		s.PaymentLogs = append(s.PaymentLogs, *log)
+	}
+	for _, log := range store.failureLogs {
+		if log == nil {
+			continue
+		}
+		s.FailureLogs = append(s.FailureLogs, *log)
+	}
+	for _, schedule := range store.retries {
+		s.RetrySchedules = append(s.RetrySchedules, *schedule)
+	}
+	for _, dispute := range store.disputes {
+		s.Disputes = append(s.Disputes, *dispute)
+	}
+	for _, payout := range store.payouts {
+		s.Payouts = append(s.Payouts, *payout)
+	}
+	store.Unlock()
+	SortLogsByCreated(s.PaymentLogs)
+	SortFailureLogs(s.FailureLogs)
+	sort.Sort(retriesByNextAttempt(s.RetrySchedules))
+	sort.Sort(sortedDisputes(s.Disputes))
+	sort.Sort(sortedPayouts(s.Payouts))
+	enc := json.NewEncoder(w)
+	return enc.Encode(s)
+}
+
+func LoadMemoryStore(r io.Reader) (*MemoryStore, error) {
+	var s snapshot
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return nil, err
+	}
+	if s.Version != SnapshotVersion {
+		return nil, UnsupportedSnapshotVersion
+	}
+	store := NewMemoryStore()
+	for pos := range s.PaymentLogs {
+		log := s.PaymentLogs[pos]
+		if _, ok := store.paymentLogs[log.ID]; ok {
+			return nil, AlreadyExists
+		}
+		store.paymentLogs[log.ID] = &log
+		store.index(&log)
+		store.summarize(nil, &log)
+	}
+	for pos := range s.FailureLogs {
+		log := s.FailureLogs[pos]
+		if _, ok := store.failureLogs[log.ID]; ok {
+			return nil, AlreadyExists
+		}
+		store.failureLogs[log.ID] = &log
+	}
+	for pos := range s.RetrySchedules {
+		schedule := s.RetrySchedules[pos]
+		store.retries[schedule.PaymentLogID] = &schedule
+	}
+	for pos := range s.Disputes {
+		dispute := s.Disputes[pos]
+		if _, ok := store.disputes[dispute.ID]; ok {
+			return nil, DisputeAlreadyExists
+		}
+		store.disputes[dispute.ID] = &dispute
+	}
+	charged := map[string]uint{}
+	for _, dispute := range store.disputes {
+		if dispute.ChargedBack() {
+			charged[dispute.PaymentLogID] += dispute.Amount
+		}
+	}
+	for id, log := range store.paymentLogs {
+		log.ChargedBack = charged[id]
+	}
+	for pos := range s.Payouts {
+		payout := s.Payouts[pos]
+		if _, ok := store.payouts[payout.ID]; ok {
+			return nil, PayoutAlreadyExists
+		}
+		store.payouts[payout.ID] = &payout
+		for _, id := range payout.PaymentLogIDs {
+			if _, ok := store.paidOut[id]; ok {
+				return nil, AlreadyPaidOut
+			}
+			store.paidOut[id] = payout.ID
+		}
+	}
+	return store, nil
+}
@@ -0,0 +1,61 @@
+package paymentlog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSnapshottingMemoryStore(t *testing.T) {
+	store := exportTestStore()
+	var buf bytes.Buffer
+	if err := store.Snapshot(&buf); err != nil {
+		t.Fatalf("Error snapshotting memory store: %s", err)
+	}
+	if !strings.HasPrefix(buf.String(), `{"version":1,`) {
+		t.Errorf("Expected the snapshot to start with its version, got %q.", buf.String())
+	}
+	loaded, err := LoadMemoryStore(&buf)
+	if err != nil {
+		t.Fatalf("Error loading memory store: %s", err)
+	}
+	if len(loaded.paymentLogs) != len(store.paymentLogs) {
+		t.Errorf("Expected %d payment logs, got %d.", len(store.paymentLogs), len(loaded.paymentLogs))
+	}
+	for id, log := range store.paymentLogs {
+		result, ok := loaded.paymentLogs[id]
+		if !ok {
+			t.Errorf("Payment log %s was not loaded.", id)
+			continue
+		}
+		success, field, expectation, res := comparePaymentLogs(*log, *result)
+		if !success {
+			t.Errorf("Expected payment log %s %s to be %+v, got %+v.", id, field, expectation, res)
+		}
+	}
+	if len(loaded.failureLogs) != len(store.failureLogs) {
+		t.Errorf("Expected %d failure logs, got %d.", len(store.failureLogs), len(loaded.failureLogs))
+	}
+	for id, log := range store.failureLogs {
+		result, ok := loaded.failureLogs[id]
+		if !ok {
+			t.Errorf("Failure log %s was not loaded.", id)
+			continue
+		}
+		success, field, expectation, res := compareFailureLogs(*log, *result)
+		if !success {
+			t.Errorf("Expected failure log %s %s to be %+v, got %+v.", id, field, expectation, res)
+		}
+	}
+}
+
+func TestLoadingUnsupportedSnapshot(t *testing.T) {
+	_, err := LoadMemoryStore(strings.NewReader(`{"version": 2, "payment_logs": [], "failure_logs": []}`))
+	if err != UnsupportedSnapshotVersion {
+		t.Errorf("Expected %s, got %v.", UnsupportedSnapshotVersion, err)
+	}
+	_, err = LoadMemoryStore(strings.NewReader(`{"version": 1, "payment_logs": [{"id": "id"}, {"id": "id"}]}`))
+	if err != AlreadyExists {
+		t.Errorf("Expected %s for duplicate payment logs, got %v.", AlreadyExists, err)
+	}
+}
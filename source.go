@@ -0,0 +1,198 @@
+package paymentlog
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+const (
+	SourceStripe = "stripe"
+	SourcePayPal = "paypal"
+
+	MaxSourceEventSize = 1 << 20
+)
+
+var (
+	UnknownSource         = errors.New("Unknown payment source.")
+	InvalidEventSignature = errors.New("Invalid payment source event signature.")
+	MissingWebhookSecret  = errors.New("Missing webhook secret.")
+)
+
+// SourceEvent is a payment processor event normalized into the changes it
+// makes to the payment log. Any of PaymentLog, Change and Failure may be
+// nil.
+type SourceEvent struct {
+	// ID is the processor's ID for the event.
+	ID string
+	// PaymentLogID is the payment log the event is about.
+	PaymentLogID string
+	// PaymentLog is stored if no payment log with its ID exists yet.
+	PaymentLog *PaymentLog
+	// Change is applied to the payment log unless the payment log was
+	// updated more recently than Change.Updated.
+	Change *PaymentLogChange
+	// Failure is stored if no failure log with its ID exists yet.
+	Failure *FailureLog
+	// RefundedAmount, if set, is how much of the payment has been refunded.
+	// A Change to StatusRefunded is only applied once it covers the
+	// payment's amount, so partial refunds leave the payment succeeded.
+	RefundedAmount uint
+}
+
+// SourceAdapter turns the webhook events of a payment processor into
+// SourceEvents. Failure logs carry the processor's own failure codes.
+type SourceAdapter interface {
+	Source() string
+	Verify(payload []byte, header http.Header, secret string) bool
+	ParseEvents(payload []byte) ([]SourceEvent, error)
+	// Status maps a processor status onto StatusPending, StatusSucceeded,
+	// StatusFailed or StatusRefunded. Unknown statuses map to "".
+	Status(processorStatus string) string
+	// FailureCategory classifies one of the processor's failure codes.
+	// Unknown codes map to "".
+	FailureCategory(code string) FailureCategory
+}
+
+var (
+	sources     = map[string]SourceAdapter{}
+	sourcesLock sync.RWMutex
+)
+
+// RegisterSource makes an adapter available by its Source. Adapter packages
+// register themselves when they're imported. It panics if an adapter for
+// the same Source is already registered.
+func RegisterSource(adapter SourceAdapter) {
+	sourcesLock.Lock()
+	defer sourcesLock.Unlock()
+	if _, ok := sources[adapter.Source()]; ok {
+		panic("paymentlog: source " + adapter.Source() + " registered twice")
+	}
+	sources[adapter.Source()] = adapter
+}
+
+func SourceAdapterFor(source string) (SourceAdapter, error) {
+	sourcesLock.RLock()
+	defer sourcesLock.RUnlock()
+	adapter, ok := sources[source]
+	if !ok {
+		return nil, UnknownSource
+	}
+	return adapter, nil
+}
+
+func Sources() []string {
+	sourcesLock.RLock()
+	defer sourcesLock.RUnlock()
+	results := make([]string, 0, len(sources))
+	for source := range sources {
+		results = append(results, source)
+	}
+	sort.Strings(results)
+	return results
+}
+
+// SourcePaymentLogID returns the ID of the payment log that records a
+// processor's payment. IDs are derived from the processor's ID so that
+// every event about a payment can find its payment log.
+func SourcePaymentLogID(source, sourceID string) string {
+	return source + "-" + sourceID
+}
+
+// ApplySourceEvent records event in store. Applying the same event more
+// than once has the same effect as applying it once, and applying an older
+// event after a newer one doesn't undo the newer one.
+func ApplySourceEvent(store LogStore, event SourceEvent) error {
+	if event.PaymentLog != nil {
+		if err := event.PaymentLog.Validate(); err != nil {
+			return err
+		}
+		err := store.StorePaymentLog(*event.PaymentLog)
+		if err != nil && err != AlreadyExists {
+			return err
+		}
+	}
+	if event.Change != nil {
+		log, err := store.GetPaymentLog(event.PaymentLogID)
+		if err != nil {
+			return err
+		}
+		if !staleChange(log, *event.Change) && !partialRefund(log, event) {
+			if err := store.UpdatePaymentLog(event.PaymentLogID, *event.Change); err != nil {
+				return err
+			}
+		}
+	}
+	if event.Failure != nil {
+		err := store.StoreFailureLog(*event.Failure)
+		if err != nil && err != AlreadyExists {
+			return err
+		}
+	}
+	return nil
+}
+
+func partialRefund(log PaymentLog, event SourceEvent) bool {
+	if event.RefundedAmount == 0 || event.Change.Status == nil || *event.Change.Status != StatusRefunded {
+		return false
+	}
+	return event.RefundedAmount < log.Amount
+}
+
+func staleChange(log PaymentLog, change PaymentLogChange) bool {
+	if change.Updated == nil {
+		return false
+	}
+	if log.Updated.After(*change.Updated) {
+		return true
+	}
+	return log.Updated.Equal(*change.Updated) && (change.Status == nil || *change.Status == log.Status)
+}
+
+// WebhookHandler receives a payment processor's webhook events and applies
+// them to Store. Without a Secret, anyone could sign events, so every event
+// is refused.
+type WebhookHandler struct {
+	Store   LogStore
+	Adapter SourceAdapter
+	Secret  string
+}
+
+func NewWebhookHandler(store LogStore, adapter SourceAdapter, secret string) *WebhookHandler {
+	return &WebhookHandler{Store: store, Adapter: adapter, Secret: secret}
+}
+
+func (h *WebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "Method not allowed.", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.Secret == "" {
+		http.Error(w, MissingWebhookSecret.Error(), http.StatusInternalServerError)
+		return
+	}
+	payload, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, MaxSourceEventSize))
+	if err != nil {
+		http.Error(w, "Error reading request body.", http.StatusBadRequest)
+		return
+	}
+	if !h.Adapter.Verify(payload, r.Header, h.Secret) {
+		http.Error(w, InvalidEventSignature.Error(), http.StatusUnauthorized)
+		return
+	}
+	events, err := h.Adapter.ParseEvents(payload)
+	if err != nil {
+		http.Error(w, "Invalid request body.", http.StatusBadRequest)
+		return
+	}
+	for _, event := range events {
+		if err := ApplySourceEvent(h.Store, event); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
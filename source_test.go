@@ -0,0 +1,157 @@
+package paymentlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type testAdapter struct{}
+
+func (testAdapter) Source() string {
+	return "test-source"
+}
+
+func (testAdapter) Verify(payload []byte, header http.Header, secret string) bool {
+	return header.Get("X-Secret") == secret
+}
+
+func (testAdapter) ParseEvents(payload []byte) ([]SourceEvent, error) {
+	var events []SourceEvent
+	err := json.Unmarshal(payload, &events)
+	return events, err
+}
+
+func (testAdapter) Status(processorStatus string) string {
+	return processorStatus
+}
+
+func (testAdapter) FailureCategory(code string) FailureCategory {
+	return FailureCategory(code)
+}
+
+func TestRegisteringSources(t *testing.T) {
+	if _, err := SourceAdapterFor("test-source"); err == UnknownSource {
+		RegisterSource(testAdapter{})
+	}
+	adapter, err := SourceAdapterFor("test-source")
+	if err != nil {
+		t.Fatalf("Error finding adapter: %s", err)
+	}
+	if _, ok := adapter.(testAdapter); !ok {
+		t.Errorf("Expected the test adapter, got %T.", adapter)
+	}
+	found := false
+	for _, source := range Sources() {
+		found = found || source == "test-source"
+	}
+	if !found {
+		t.Errorf("Expected test-source in %v.", Sources())
+	}
+	if _, err := SourceAdapterFor("no-such-source"); err != UnknownSource {
+		t.Errorf("Expected %s, got %v.", UnknownSource, err)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected registering a source twice to panic.")
+		}
+	}()
+	RegisterSource(testAdapter{})
+}
+
+func TestApplyingSourceEvents(t *testing.T) {
+	store := NewMemoryStore()
+	created := time.Date(2014, time.March, 1, 12, 0, 0, 0, time.UTC)
+	log := PaymentLog{
+		ID:          "test-source-payment",
+		Amount:      1250,
+		Source:      "test-source",
+		SourceID:    "payment",
+		Created:     created,
+		Updated:     created,
+		Status:      StatusPending,
+		Currency:    CurrencyUSD,
+		ProjectID:   "project-id",
+		UserID:      "user-id",
+		AccountID:   "account-id",
+		AccountType: "google",
+	}
+	succeeded, refunded := StatusSucceeded, StatusRefunded
+	succeededAt, refundedAt := created.Add(time.Second), created.Add(time.Hour)
+	events := []SourceEvent{
+		{ID: "1", PaymentLogID: log.ID, PaymentLog: &log},
+		{ID: "3", PaymentLogID: log.ID, Change: &PaymentLogChange{Status: &refunded, Updated: &refundedAt}},
+		{ID: "2", PaymentLogID: log.ID, PaymentLog: &log, Change: &PaymentLogChange{Status: &succeeded, Updated: &succeededAt}},
+		{ID: "4", PaymentLogID: log.ID, Failure: &FailureLog{ID: "4", PaymentLogID: log.ID, FailureReasonCode: "code", Timestamp: refundedAt}},
+		{ID: "4", PaymentLogID: log.ID, Failure: &FailureLog{ID: "4", PaymentLogID: log.ID, FailureReasonCode: "code", Timestamp: refundedAt}},
+	}
+	for _, event := range events {
+		if err := ApplySourceEvent(store, event); err != nil {
+			t.Fatalf("Error applying event %s: %s", event.ID, err)
+		}
+	}
+	result, err := store.GetPaymentLog(log.ID)
+	if err != nil {
+		t.Fatalf("Error retrieving payment log: %s", err)
+	}
+	if result.Status != StatusRefunded || !result.Updated.Equal(refundedAt) {
+		t.Errorf("Expected the stale change to be ignored, got %+v.", result)
+	}
+	failures, _ := store.ListFailureLogs(10, 0)
+	if len(failures) != 1 {
+		t.Errorf("Expected 1 failure log, got %+v.", failures)
+	}
+	err = ApplySourceEvent(store, SourceEvent{PaymentLogID: "missing", Change: &PaymentLogChange{Status: &succeeded}})
+	if err != LogNotFound {
+		t.Errorf("Expected %s, got %v.", LogNotFound, err)
+	}
+}
+
+func TestWebhookHandler(t *testing.T) {
+	store := NewMemoryStore()
+	handler := NewWebhookHandler(store, testAdapter{}, "shh")
+	log := exportTestStore().paymentLogs["test-payment-log 1"]
+	body, err := json.Marshal([]SourceEvent{{ID: "1", PaymentLogID: log.ID, PaymentLog: log}})
+	if err != nil {
+		t.Fatalf("Error encoding events: %s", err)
+	}
+	for _, secret := range []string{"wrong", "shh"} {
+		r, _ := http.NewRequest("POST", "/webhooks/test-source", bytes.NewReader(body))
+		r.Header.Set("X-Secret", secret)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		expectation := http.StatusOK
+		if secret == "wrong" {
+			expectation = http.StatusUnauthorized
+		}
+		if w.Code != expectation {
+			t.Errorf("Expected status %d with secret %q, got %d: %s", expectation, secret, w.Code, w.Body.String())
+		}
+	}
+	if _, err := store.GetPaymentLog(log.ID); err != nil {
+		t.Errorf("Error retrieving payment log: %s", err)
+	}
+}
+
+func TestWebhookHandlerWithoutSecret(t *testing.T) {
+	store := NewMemoryStore()
+	handler := NewWebhookHandler(store, testAdapter{}, "")
+	log := exportTestStore().paymentLogs["test-payment-log 1"]
+	body, err := json.Marshal([]SourceEvent{{ID: "1", PaymentLogID: log.ID, PaymentLog: log}})
+	if err != nil {
+		t.Fatalf("Error encoding events: %s", err)
+	}
+	r, _ := http.NewRequest("POST", "/webhooks/test-source", bytes.NewReader(body))
+	r.Header.Set("X-Secret", "")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status %d without a secret, got %d.", http.StatusInternalServerError, w.Code)
+	}
+	if _, err := store.GetPaymentLog(log.ID); err != LogNotFound {
+		t.Errorf("Expected the event to be refused, got %v.", err)
+	}
+}
@@ -0,0 +1,256 @@
+package stripe
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"code.whipround.net/paymentlog"
+)
+
+const (
+	SignatureHeader  = "Stripe-Signature"
+	DefaultTolerance = 5 * time.Minute
+)
+
+var MissingCharge = errors.New("Stripe event is missing its charge.")
+
+type Charge struct {
+	ID             string            `json:"id"`
+	Object         string            `json:"object"`
+	Amount         uint              `json:"amount"`
+	AmountRefunded uint              `json:"amount_refunded"`
+	Currency       string            `json:"currency"`
+	Status         string            `json:"status"`
+	Refunded       bool              `json:"refunded"`
+	Description    string            `json:"description"`
+	FailureCode    string            `json:"failure_code"`
+	FailureMessage string            `json:"failure_message"`
+	Created        int64             `json:"created"`
+	Metadata       map[string]string `json:"metadata"`
+}
+
+type Event struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Created int64  `json:"created"`
+	Data    struct {
+		Object json.RawMessage `json:"object"`
+	} `json:"data"`
+}
+
+// PaymentLogID returns the ID of the payment log that records a Stripe
+// charge.
+func PaymentLogID(chargeID string) string {
+	return paymentlog.SourcePaymentLogID(paymentlog.SourceStripe, chargeID)
+}
+
+func sign(payload []byte, timestamp, secret string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// Sign returns the SignatureHeader value Stripe would send with payload at
+// time t.
+func Sign(payload []byte, secret string, t time.Time) string {
+	timestamp := strconv.FormatInt(t.Unix(), 10)
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(sign(payload, timestamp, secret))
+}
+
+// Verify checks a SignatureHeader value. Signatures older or newer than
+// tolerance are rejected, to limit replay attacks.
+func Verify(payload []byte, header, secret string, tolerance time.Duration) bool {
+	var timestamp string
+	var signatures [][]byte
+	for _, part := range strings.Split(header, ",") {
+		pieces := strings.SplitN(part, "=", 2)
+		if len(pieces) != 2 {
+			continue
+		}
+		switch pieces[0] {
+		case "t":
+			timestamp = pieces[1]
+		case "v1":
+			signature, err := hex.DecodeString(pieces[1])
+			if err == nil {
+				signatures = append(signatures, signature)
+			}
+		}
+	}
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	age := time.Since(time.Unix(seconds, 0))
+	if age > tolerance || age < -tolerance {
+		return false
+	}
+	expected := sign(payload, timestamp, secret)
+	for _, signature := range signatures {
+		if hmac.Equal(signature, expected) {
+			return true
+		}
+	}
+	return false
+}
+
+func ParseEvent(payload []byte) (Event, error) {
+	var event Event
+	err := json.Unmarshal(payload, &event)
+	return event, err
+}
+
+func (c Charge) PaymentLog(updated time.Time) paymentlog.PaymentLog {
+	return paymentlog.PaymentLog{
+		ID:          PaymentLogID(c.ID),
+		Amount:      c.Amount,
+		Description: c.Description,
+		Source:      paymentlog.SourceStripe,
+		SourceID:    c.ID,
+		Created:     time.Unix(c.Created, 0).UTC(),
+		Updated:     updated,
+		Status:      c.status(),
+		Currency:    strings.ToLower(c.Currency),
+		ProjectID:   c.Metadata["project_id"],
+		UserID:      c.Metadata["user_id"],
+		AccountID:   c.Metadata["account_id"],
+		AccountType: c.Metadata["account_type"],
+	}
+}
+
+// status only considers a charge refunded once all of it has been refunded.
+func (c Charge) status() string {
+	if c.Refunded {
+		return paymentlog.StatusRefunded
+	}
+	return Adapter{}.Status(c.Status)
+}
+
+// SourceEvent normalizes a Stripe event. Events that aren't about charges,
+// including charge.* events whose object is a refund or dispute, normalize to
+// an empty SourceEvent.
+func (e Event) SourceEvent() (paymentlog.SourceEvent, error) {
+	result := paymentlog.SourceEvent{ID: e.ID}
+	if !strings.HasPrefix(e.Type, "charge.") || strings.HasPrefix(e.Type, "charge.dispute.") {
+		return result, nil
+	}
+	var charge Charge
+	if len(e.Data.Object) == 0 {
+		return result, MissingCharge
+	}
+	if err := json.Unmarshal(e.Data.Object, &charge); err != nil {
+		return result, err
+	}
+	if charge.Object != "charge" {
+		return result, nil
+	}
+	if charge.ID == "" {
+		return result, MissingCharge
+	}
+	updated := time.Unix(e.Created, 0).UTC()
+	log := charge.PaymentLog(updated)
+	result.PaymentLogID = log.ID
+	result.PaymentLog = &log
+	result.Change = &paymentlog.PaymentLogChange{
+		Status:  &log.Status,
+		Updated: &log.Updated,
+	}
+	if e.Type == "charge.failed" {
+		result.Failure = &paymentlog.FailureLog{
+			ID:                e.ID,
+			PaymentLogID:      log.ID,
+			Source:            paymentlog.SourceStripe,
+			FailureReason:     charge.FailureMessage,
+			FailureReasonCode: charge.FailureCode,
+			Timestamp:         updated,
+		}
+	}
+	return result, nil
+}
+
+// Adapter is the paymentlog.SourceAdapter for Stripe. The zero value
+// accepts signatures within DefaultTolerance.
+type Adapter struct {
+	Tolerance time.Duration
+}
+
+func (Adapter) Source() string {
+	return paymentlog.SourceStripe
+}
+
+func (a Adapter) Verify(payload []byte, header http.Header, secret string) bool {
+	tolerance := a.Tolerance
+	if tolerance <= 0 {
+		tolerance = DefaultTolerance
+	}
+	return Verify(payload, header.Get(SignatureHeader), secret, tolerance)
+}
+
+func (Adapter) ParseEvents(payload []byte) ([]paymentlog.SourceEvent, error) {
+	event, err := ParseEvent(payload)
+	if err != nil {
+		return nil, err
+	}
+	normalized, err := event.SourceEvent()
+	if err != nil {
+		return nil, err
+	}
+	return []paymentlog.SourceEvent{normalized}, nil
+}
+
+func (Adapter) Status(processorStatus string) string {
+	switch processorStatus {
+	case "pending":
+		return paymentlog.StatusPending
+	case "succeeded", "paid":
+		return paymentlog.StatusSucceeded
+	case "failed":
+		return paymentlog.StatusFailed
+	}
+	return ""
+}
+
+// FailureCategories maps Stripe failure and decline codes onto failure
+// categories.
+var FailureCategories = map[string]paymentlog.FailureCategory{
+	"insufficient_funds":   paymentlog.CategoryInsufficientFunds,
+	"card_declined":        paymentlog.CategoryCardDeclined,
+	"generic_decline":      paymentlog.CategoryCardDeclined,
+	"do_not_honor":         paymentlog.CategoryCardDeclined,
+	"incorrect_cvc":        paymentlog.CategoryCardDeclined,
+	"invalid_cvc":          paymentlog.CategoryCardDeclined,
+	"expired_card":         paymentlog.CategoryExpiredCard,
+	"incorrect_number":     paymentlog.CategoryInvalidAccount,
+	"invalid_number":       paymentlog.CategoryInvalidAccount,
+	"account_closed":       paymentlog.CategoryInvalidAccount,
+	"no_account":           paymentlog.CategoryInvalidAccount,
+	"fraudulent":           paymentlog.CategoryFraudSuspected,
+	"lost_card":            paymentlog.CategoryFraudSuspected,
+	"stolen_card":          paymentlog.CategoryFraudSuspected,
+	"merchant_blacklist":   paymentlog.CategoryFraudSuspected,
+	"processing_error":     paymentlog.CategoryProcessorError,
+	"api_error":            paymentlog.CategoryProcessorError,
+	"api_connection_error": paymentlog.CategoryNetworkError,
+	"refund_failed":        paymentlog.CategoryRefundFailed,
+}
+
+func (Adapter) FailureCategory(code string) paymentlog.FailureCategory {
+	return FailureCategories[code]
+}
+
+func init() {
+	paymentlog.RegisterSource(Adapter{})
+}
+
+// NewHandler returns a handler for Stripe webhook events.
+func NewHandler(store paymentlog.LogStore, secret string) *paymentlog.WebhookHandler {
+	return paymentlog.NewWebhookHandler(store, Adapter{}, secret)
+}
@@ -0,0 +1,172 @@
+package stripe
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"code.whipround.net/paymentlog"
+)
+
+const (
+	testSecret   = "whsec_shh"
+	testChargeID = "ch_3LmVZ2Kx8vZ1nQ0a1pY7tG4q"
+)
+
+func fixture(t *testing.T, name string) []byte {
+	body, err := ioutil.ReadFile(filepath.Join("testdata", name+".json"))
+	if err != nil {
+		t.Fatalf("Error reading fixture %s: %s", name, err)
+	}
+	return body
+}
+
+func deliver(t *testing.T, handler http.Handler, body []byte, signature string) *httptest.ResponseRecorder {
+	r, err := http.NewRequest("POST", "/webhooks/stripe", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("Error creating request: %s", err)
+	}
+	r.Header.Set(SignatureHeader, signature)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func deliverFixtures(t *testing.T, store paymentlog.LogStore, names ...string) {
+	handler := NewHandler(store, testSecret)
+	for _, name := range names {
+		body := fixture(t, name)
+		w := deliver(t, handler, body, Sign(body, testSecret, time.Now()))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected status %d, got %d: %s", name, http.StatusOK, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestAdapterIsRegistered(t *testing.T) {
+	adapter, err := paymentlog.SourceAdapterFor(paymentlog.SourceStripe)
+	if err != nil {
+		t.Fatalf("Error finding adapter: %s", err)
+	}
+	if _, ok := adapter.(Adapter); !ok {
+		t.Errorf("Expected the stripe adapter, got %T.", adapter)
+	}
+}
+
+func TestChargeLifecycle(t *testing.T) {
+	store := paymentlog.NewMemoryStore()
+	deliverFixtures(t, store, "charge_pending", "charge_succeeded", "charge_refunded")
+	log, err := store.GetPaymentLog(PaymentLogID(testChargeID))
+	if err != nil {
+		t.Fatalf("Error retrieving payment log: %s", err)
+	}
+	expectation := paymentlog.PaymentLog{
+		ID:          "stripe-" + testChargeID,
+		Amount:      1250,
+		Description: "Contribution to project-id",
+		Source:      paymentlog.SourceStripe,
+		SourceID:    testChargeID,
+		Created:     time.Date(2014, time.March, 1, 12, 0, 0, 0, time.UTC),
+		Updated:     time.Date(2014, time.March, 2, 9, 50, 0, 0, time.UTC),
+		Status:      paymentlog.StatusRefunded,
+		Currency:    paymentlog.CurrencyUSD,
+		ProjectID:   "project-id",
+		UserID:      "user-id",
+		AccountID:   "account-id",
+		AccountType: "google",
+	}
+	if log.ID != expectation.ID || log.Amount != expectation.Amount || log.Status != expectation.Status ||
+		log.Currency != expectation.Currency || log.ProjectID != expectation.ProjectID || log.AccountType != expectation.AccountType ||
+		!log.Created.Equal(expectation.Created) || !log.Updated.Equal(expectation.Updated) {
+		t.Errorf("Expected %+v, got %+v.", expectation, log)
+	}
+}
+
+func TestOutOfOrderEvents(t *testing.T) {
+	store := paymentlog.NewMemoryStore()
+	deliverFixtures(t, store, "charge_refunded", "charge_succeeded", "charge_pending", "charge_refunded")
+	log, err := store.GetPaymentLog(PaymentLogID(testChargeID))
+	if err != nil {
+		t.Fatalf("Error retrieving payment log: %s", err)
+	}
+	if log.Status != paymentlog.StatusRefunded {
+		t.Errorf("Expected older events not to undo the refund, got status %s.", log.Status)
+	}
+}
+
+func TestIgnoringNonChargeObjects(t *testing.T) {
+	store := paymentlog.NewMemoryStore()
+	deliverFixtures(t, store, "charge_succeeded", "charge_refund_updated")
+	log, err := store.GetPaymentLog(PaymentLogID(testChargeID))
+	if err != nil {
+		t.Fatalf("Error retrieving payment log: %s", err)
+	}
+	if log.Status != paymentlog.StatusSucceeded {
+		t.Errorf("Expected a refund object to leave the charge alone, got status %s.", log.Status)
+	}
+}
+
+func TestFailedCharge(t *testing.T) {
+	store := paymentlog.NewMemoryStore()
+	deliverFixtures(t, store, "charge_pending", "charge_failed", "charge_failed")
+	log, err := store.GetPaymentLog(PaymentLogID(testChargeID))
+	if err != nil {
+		t.Fatalf("Error retrieving payment log: %s", err)
+	}
+	if log.Status != paymentlog.StatusFailed {
+		t.Errorf("Expected status %s, got %s.", paymentlog.StatusFailed, log.Status)
+	}
+	failures, err := store.ListFailureLogs(10, 0)
+	if err != nil {
+		t.Fatalf("Error listing failure logs: %s", err)
+	}
+	if len(failures) != 1 {
+		t.Fatalf("Expected 1 failure log, got %+v.", failures)
+	}
+	if failures[0].PaymentLogID != log.ID || failures[0].FailureReason != "Your card has insufficient funds." || failures[0].FailureReasonCode != "card_declined" {
+		t.Errorf("Unexpected failure log: %+v", failures[0])
+	}
+	if category := failures[0].Category(); category != paymentlog.CategoryCardDeclined {
+		t.Errorf("Expected category %s, got %s.", paymentlog.CategoryCardDeclined, category)
+	}
+}
+
+func TestRejectingUnsignedEvents(t *testing.T) {
+	store := paymentlog.NewMemoryStore()
+	handler := NewHandler(store, testSecret)
+	body := fixture(t, "charge_succeeded")
+	signatures := []string{
+		"",
+		"t=abc,v1=def",
+		Sign(body, "wrong secret", time.Now()),
+		Sign(body, testSecret, time.Now().Add(-time.Hour)),
+	}
+	for _, signature := range signatures {
+		w := deliver(t, handler, body, signature)
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("Expected status %d for signature %q, got %d.", http.StatusUnauthorized, signature, w.Code)
+		}
+	}
+	logs, _ := store.ListPaymentLogs(10, 0)
+	if len(logs) != 0 {
+		t.Errorf("Expected unsigned events to be ignored, got %+v.", logs)
+	}
+}
+
+func TestStatusMapping(t *testing.T) {
+	expectations := map[string]string{
+		"pending":   paymentlog.StatusPending,
+		"succeeded": paymentlog.StatusSucceeded,
+		"failed":    paymentlog.StatusFailed,
+		"canceled":  "",
+	}
+	for status, expectation := range expectations {
+		if result := (Adapter{}).Status(status); result != expectation {
+			t.Errorf("Expected %q to map to %q, got %q.", status, expectation, result)
+		}
+	}
+}
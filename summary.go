@@ -0,0 +1,82 @@
+package paymentlog
+
+import (
+	"time"
+)
+
+// ProjectSummary describes a project's contributions in one currency.
+// Only succeeded payments count as contributions, so a refunded payment
+// stops counting towards Raised once its status changes.
+type ProjectSummary struct {
+	ProjectID           string    `json:"project_id"`
+	Currency            string    `json:"currency"`
+	Raised              uint      `json:"raised"`
+	Contributions       int       `json:"contributions"`
+	Contributors        int       `json:"contributors"`
+	LargestContribution uint      `json:"largest_contribution"`
+	FirstContribution   time.Time `json:"first_contribution"`
+	LastContribution    time.Time `json:"last_contribution"`
+}
+
+type SummaryStore interface {
+	// GetProjectSummary returns a zero summary for a project that has no
+	// contributions in the currency.
+	GetProjectSummary(projectID, currency string) (ProjectSummary, error)
+}
+
+// contributions keeps the state a ProjectSummary is computed from, so it
+// can be kept up to date as payment logs change.
+type contributions struct {
+	summary ProjectSummary
+	logs    map[string]PaymentLog
+	users   map[string]int
+}
+
+func contributes(log *PaymentLog) bool {
+	return log != nil && log.Status == StatusSucceeded
+}
+
+func (c *contributions) add(log PaymentLog) {
+	c.logs[log.ID] = log
+	c.users[log.UserID]++
+	s := &c.summary
+	s.Raised += log.Amount
+	s.Contributions++
+	s.Contributors = len(c.users)
+	if log.Amount > s.LargestContribution {
+		s.LargestContribution = log.Amount
+	}
+	if s.FirstContribution.IsZero() || log.Created.Before(s.FirstContribution) {
+		s.FirstContribution = log.Created
+	}
+	if log.Created.After(s.LastContribution) {
+		s.LastContribution = log.Created
+	}
+}
+
+func (c *contributions) remove(log PaymentLog) {
+	delete(c.logs, log.ID)
+	if c.users[log.UserID]--; c.users[log.UserID] <= 0 {
+		delete(c.users, log.UserID)
+	}
+	s := &c.summary
+	s.Raised -= log.Amount
+	s.Contributions--
+	s.Contributors = len(c.users)
+	// only rescan when the removed contribution was one of the extremes
+	if log.Amount < s.LargestContribution && log.Created.After(s.FirstContribution) && log.Created.Before(s.LastContribution) {
+		return
+	}
+	s.LargestContribution, s.FirstContribution, s.LastContribution = 0, time.Time{}, time.Time{}
+	for _, l := range c.logs {
+		if l.Amount > s.LargestContribution {
+			s.LargestContribution = l.Amount
+		}
+		if s.FirstContribution.IsZero() || l.Created.Before(s.FirstContribution) {
+			s.FirstContribution = l.Created
+		}
+		if l.Created.After(s.LastContribution) {
+			s.LastContribution = l.Created
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package paymentlog
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestProjectSummaries(t *testing.T) {
+	store := storeTestPayments(t,
+		testPayment{id: "p1", user: "alice", status: StatusSucceeded, amount: 1000},
+		testPayment{id: "p2", user: "bob", status: StatusSucceeded, amount: 2500},
+		testPayment{id: "p3", user: "alice", status: StatusSucceeded, amount: 500},
+		testPayment{id: "p4", user: "carol", status: StatusPending, amount: 9000},
+		testPayment{id: "p5", user: "dave", status: StatusFailed, amount: 9000},
+	)
+	created := testPaymentsCreated
+	expectSummary := func(expectation ProjectSummary) {
+		expectation.ProjectID, expectation.Currency = "project-id", CurrencyUSD
+		summary, err := store.GetProjectSummary("project-id", CurrencyUSD)
+		if err != nil {
+			t.Fatalf("Error getting project summary: %s", err)
+		}
+		if summary != expectation {
+			t.Errorf("Expected %+v, got %+v.", expectation, summary)
+		}
+	}
+	expectSummary(ProjectSummary{
+		Raised:              4000,
+		Contributions:       3,
+		Contributors:        2,
+		LargestContribution: 2500,
+		FirstContribution:   created,
+		LastContribution:    created.Add(2 * time.Hour),
+	})
+
+	refunded, succeeded := StatusRefunded, StatusSucceeded
+	if err := store.UpdatePaymentLog("p2", PaymentLogChange{Status: &refunded}); err != nil {
+		t.Fatalf("Error updating payment log: %s", err)
+	}
+	if err := store.UpdatePaymentLog("p4", PaymentLogChange{Status: &succeeded}); err != nil {
+		t.Fatalf("Error updating payment log: %s", err)
+	}
+	if err := store.DeletePaymentLog("p1"); err != nil {
+		t.Fatalf("Error deleting payment log: %s", err)
+	}
+	expected := ProjectSummary{
+		Raised:              9500,
+		Contributions:       2,
+		Contributors:        2,
+		LargestContribution: 9000,
+		FirstContribution:   created.Add(2 * time.Hour),
+		LastContribution:    created.Add(3 * time.Hour),
+	}
+	expectSummary(expected)
+
+	var buf bytes.Buffer
+	if err := store.Snapshot(&buf); err != nil {
+		t.Fatalf("Error snapshotting memory store: %s", err)
+	}
+	if store, err := LoadMemoryStore(&buf); err != nil {
+		t.Fatalf("Error loading memory store: %s", err)
+	} else if summary, _ := store.GetProjectSummary("project-id", CurrencyUSD); summary.Raised != expected.Raised || summary.Contributors != expected.Contributors {
+		t.Errorf("Expected %+v after loading, got %+v.", expected, summary)
+	}
+
+	if summary, err := store.GetProjectSummary("project-id", "eur"); err != nil || summary.Contributions != 0 || summary.Raised != 0 {
+		t.Errorf("Expected an empty summary, got %+v, %v.", summary, err)
+	}
+}
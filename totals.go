@@ -0,0 +1,75 @@
+package paymentlog
+
+import (
+	"sort"
+)
+
+// ProjectTotal sums up a project's succeeded payments in one currency.
+// Net is what the project receives: Gross less fees and chargebacks.
+type ProjectTotal struct {
+	ProjectID     string `json:"project_id"`
+	Currency      string `json:"currency"`
+	Payments      int    `json:"payments"`
+	Gross         uint   `json:"gross"`
+	ProcessorFees uint   `json:"processor_fees"`
+	PlatformFees  uint   `json:"platform_fees"`
+	ChargedBack   uint   `json:"charged_back"`
+	Net           uint   `json:"net"`
+}
+
+// ProjectTotals aggregates the succeeded payment logs matching every filter
+// by project and currency, sorted by project and then currency. Pending,
+// failed and refunded payments aren't counted, since the project won't
+// receive them.
+func ProjectTotals(store LogStore, filters ...PaymentLogFilter) ([]ProjectTotal, error) {
+	totals := map[[2]string]*ProjectTotal{}
+	err := store.IteratePaymentLogs(func(log PaymentLog) error {
+		if log.Status != StatusSucceeded {
+			return nil
+		}
+		for _, filter := range filters {
+			if !filter(log) {
+				return nil
+			}
+		}
+		key := [2]string{log.ProjectID, log.Currency}
+		total, ok := totals[key]
+		if !ok {
+			total = &ProjectTotal{ProjectID: log.ProjectID, Currency: log.Currency}
+			totals[key] = total
+		}
+		total.Payments++
+		total.Gross += log.Amount
+		total.ProcessorFees += log.ProcessorFee
+		total.PlatformFees += log.PlatformFee
+		total.ChargedBack += log.ChargedBack
+		total.Net += log.NetAmount()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	results := make([]ProjectTotal, 0, len(totals))
+	for _, total := range totals {
+		results = append(results, *total)
+	}
+	sort.Sort(projectTotals(results))
+	return results, nil
+}
+
+type projectTotals []ProjectTotal
+
+func (p projectTotals) Len() int {
+	return len(p)
+}
+
+func (p projectTotals) Swap(i, j int) {
+	p[i], p[j] = p[j], p[i]
+}
+
+func (p projectTotals) Less(i, j int) bool {
+	if p[i].ProjectID == p[j].ProjectID {
+		return p[i].Currency < p[j].Currency
+	}
+	return p[i].ProjectID < p[j].ProjectID
+}
@@ -0,0 +1,127 @@
+package paymentlog
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFeesAndNetAmount(t *testing.T) {
+	log := PaymentLog{Amount: 1000, ProcessorFee: 59, PlatformFee: 50}
+	if log.Fees() != 109 || log.NetAmount() != 891 {
+		t.Errorf("Expected fees of 109 and a net amount of 891, got %d and %d.", log.Fees(), log.NetAmount())
+	}
+	log.ChargedBack = 1000
+	if log.NetAmount() != 0 {
+		t.Errorf("Expected a net amount of 0, got %d.", log.NetAmount())
+	}
+
+	store := exportTestStore()
+	log, _ = store.GetPaymentLog("test-payment-log 1")
+	log.PlatformFee, log.ProcessorFee = 1000, 251
+	if err := log.Validate(); err != FeesExceedAmount {
+		t.Errorf("Expected %s, got %v.", FeesExceedAmount, err)
+	}
+	processorFee, platformFee := uint(1000), uint(251)
+	if err := store.UpdatePaymentLog("test-payment-log 1", PaymentLogChange{ProcessorFee: &processorFee, PlatformFee: &platformFee}); err != FeesExceedAmount {
+		t.Errorf("Expected %s updating fees, got %v.", FeesExceedAmount, err)
+	}
+	if log, _ = store.GetPaymentLog("test-payment-log 1"); log.ProcessorFee != 0 || log.PlatformFee != 0 {
+		t.Errorf("Expected a rejected update to leave the fees alone, got %+v.", log)
+	}
+	processorFee, platformFee = 66, 63
+	if err := store.UpdatePaymentLog("test-payment-log 1", PaymentLogChange{ProcessorFee: &processorFee, PlatformFee: &platformFee}); err != nil {
+		t.Fatalf("Error updating payment log: %s", err)
+	}
+	log, _ = store.GetPaymentLog("test-payment-log 1")
+	if log.ProcessorFee != 66 || log.PlatformFee != 63 || log.NetAmount() != 1121 {
+		t.Errorf("Unexpected payment log: %+v", log)
+	}
+	fields := PaymentLogChange{ProcessorFee: &processorFee, PlatformFee: &platformFee}.Fields()
+	if len(fields) != 2 || fields[0] != "ProcessorFee" || fields[1] != "PlatformFee" {
+		t.Errorf("Expected [ProcessorFee PlatformFee], got %v.", fields)
+	}
+}
+
+// testPayment describes a payment log for storeTestPayments, which fills
+// in the rest. The project defaults to project-id, the user to user-id and
+// the currency to usd.
+type testPayment struct {
+	id, project, user, currency, status string
+	amount, processorFee, platformFee   uint
+}
+
+var testPaymentsCreated = time.Date(2014, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+// storeTestPayments stores payments in a new MemoryStore, created an hour
+// apart starting at testPaymentsCreated.
+func storeTestPayments(t *testing.T, payments ...testPayment) *MemoryStore {
+	store := NewMemoryStore()
+	for pos, p := range payments {
+		log := PaymentLog{
+			ID:           p.id,
+			Amount:       p.amount,
+			ProcessorFee: p.processorFee,
+			PlatformFee:  p.platformFee,
+			Source:       SourceBalanced,
+			SourceID:     p.id,
+			Created:      testPaymentsCreated.Add(time.Duration(pos) * time.Hour),
+			Status:       p.status,
+			Currency:     p.currency,
+			ProjectID:    p.project,
+			UserID:       p.user,
+			AccountID:    "account-id",
+			AccountType:  "google",
+		}
+		if log.ProjectID == "" {
+			log.ProjectID = "project-id"
+		}
+		if log.UserID == "" {
+			log.UserID = "user-id"
+		}
+		if log.Currency == "" {
+			log.Currency = CurrencyUSD
+		}
+		if err := store.StorePaymentLog(log); err != nil {
+			t.Fatalf("Error storing payment log: %s", err)
+		}
+	}
+	return store
+}
+
+func TestProjectTotals(t *testing.T) {
+	store := storeTestPayments(t,
+		testPayment{id: "p1", project: "project-b", status: StatusSucceeded, amount: 1000, processorFee: 59, platformFee: 50},
+		testPayment{id: "p2", project: "project-b", status: StatusSucceeded, amount: 2500, processorFee: 103, platformFee: 125},
+		testPayment{id: "p3", project: "project-b", currency: "eur", status: StatusSucceeded, amount: 500, processorFee: 35, platformFee: 25},
+		testPayment{id: "p4", project: "project-b", status: StatusPending, amount: 9900},
+		testPayment{id: "p5", project: "project-a", status: StatusSucceeded, amount: 200, processorFee: 36, platformFee: 10},
+		testPayment{id: "p6", project: "project-a", status: StatusRefunded, amount: 200, processorFee: 36, platformFee: 10},
+	)
+	created := testPaymentsCreated
+	if err := store.StoreDispute(Dispute{ID: "dp1", PaymentLogID: "p1", Amount: 1000, Outcome: DisputeLost, Created: created}); err != nil {
+		t.Fatalf("Error storing dispute: %s", err)
+	}
+
+	totals, err := ProjectTotals(store)
+	if err != nil {
+		t.Fatalf("Error totalling projects: %s", err)
+	}
+	expectations := []ProjectTotal{
+		{ProjectID: "project-a", Currency: CurrencyUSD, Payments: 1, Gross: 200, ProcessorFees: 36, PlatformFees: 10, Net: 154},
+		{ProjectID: "project-b", Currency: "eur", Payments: 1, Gross: 500, ProcessorFees: 35, PlatformFees: 25, Net: 440},
+		{ProjectID: "project-b", Currency: CurrencyUSD, Payments: 2, Gross: 3500, ProcessorFees: 162, PlatformFees: 175, ChargedBack: 1000, Net: 2272},
+	}
+	if len(totals) != len(expectations) {
+		t.Fatalf("Expected %+v, got %+v.", expectations, totals)
+	}
+	for pos, expectation := range expectations {
+		if totals[pos] != expectation {
+			t.Errorf("Expected %+v, got %+v.", expectation, totals[pos])
+		}
+	}
+
+	totals, err = ProjectTotals(store, ProjectFilter("project-a"))
+	if err != nil || len(totals) != 1 || totals[0] != expectations[0] {
+		t.Errorf("Expected [%+v], got %+v, %v.", expectations[0], totals, err)
+	}
+}